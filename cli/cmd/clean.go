@@ -9,7 +9,6 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/path"
 	"github.com/turnerlabs/cstore/v4/components/remote"
-	"github.com/turnerlabs/cstore/v4/components/store"
 )
 
 var cleanCmd = &cobra.Command{
@@ -53,7 +52,7 @@ func cleanCatalog(catalogPath string) {
 			os.Exit(1)
 		}
 
-		if !remoteComp.Store.SupportsFeature(store.SourceControlFeature) {
+		if !remoteComp.Store.Capabilities().SourceControl {
 			file := clog.GetFullPath(f.ActualPath())
 			if err := os.Remove(file); err != nil {
 				if !os.IsNotExist(err) {
@@ -9,6 +9,7 @@ import (
 	"github.com/subosito/gotenv"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/store"
 )
 
 //"\xE2\x9C\x94" This is a checkmark on mac, but question mark on windows; so,
@@ -30,6 +31,10 @@ func updateUserOptions(file catalog.File, fileUpdate bool, opt cfg.UserOptions)
 		file.DeleteAfterPush = b
 	}
 
+	if b, err := strconv.ParseBool(opt.Encrypt); err == nil {
+		store.SetEncryption(&file, b)
+	}
+
 	if len(opt.Paths) > 0 && len(opt.Tags) > 0 {
 		file.Tags = opt.TagList
 	} else if len(file.Tags) == 0 {
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/remote"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List prior copies of file(s) kept by the store.",
+	Long: `List prior copies of file(s) kept by the store.
+
+Only stores keeping a copy of the file for each push, like 'aws-s3' with bucket versioning enabled, support history.`,
+	Run: func(cmd *cobra.Command, userSpecifiedFilePaths []string) {
+		setupUserOptions(userSpecifiedFilePaths)
+
+		fmt.Fprintln(ioStreams.UserOutput)
+
+		if err := History(uo, ioStreams); err != nil {
+			display.Error(fmt.Errorf("%s for %s", err, uo.Catalog), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ioStreams.UserOutput, "Use 'cstore rollback FILE -r REVISION' to restore a prior copy.\n\n")
+	},
+}
+
+// History ...
+func History(opt cfg.UserOptions, io models.IO) error {
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		return err
+	}
+
+	for _, fileEntry := range clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, "") {
+		if fileEntry.IsRef {
+			continue
+		}
+
+		fileEntryTemp := remote.OverrideFileSettings(fileEntry, opt)
+
+		remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(io.UserOutput, "|-")
+		color.New(color.FgBlue).Fprintf(io.UserOutput, " %s ", fileEntry.ActualPath())
+		color.New(color.Bold).Fprintf(io.UserOutput, "[%s]\n", fileEntry.Store)
+
+		h, ok := remoteComp.Store.(contract.IHistory)
+		if !ok || !remoteComp.Store.Capabilities().History {
+			display.ErrorText(fmt.Sprintf("|    %s", contract.ErrHistoryNotSupported), io.UserOutput)
+			fmt.Fprintln(io.UserOutput, "|")
+			continue
+		}
+
+		revisions, err := h.History(&fileEntryTemp)
+		if err != nil {
+			display.ErrorText(fmt.Sprintf("|    %s", err), io.UserOutput)
+			fmt.Fprintln(io.UserOutput, "|")
+			continue
+		}
+
+		for _, r := range revisions {
+			fmt.Fprintf(io.UserOutput, "|    |- %s %s", r.Modified.Local().Format("2006-01-02 15:04:05"), r.ID)
+
+			if len(r.Version) > 0 {
+				color.New(color.Bold).Fprintf(io.UserOutput, " (%s)", r.Version)
+			}
+
+			if r.Latest {
+				color.New(color.FgGreen).Fprint(io.UserOutput, " latest")
+			}
+
+			fmt.Fprintln(io.UserOutput)
+		}
+
+		fmt.Fprintln(io.UserOutput, "|")
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVarP(&uo.Tags, tagsToken, "t", "", "Specify a list of tags used to filter files.")
+}
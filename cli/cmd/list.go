@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -10,6 +11,8 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/path"
+	"github.com/turnerlabs/cstore/v4/components/remote"
+	"github.com/turnerlabs/cstore/v4/components/store"
 )
 
 // listCmd represents the list command
@@ -30,7 +33,7 @@ var listCmd = &cobra.Command{
 
 		color.New(color.Bold).Fprintf(ioStreams.UserOutput, "\n%d file(s) stored remotely.\n", total)
 
-		fmt.Fprintf(ioStreams.UserOutput, "\nUse -g and -v to display file tags and versions.\n\n")
+		fmt.Fprintf(ioStreams.UserOutput, "\nUse -g, -v, and -m to display file tags, versions, and remote attributes.\n\n")
 	},
 }
 
@@ -97,16 +100,71 @@ func listFilesFor(catalogPath string, opt cfg.UserOptions, io models.IO) (int, e
 			fmt.Fprintln(io.UserOutput, "|")
 		}
 
+		if opt.ViewAttributes {
+			listAttributesFor(fileEntry, clog, opt, io)
+		}
+
 		total++
 	}
 
 	return total, nil
 }
 
+//-------------------------------------------------
+//- Print the attributes of the remote file.
+//-------------------------------------------------
+func listAttributesFor(fileEntry catalog.File, clog catalog.Catalog, opt cfg.UserOptions, io models.IO) {
+	fmt.Fprintf(io.UserOutput, "|")
+	color.New(color.Bold).Fprintln(io.UserOutput, "   attributes")
+
+	fileEntryTemp := remote.OverrideFileSettings(fileEntry, opt)
+
+	remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
+	if err != nil {
+		display.ErrorText(fmt.Sprintf("|    %s", err), io.UserOutput)
+		fmt.Fprintln(io.UserOutput, "|")
+		return
+	}
+
+	attr, err := store.Stat(remoteComp.Store, &fileEntryTemp, opt.Version)
+	if err != nil {
+		display.ErrorText(fmt.Sprintf("|    %s", err), io.UserOutput)
+		fmt.Fprintln(io.UserOutput, "|")
+		return
+	}
+
+	for _, a := range []struct {
+		name  string
+		value string
+	}{
+		{"modified", formatTime(attr.Modified)},
+		{"revision", attr.Revision},
+		{"size", fmt.Sprintf("%d bytes", attr.Size)},
+		{"checksum", attr.Checksum},
+		{"kms key", attr.KMSKeyID},
+		{"last writer", attr.LastWriter},
+	} {
+		if len(a.value) > 0 {
+			fmt.Fprintf(io.UserOutput, "|    |- %s: %s\n", a.name, a.value)
+		}
+	}
+
+	fmt.Fprintln(io.UserOutput, "|")
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.Local().Format("2006-01-02 15:04:05 MST")
+}
+
 func init() {
 	RootCmd.AddCommand(listCmd)
 
 	listCmd.Flags().StringVarP(&uo.Tags, "tags", "t", "", "Specify a list of tags used to filter files.")
 	listCmd.Flags().BoolVarP(&uo.ViewTags, "view-tags", "g", false, "Display a list of tags for each file.")
 	listCmd.Flags().BoolVarP(&uo.ViewVersions, "view-version", "v", false, "Display a list of versions for each file.")
+	listCmd.Flags().BoolVarP(&uo.ViewAttributes, "view-attributes", "m", false, "Display remote attributes for each file, like last modified time and checksum.")
 }
@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/subosito/gotenv"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/logger"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/remote"
+	"github.com/turnerlabs/cstore/v4/components/store"
+)
+
+var migrateTo string
+
+// migrateCmd represents the migrate command
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move file(s) to a different store.",
+	Long: `Move file(s) to a different store.
+
+Every version of the file is copied to the new store and read back to verify the copy matches. Environment and JSON files match when their values match, since stores like Parameter Store rebuild them without comments or key order; other files must match byte for byte. Only after all copies are verified is the catalog updated and the file purged from the old store.`,
+	Run: func(cmd *cobra.Command, userSpecifiedFilePaths []string) {
+		setupUserOptions(userSpecifiedFilePaths)
+
+		if err := Migrate(uo, migrateTo, ioStreams); err != nil {
+			display.Error(fmt.Errorf("%s for %s", err, uo.Catalog), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+// Migrate ...
+func Migrate(opt cfg.UserOptions, to string, io models.IO) error {
+	migrated := 0
+	fileCount := 0
+
+	if len(to) == 0 {
+		return errors.New("store required")
+	}
+
+	if _, found := store.Get()[to]; !found {
+		return contract.ErrStoreNotFound
+	}
+
+	//-------------------------------------------------
+	//- Get the local catalog for reference.
+	//-------------------------------------------------
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(io.UserOutput)
+	for _, fileEntry := range clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, "") {
+		if fileEntry.IsRef {
+			continue
+		}
+
+		fileCount++
+
+		fmt.Fprint(io.UserOutput, "Migrating [")
+		color.New(color.FgBlue).Fprintf(io.UserOutput, fileEntry.ActualPath())
+		fmt.Fprintf(io.UserOutput, "] [%s] -> [", fileEntry.Store)
+		color.New(color.Bold).Fprintf(io.UserOutput, to)
+		fmt.Fprintln(io.UserOutput, "]")
+
+		if fileEntry.Store == to {
+			display.ErrorText(fmt.Sprintf("%s is already stored in %s", fileEntry.ActualPath(), to), io.UserOutput)
+			continue
+		}
+
+		if err := migrate(clog, fileEntry, to, opt, io); err != nil {
+			display.Error(fmt.Errorf("Migration aborted for %s! (%s)", fileEntry.ActualPath(), err), io.UserOutput)
+			continue
+		}
+
+		migrated++
+	}
+
+	color.New(color.Bold).Fprintf(io.UserOutput, "\n%d of %d file(s) migrated to %s.\n\n", migrated, fileCount, to)
+
+	return nil
+}
+
+func migrate(clog catalog.Catalog, fileEntry catalog.File, to string, opt cfg.UserOptions, io models.IO) error {
+	versions := append([]string{none}, fileEntry.Versions...)
+
+	//-------------------------------------------------
+	//- Pull every version from the old store.
+	//-------------------------------------------------
+	oldEntry := remote.OverrideFileSettings(fileEntry, opt)
+
+	oldComp, err := remote.InitComponents(&oldEntry, clog, opt, io)
+	if err != nil {
+		return err
+	}
+
+	//-------------------------------------------------
+	//- Files pushed only with a version may not have
+	//- an unversioned copy.
+	//-------------------------------------------------
+	if len(fileEntry.Versions) > 0 {
+		if modified, err := oldComp.Store.Changed(&oldEntry, nil, none); err == nil && modified.IsZero() {
+			versions = versions[1:]
+		}
+	}
+
+	data := map[string][]byte{}
+
+	for _, version := range versions {
+		if data[version], _, err = oldComp.Store.Pull(&oldEntry, version); err != nil {
+			return fmt.Errorf("failed to pull %s from %s (%s)", formatVersion(version), oldEntry.Store, err)
+		}
+	}
+
+	//-------------------------------------------------
+	//- Ensure the new store can hold every version
+	//- before anything is pushed.
+	//-------------------------------------------------
+	newEntry := store.Moved(oldEntry, to)
+
+	newComp, err := remote.InitComponents(&newEntry, clog, opt, io)
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions {
+		if err := store.ValidatePush(newComp.Store, newEntry, data[version], version); err != nil {
+			return err
+		}
+	}
+
+	//-------------------------------------------------
+	//- Push every version to the new store and verify
+	//- the copy matches.
+	//-------------------------------------------------
+	revision := ""
+
+	for i, version := range versions {
+		rev, err := store.PushIf(newComp.Store, &newEntry, data[version], version, "")
+		if err == nil {
+			err = verify(newComp.Store, &newEntry, data[version], version)
+		}
+
+		if err != nil {
+			rollback(clog, newComp, &newEntry, versions[:i+1], opt, io)
+			return err
+		}
+
+		if version == none {
+			revision = rev
+		}
+	}
+
+	//-------------------------------------------------
+	//- Save the catalog with the new store.
+	//-------------------------------------------------
+	clog.Files[fileEntry.Key()] = newEntry
+
+	if err := catalog.Write(clog.GetFullPath(opt.Catalog), clog); err != nil {
+		return err
+	}
+
+	if err := clog.RecordPull(fileEntry.Key(), time.Now().Add(time.Second*1), none, revision); err != nil {
+		logger.L.Print(err)
+	}
+
+	//-------------------------------------------------
+	//- Purge the old store copies last, so the file
+	//- is never missing from both stores. Files kept
+	//- in source control are left for the user.
+	//-------------------------------------------------
+	if oldComp.Store.Capabilities().SourceControl {
+		return nil
+	}
+
+	for i := len(versions) - 1; i >= 0; i-- {
+		if err := oldComp.Store.Purge(&oldEntry, versions[i]); err != nil {
+			display.Warn(fmt.Errorf("%s migrated, but %s was not purged from %s (%s)", fileEntry.ActualPath(), formatVersion(versions[i]), fileEntry.Store, err), io.UserOutput)
+		}
+	}
+
+	return nil
+}
+
+//-------------------------------------------------
+//- Purge the copies pushed to the new store without
+//- prompting, since the user only asked to migrate.
+//-------------------------------------------------
+func rollback(clog catalog.Catalog, comp remote.Components, file *catalog.File, versions []string, opt cfg.UserOptions, io models.IO) {
+	if comp.Store.Capabilities().SourceControl {
+		return
+	}
+
+	opt.Silent = true
+
+	if err := comp.Store.Pre(clog, file, comp.Access, opt, io); err != nil {
+		logger.L.Print(err)
+		return
+	}
+
+	for _, v := range versions {
+		if err := comp.Store.Purge(file, v); err != nil {
+			logger.L.Print(err)
+		}
+	}
+}
+
+//-------------------------------------------------
+//- Compare the stored copy with the data pushed.
+//-------------------------------------------------
+func verify(s contract.IStore, file *catalog.File, expected []byte, version string) error {
+	actual, _, err := s.Pull(file, version)
+	if err != nil {
+		return fmt.Errorf("failed to verify %s in %s (%s)", formatVersion(version), s.Name(), err)
+	}
+
+	if !sameContent(file.Type, expected, actual) {
+		return fmt.Errorf("%s in %s does not match the original copy", formatVersion(version), s.Name())
+	}
+
+	return nil
+}
+
+//-------------------------------------------------
+//- Stores may rebuild environment and JSON files
+//- from their values, dropping comments and
+//- changing the order of keys, so only the values
+//- are compared.
+//-------------------------------------------------
+func sameContent(fileType string, expected, actual []byte) bool {
+	switch fileType {
+	case store.EnvFeature:
+		return reflect.DeepEqual(gotenv.Parse(bytes.NewReader(expected)), gotenv.Parse(bytes.NewReader(actual)))
+	case store.JSONFeature:
+		var e, a interface{}
+
+		if json.Unmarshal(expected, &e) != nil || json.Unmarshal(actual, &a) != nil {
+			return bytes.Equal(expected, actual)
+		}
+
+		return reflect.DeepEqual(e, a)
+	default:
+		return bytes.Equal(expected, actual)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+
+	migrateCmd.Flags().StringVarP(&migrateTo, "to", "", "", "Store to move the file(s) to. The 'stores' command lists options.")
+}
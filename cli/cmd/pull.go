@@ -11,8 +11,10 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/turnerlabs/cstore/v4/components/cache"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/env"
 	localFile "github.com/turnerlabs/cstore/v4/components/file"
@@ -20,6 +22,7 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/path"
 	"github.com/turnerlabs/cstore/v4/components/remote"
+	"github.com/turnerlabs/cstore/v4/components/store"
 	"github.com/turnerlabs/cstore/v4/components/token"
 )
 
@@ -31,6 +34,9 @@ var pullCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, userSpecifiedFilePaths []string) {
 		setupUserOptions(userSpecifiedFilePaths)
 
+		uo.Cache = viper.GetBool(cacheToken)
+		uo.CacheMaxAge = viper.GetDuration(cacheMaxAgeToken)
+
 		if count, total, err := Pull(uo.Catalog, uo, ioStreams); err != nil {
 			display.Error(fmt.Errorf("%s for %s", err, uo.Catalog), ioStreams.UserOutput)
 			os.Exit(1)
@@ -106,22 +112,33 @@ func Pull(catalogPath string, opt cfg.UserOptions, io models.IO) (int, int, erro
 		fileCount++
 
 		//----------------------------------------------------
-		//- Get the remote store and vaults components ready.
+		//- Pull remote file from store or, when the store
+		//- fails, from the cache if the user allows it.
 		//----------------------------------------------------
-		fileEntryTemp := fileEntry
-		remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
-		if err != nil {
-			display.Error(fmt.Errorf("PullFailedException3: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err), io.UserOutput)
-			continue
-		}
+		fromCache := false
 
-		//----------------------------------------------------
-		//- Pull remote file from store.
-		//----------------------------------------------------
-		file, _, err := remoteComp.Store.Pull(&fileEntry, opt.Version)
+		remoteComp, file, attr, err := pullRemote(clog, root, &fileEntry, opt, io)
 		if err != nil {
-			display.Error(fmt.Errorf("PullFailedException4: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err), io.UserOutput)
-			continue
+			if !opt.Cache {
+				display.Error(err, io.UserOutput)
+				continue
+			}
+
+			cachedComp, entry, cacheErr := pullCache(clog, fileEntry, opt, io)
+			if cacheErr != nil {
+				display.Error(err, io.UserOutput)
+				display.Error(fmt.Errorf("CacheMissException: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), cacheErr), io.UserOutput)
+				continue
+			}
+
+			display.Warn(fmt.Errorf("%s served from cache pulled %s ago, because the remote store failed (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), entry.Age(), err), io.UserOutput)
+
+			remoteComp, file, attr, fromCache = cachedComp, entry.Data, entry.Attributes, true
+
+		} else if opt.Cache {
+			if err := cache.Save(clog, fileEntry, opt.Version, file, attr, remoteComp.Access); err != nil {
+				display.Warn(fmt.Errorf("%s was not cached (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err), io.UserOutput)
+			}
 		}
 
 		//----------------------------------------------------
@@ -227,15 +244,33 @@ func Pull(catalogPath string, opt cfg.UserOptions, io models.IO) (int, int, erro
 		}
 
 		fmt.Fprint(io.UserOutput, " <- [")
-		color.New(color.Bold).Fprintf(io.UserOutput, remoteComp.Store.Name())
+		if fromCache {
+			color.New(color.Bold, color.FgYellow).Fprintf(io.UserOutput, "cache")
+		} else {
+			color.New(color.Bold).Fprintf(io.UserOutput, remoteComp.Store.Name())
+		}
 		fmt.Fprintln(io.UserOutput, "]")
 
 		restoredCount++
 
 		//-------------------------------------------------
-		//- Save the time the user last pulled file.
+		//- Cached copies are not a pull from the store.
+		//-------------------------------------------------
+		if fromCache {
+			continue
+		}
+
 		//-------------------------------------------------
-		if err := clog.RecordPull(fileEntry.Key(), time.Now(), opt.Version); err != nil {
+		//- Save the time and revision the user last pulled.
+		//-------------------------------------------------
+		revision := attr.Revision
+		if len(revision) == 0 {
+			if revision, err = remoteComp.Store.Revision(&fileEntry, opt.Version); err != nil {
+				logger.L.Print(err)
+			}
+		}
+
+		if err := clog.RecordPull(fileEntry.Key(), time.Now(), opt.Version, revision); err != nil {
 			logger.L.Print(err)
 			continue
 		}
@@ -286,6 +321,44 @@ func Pull(catalogPath string, opt cfg.UserOptions, io models.IO) (int, int, erro
 	return restoredCount, fileCount, nil
 }
 
+//----------------------------------------------------
+//- Get the remote store and vaults components ready
+//- and pull the file.
+//----------------------------------------------------
+func pullRemote(clog catalog.Catalog, root string, fileEntry *catalog.File, opt cfg.UserOptions, io models.IO) (remote.Components, []byte, contract.Attributes, error) {
+	fileEntryTemp := *fileEntry
+	remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
+	if err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedException3: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err)
+	}
+
+	if err := store.ValidatePull(remoteComp.Store, *fileEntry, opt.Version); err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedException3: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err)
+	}
+
+	file, attr, err := remoteComp.Store.Pull(fileEntry, opt.Version)
+	if err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedException4: %s (%s)", getPath(root, fileEntry.ActualPath(), opt.Version), err)
+	}
+
+	return remoteComp, file, attr, nil
+}
+
+//----------------------------------------------------
+//- Get the vaults ready without the store and read
+//- the last pulled copy of the file.
+//----------------------------------------------------
+func pullCache(clog catalog.Catalog, fileEntry catalog.File, opt cfg.UserOptions, io models.IO) (remote.Components, cache.Entry, error) {
+	remoteComp, err := remote.InitVaults(&fileEntry, clog, opt, io)
+	if err != nil {
+		return remoteComp, cache.Entry{}, err
+	}
+
+	entry, err := cache.Get(clog, fileEntry, opt.Version, opt.CacheMaxAge, remoteComp.Access)
+
+	return remoteComp, entry, err
+}
+
 func getPath(root, filepath, version string) string {
 
 	if len(version) > 0 {
@@ -313,6 +386,8 @@ const (
 	injectToken      = "inject-secrets"
 	modifyToken      = "modify-secrets"
 	noOverwriteToken = "no-overwrite"
+	cacheToken       = "cache"
+	cacheMaxAgeToken = "cache-max-age"
 )
 
 func init() {
@@ -326,6 +401,8 @@ func init() {
 	pullCmd.Flags().BoolVarP(&uo.ModifySecrets, modifyToken, "m", false, "Pulls configuration with secret tokens and secrets.")
 	pullCmd.Flags().StringVarP(&uo.AlternateRestorePath, altToken, "a", "", "Set an alternate path to clone the file to during a restore.")
 	pullCmd.Flags().BoolVarP(&uo.NoOverwrite, noOverwriteToken, "n", false, "Only pulls the environment variables that are not exported in the current environment.")
+	pullCmd.Flags().BoolVarP(&uo.Cache, cacheToken, "", false, "Cache pulled files encrypted locally and use the cached copy when the remote store fails.")
+	pullCmd.Flags().DurationVarP(&uo.CacheMaxAge, cacheMaxAgeToken, "", 24*time.Hour, "Oldest cached copy to use when the remote store fails. Zero accepts any age.")
 
 	viper.BindPFlag(exportToken, RootCmd.PersistentFlags().Lookup(exportToken))
 	viper.BindPFlag(tagsToken, RootCmd.PersistentFlags().Lookup(tagsToken))
@@ -334,4 +411,8 @@ func init() {
 	viper.BindPFlag(modifyToken, RootCmd.PersistentFlags().Lookup(modifyToken))
 	viper.BindPFlag(altToken, RootCmd.PersistentFlags().Lookup(altToken))
 	viper.BindPFlag(noOverwriteToken, RootCmd.PersistentFlags().Lookup(noOverwriteToken))
+	viper.BindPFlag(cacheToken, pullCmd.Flags().Lookup(cacheToken))
+	viper.BindPFlag(cacheMaxAgeToken, pullCmd.Flags().Lookup(cacheMaxAgeToken))
+	viper.BindEnv(cacheToken, "CSTORE_CACHE")
+	viper.BindEnv(cacheMaxAgeToken, "CSTORE_CACHE_MAX_AGE")
 }
@@ -7,6 +7,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/turnerlabs/cstore/v4/components/cache"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/display"
@@ -15,6 +16,7 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/path"
 	"github.com/turnerlabs/cstore/v4/components/prompt"
 	"github.com/turnerlabs/cstore/v4/components/remote"
+	"github.com/turnerlabs/cstore/v4/components/store"
 )
 
 // purgeCmd represents the purge command
@@ -103,6 +105,11 @@ func Purge(opt cfg.UserOptions, io models.IO) error {
 			continue
 		}
 
+		if err := store.ValidatePurge(remoteComp.Store, fileEntry, opt.Version); err != nil {
+			display.Error(fmt.Errorf("Purge aborted for %s! (%s)", fileEntry.ActualPath(), err), ioStreams.UserOutput)
+			continue
+		}
+
 		//----------------------------------------------------
 		//- If version specified, delete it.
 		//----------------------------------------------------
@@ -111,6 +118,10 @@ func Purge(opt cfg.UserOptions, io models.IO) error {
 				logger.L.Print(err)
 			}
 
+			if err := cache.Purge(clog, fileEntry, opt.Version); err != nil {
+				logger.L.Print(err)
+			}
+
 			for i, ver := range fileEntry.Versions {
 				if ver == opt.Version {
 					fileEntry.Versions = append(fileEntry.Versions[:i], fileEntry.Versions[i+1:]...)
@@ -137,6 +148,10 @@ func Purge(opt cfg.UserOptions, io models.IO) error {
 					undeletedVersions = append(undeletedVersions, version)
 					continue
 				}
+
+				if err := cache.Purge(clog, fileEntry, version); err != nil {
+					logger.L.Print(err)
+				}
 			}
 
 			f := clog.Files[key]
@@ -152,6 +167,10 @@ func Purge(opt cfg.UserOptions, io models.IO) error {
 					continue
 				}
 
+				if err := cache.Purge(clog, fileEntry, none); err != nil {
+					logger.L.Print(err)
+				}
+
 				delete(clog.Files, key)
 				purged++
 			}
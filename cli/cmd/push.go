@@ -11,6 +11,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/display"
 	localFile "github.com/turnerlabs/cstore/v4/components/file"
 	"github.com/turnerlabs/cstore/v4/components/logger"
@@ -111,6 +112,33 @@ func Push(opt cfg.UserOptions, io models.IO) error {
 		color.New(color.Bold).Fprintf(io.UserOutput, remoteComp.Store.Name())
 		fmt.Fprintln(io.UserOutput, "]")
 
+		//--------------------------------------------------------
+		//- Ensure the store can push the file before secrets or
+		//- the file are changed.
+		//--------------------------------------------------------
+		if err := store.ValidatePush(remoteComp.Store, fileEntry, file, opt.Version); err != nil {
+			display.Error(err, io.UserOutput)
+			continue
+		}
+
+		//--------------------------------------------------------
+		//- Ensure file has not been modified by another user
+		//- since the revision last pulled. The push is checked
+		//- again when stored, but secrets and the local file
+		//- are changed before then.
+		//--------------------------------------------------------
+		revision := fileEntry.PulledRevision(clog.Context, opt.Version)
+
+		if len(revision) > 0 {
+			if current, err := remoteComp.Store.Revision(&fileEntry, opt.Version); err != nil {
+				display.Error(err, io.UserOutput)
+				continue
+			} else if current != revision {
+				display.Error(conflictError(fileEntry), io.UserOutput)
+				continue
+			}
+		}
+
 		//--------------------------------------------------------
 		//- Ensure file has not been modified by another user and
 		//- is the correct version.
@@ -131,7 +159,7 @@ func Push(opt cfg.UserOptions, io models.IO) error {
 				}
 			}
 
-			if !current {
+			if !current && len(revision) == 0 {
 				if !prompt.Confirm(fmt.Sprintf("Remotely stored [%s] was modified %s. Overwrite?", fileEntry.ActualPath(), lastModified.Format("01/02/06")), prompt.Warn, io) {
 					fmt.Fprint(io.UserOutput, "Skipping [")
 					color.New(color.FgBlue).Fprintf(io.UserOutput, fileEntry.ActualPath())
@@ -169,23 +197,22 @@ func Push(opt cfg.UserOptions, io models.IO) error {
 		}
 
 		//-------------------------------------------------
-		//- Validate version and file version data.
+		//- Add the version to the file version data.
 		//-------------------------------------------------
-		if len(opt.Version) > 0 {
-			if !remoteComp.Store.SupportsFeature(store.VersionFeature) {
-				display.Error(fmt.Errorf("%s store does not support %s", remoteComp.Store.Name(), store.VersionFeature), io.UserOutput)
-				continue
-			}
-
-			if fileEntry.Missing(opt.Version) {
-				fileEntry.Versions = append(fileEntry.Versions, opt.Version)
-			}
+		if len(opt.Version) > 0 && fileEntry.Missing(opt.Version) {
+			fileEntry.Versions = append(fileEntry.Versions, opt.Version)
 		}
 
 		//-------------------------------------------------
-		//- Push file to file store.
+		//- Push file to file store when the remote file is
+		//- still the revision last pulled.
 		//-------------------------------------------------
-		if err = remoteComp.Store.Push(&fileEntry, file, opt.Version); err != nil {
+		revision, err = store.PushIf(remoteComp.Store, &fileEntry, file, opt.Version, revision)
+		if err != nil {
+			if err == contract.ErrRevisionConflict {
+				err = conflictError(fileEntry)
+			}
+
 			display.Error(err, io.UserOutput)
 			continue
 		}
@@ -201,7 +228,7 @@ func Push(opt cfg.UserOptions, io models.IO) error {
 		//-------------------------------------------------
 		//- Save the time the user last pulled file.
 		//-------------------------------------------------
-		if err := clog.RecordPull(fileEntry.Key(), time.Now().Add(time.Second*1), opt.Version); err != nil {
+		if err := clog.RecordPull(fileEntry.Key(), time.Now().Add(time.Second*1), opt.Version, revision); err != nil {
 			logger.L.Print(err)
 			continue
 		}
@@ -254,11 +281,16 @@ func formatVersion(version string) string {
 	return version
 }
 
+func conflictError(file catalog.File) error {
+	return fmt.Errorf("ConflictError: remotely stored [%s] changed since it was last pulled; pull the file, reapply changes, and push again", file.ActualPath())
+}
+
 const (
-	storeToken  = "store"
-	deleteToken = "delete"
-	tagsToken   = "tags"
-	altToken    = "alt"
+	storeToken   = "store"
+	deleteToken  = "delete"
+	encryptToken = "encrypt"
+	tagsToken    = "tags"
+	altToken     = "alt"
 )
 
 func init() {
@@ -266,12 +298,14 @@ func init() {
 
 	pushCmd.Flags().StringVarP(&uo.Store, storeToken, "s", "", "Set the context store used to store files. The 'stores' command lists options.")
 	pushCmd.Flags().StringVarP(&uo.DeleteLocalFiles, deleteToken, "d", "", "Delete the local file after any successful pushes.")
+	pushCmd.Flags().StringVarP(&uo.Encrypt, encryptToken, "e", "", "Encrypt the file before pushing using a key from the access vault.")
 	pushCmd.Flags().StringVarP(&uo.Tags, tagsToken, "t", "", "Set a list of tags used to identify the file.")
 	pushCmd.Flags().StringVarP(&uo.Version, "ver", "v", "", "Set a version to identify the file current state.")
 	pushCmd.Flags().StringVarP(&uo.AlternateRestorePath, altToken, "a", "", "Set an alternate path to clone the file to during a restore.")
 
 	viper.BindPFlag(storeToken, RootCmd.PersistentFlags().Lookup(storeToken))
 	viper.BindPFlag(deleteToken, RootCmd.PersistentFlags().Lookup(deleteToken))
+	viper.BindPFlag(encryptToken, RootCmd.PersistentFlags().Lookup(encryptToken))
 	viper.BindPFlag(tagsToken, RootCmd.PersistentFlags().Lookup(tagsToken))
 	viper.BindPFlag(altToken, RootCmd.PersistentFlags().Lookup(altToken))
 }
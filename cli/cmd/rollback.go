@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/remote"
+)
+
+var revision string
+
+// rollbackCmd represents the rollback command
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback",
+	Short: "Restore a prior copy of a file remotely.",
+	Long: `Restore a prior copy of a file remotely.
+
+The prior copy becomes the latest copy of the file in the store. Local files are not affected; pull the file to restore it locally.
+
+Use the 'history' command to view prior copies of a file.`,
+	Run: func(cmd *cobra.Command, userSpecifiedFilePaths []string) {
+		setupUserOptions(userSpecifiedFilePaths)
+
+		if err := Rollback(uo, revision, ioStreams); err != nil {
+			display.Error(fmt.Errorf("%s for %s", err, uo.Catalog), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+// Rollback ...
+func Rollback(opt cfg.UserOptions, revision string, io models.IO) error {
+
+	if len(revision) == 0 {
+		return errors.New("revision or version required")
+	}
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		return err
+	}
+
+	files := clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, "")
+
+	if len(files) != 1 {
+		return fmt.Errorf("rollback requires one file, %d files matched", len(files))
+	}
+
+	for _, fileEntry := range files {
+		fileEntryTemp := remote.OverrideFileSettings(fileEntry, opt)
+
+		remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
+		if err != nil {
+			return err
+		}
+
+		h, ok := remoteComp.Store.(contract.IHistory)
+		if !ok || !remoteComp.Store.Capabilities().History {
+			return contract.ErrHistoryNotSupported
+		}
+
+		if err := h.Rollback(&fileEntryTemp, revision); err != nil {
+			return fmt.Errorf("rollback failed for %s (%s)", fileEntry.ActualPath(), err)
+		}
+
+		fmt.Fprintf(io.UserOutput, "\n%s rolled back to %s. Pull the file to restore it locally.\n\n", fileEntry.ActualPath(), color.New(color.Bold).Sprint(revision))
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(rollbackCmd)
+
+	rollbackCmd.Flags().StringVarP(&revision, "revision", "r", "", "Revision id or version of the prior copy to restore.")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/plugin"
 )
 
 const (
@@ -44,7 +45,14 @@ var RootCmd = &cobra.Command{
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	err := RootCmd.Execute()
+
+	//------------------------------------------
+	//- Stop plugins started by the command.
+	//------------------------------------------
+	plugin.Close()
+
+	if err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
@@ -2,10 +2,14 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
 
-	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/store"
 )
 
@@ -26,16 +30,74 @@ var storesCmd = &cobra.Command{
 
 			fmt.Fprintf(ioStreams.UserOutput, "Use 'cstore stores STORE_NAME' cmd for details.\n")
 
-			for _, store := range store.Get() {
-				fmt.Print("|-")
-				color.New(color.FgBlue).Fprintf(ioStreams.UserOutput, "%s\n", store.Name())
-			}
-
+			fmt.Fprintln(ioStreams.UserOutput)
+			listStores(store.Get(), ioStreams.UserOutput)
 			fmt.Fprintln(ioStreams.UserOutput)
 		}
 	},
 }
 
+//-------------------------------------------------
+//- Print a matrix comparing store capabilities.
+//-------------------------------------------------
+func listStores(stores map[string]contract.IStore, out io.Writer) {
+	names := []string{}
+	for name := range stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "STORE\tVERSIONS\tHISTORY\tCONDITIONAL\tENCRYPTION\tBINARY\tMAX SIZE\tFILE TYPES")
+
+	for _, name := range names {
+		c := stores[name].Capabilities()
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			name,
+			yesNo(c.Versioning),
+			yesNo(c.History),
+			yesNo(c.ConditionalWrites),
+			yesNo(c.ClientEncryption),
+			yesNo(c.Binary),
+			formatSize(c.MaxSize),
+			formatFileTypes(c))
+	}
+
+	w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "-"
+}
+
+func formatSize(size int) string {
+	switch {
+	case size == 0:
+		return "-"
+	case size%1024 == 0:
+		return fmt.Sprintf("%d KB", size/1024)
+	default:
+		return fmt.Sprintf("%d B", size)
+	}
+}
+
+func formatFileTypes(c contract.Capabilities) string {
+	switch {
+	case c.NoFileTypes:
+		return "none"
+	case len(c.FileTypes) == 0:
+		return "any"
+	default:
+		return strings.Join(c.FileTypes, ",")
+	}
+}
+
 func init() {
 	RootCmd.AddCommand(storesCmd)
 }
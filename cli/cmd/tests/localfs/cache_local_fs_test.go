@@ -0,0 +1,141 @@
+package localfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+)
+
+//---------------------------------------------------
+//- When the remote store fails, a cached pull should
+//- be served unless it is older than allowed.
+//---------------------------------------------------
+func TestEnsureCachedFileIsPulledWhenStoreFails(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	os.Setenv("CSTORE_CACHE_KEY", "ZGV2LWtleS0zMi1ieXRlcy1sb25nLWZvci10ZXN0cyE=")
+	defer os.Unsetenv("CSTORE_CACHE_KEY")
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pullOpt := cfg.UserOptions{Catalog: opt.Catalog, Cache: true, CacheMaxAge: time.Hour}
+
+	if count, _, err := cmd.Pull(opt.Catalog, pullOpt, makeIO(testWriter, testWriter)); err != nil || count != 1 {
+		t.Fatalf("\nEXPECTED: %d \nACTUAL: %d (%v)", 1, count, err)
+	}
+
+	stored := filepath.Join(StoreDir, clog.Context, f)
+	if err := os.Rename(stored, stored+".offline"); err != nil {
+		panic(err)
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	// act
+	count, _, err := cmd.Pull(opt.Catalog, pullOpt, makeIO(testWriter, testWriter))
+
+	// assert
+	if err != nil || count != 1 {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d (%v)", 1, count, err)
+	}
+
+	if b, err := ioutil.ReadFile(f); err != nil || string(b) != data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", data, string(b), err)
+	}
+
+	pullOpt.CacheMaxAge = time.Nanosecond
+
+	if count, _, _ := cmd.Pull(opt.Catalog, pullOpt, makeIO(testWriter, testWriter)); count != 0 {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d", 0, count)
+	}
+
+	if err := os.Rename(stored+".offline", stored); err != nil {
+		panic(err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When the cache key cannot be generated, the pull
+//- should succeed and warn how to provide the key.
+//---------------------------------------------------
+func TestEnsurePullSucceedsWhenFileCannotBeCached(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	os.Unsetenv("CSTORE_CACHE_KEY")
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	output := &bytes.Buffer{}
+
+	// act
+	count, _, err := cmd.Pull(opt.Catalog, cfg.UserOptions{Catalog: opt.Catalog, Cache: true}, makeIO(output, testWriter))
+
+	// assert
+	if err != nil || count != 1 {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d (%v)", 1, count, err)
+	}
+
+	if b, err := ioutil.ReadFile(f); err != nil || string(b) != data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", data, string(b), err)
+	}
+
+	if !strings.Contains(output.String(), "export CSTORE_CACHE_KEY=") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "cache key hint", output.String())
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
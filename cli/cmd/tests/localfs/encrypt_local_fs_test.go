@@ -0,0 +1,72 @@
+package localfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+)
+
+//---------------------------------------------------
+//- When a file is pushed with encryption, the store
+//- should only contain ciphertext and the file should
+//- be decrypted when pulled.
+//---------------------------------------------------
+func TestEnsureEncryptedFileIsUnreadableInStoreAndRetrievedAfterPush(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	os.Setenv("CSTORE_ENCRYPTION_KEY", "ZGV2LWtleS0zMi1ieXRlcy1sb25nLWZvci10ZXN0cyE=")
+	defer os.Unsetenv("CSTORE_ENCRYPTION_KEY")
+
+	context := fmt.Sprintf("%s-%s", Context, t.Name())
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "DB_PASSWORD=shh..."
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+		Encrypt: "true",
+	}
+
+	// act
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, context, StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	cmd.Pull(opt.Catalog, cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter))
+
+	// assert stored file is encrypted
+	stored, err := ioutil.ReadFile(filepath.Join(StoreDir, context, f))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(stored), data) {
+		t.Errorf("\nEXPECTED: ciphertext\nACTUAL: %s", string(stored))
+	}
+
+	// assert pulled file is decrypted
+	if file, err := ioutil.ReadFile(f); err != nil {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: file missing", f)
+		t.Error(err)
+	} else if string(file) != data {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", data, string(file))
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
@@ -0,0 +1,248 @@
+package localfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/subosito/gotenv"
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+//---------------------------------------------------
+//- When a file is migrated, the catalog should point
+//- to the new store and the old copy be purged.
+//---------------------------------------------------
+func TestEnsureMigratedFileIsMovedToNewStore(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := filepath.Join(StoreDir, clog.Context, f)
+
+	if _, err := os.Stat(stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	if err := cmd.Migrate(cfg.UserOptions{Catalog: opt.Catalog}, "source-control", makeIO(testWriter, testWriter)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	clog, err = catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range clog.Files {
+		if file.Store != "source-control" {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "source-control", file.Store)
+		}
+	}
+
+	if _, err := os.Stat(stored); !os.IsNotExist(err) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "old copy purged", err)
+	}
+
+	if b, err := ioutil.ReadFile(f); err != nil || string(b) != data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", data, string(b), err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When the new store cannot hold every version of
+//- a file, the file should stay in the old store.
+//---------------------------------------------------
+func TestEnsureMigrationIsAbortedWhenStoreCannotHoldVersions(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+
+	if err := ioutil.WriteFile(f, []byte("VER=1"), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+		Version: "v1.0.0",
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stored := filepath.Join(StoreDir, clog.Context, opt.Version, f)
+
+	// act
+	if err := cmd.Migrate(cfg.UserOptions{Catalog: opt.Catalog}, "source-control", makeIO(testWriter, testWriter)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	clog, err = catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range clog.Files {
+		if file.Store != Store {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", Store, file.Store)
+		}
+	}
+
+	if _, err := os.Stat(stored); err != nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "old copy kept", err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When the new store rebuilds a file from its values,
+//- the copy should be verified by its values and the
+//- file moved.
+//---------------------------------------------------
+func TestEnsureMigrationIsVerifiedByValuesWhenStoreRebuildsFile(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "# settings\nZONE=east\nAPP=web\n"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	if err := cmd.Migrate(cfg.UserOptions{Catalog: opt.Catalog}, NormalizedStore, makeIO(testWriter, testWriter)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range clog.Files {
+		if file.Store != NormalizedStore {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", NormalizedStore, file.Store)
+		}
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+// normalizedStore keeps env files as values and rebuilds them sorted by
+// key without comments when pulled.
+type normalizedStore struct {
+	files map[string][]byte
+	clog  catalog.Catalog
+}
+
+func (s normalizedStore) Name() string        { return NormalizedStore }
+func (s normalizedStore) Description() string { return "rebuilds env files from values" }
+
+func (s normalizedStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{Versioning: true, FileTypes: []string{"env"}}
+}
+
+func (s *normalizedStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+	return nil
+}
+
+func (s normalizedStore) key(file *catalog.File, version string) string {
+	return s.clog.Context + "/" + version + "/" + file.Path
+}
+
+func (s normalizedStore) Push(file *catalog.File, fileData []byte, version string) error {
+	env := gotenv.Parse(bytes.NewReader(fileData))
+
+	keys := []string{}
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%s\n", k, env[k])
+	}
+
+	s.files[s.key(file, version)] = b.Bytes()
+	return nil
+}
+
+func (s normalizedStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+	b, found := s.files[s.key(file, version)]
+	if !found {
+		return nil, contract.Attributes{}, os.ErrNotExist
+	}
+
+	return b, contract.Attributes{Size: len(b)}, nil
+}
+
+func (s normalizedStore) Purge(file *catalog.File, version string) error {
+	delete(s.files, s.key(file, version))
+	return nil
+}
+
+func (s normalizedStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (s normalizedStore) Revision(file *catalog.File, version string) (string, error) {
+	return "", nil
+}
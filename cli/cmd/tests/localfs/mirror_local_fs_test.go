@@ -0,0 +1,111 @@
+package localfs
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+)
+
+// pushMirrored pushes a file to a mirror of two local-fs stores and
+// returns the location of the file in each store.
+func pushMirrored(t *testing.T, f, data string) (string, string, cfg.UserOptions) {
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	primaryDir := filepath.Join(StoreDir, t.Name(), "primary")
+	secondaryDir := filepath.Join(StoreDir, t.Name(), "secondary")
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   "mirror",
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), Store, Store, primaryDir, secondaryDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return filepath.Join(primaryDir, clog.Context, f), filepath.Join(secondaryDir, clog.Context, f), opt
+}
+
+//---------------------------------------------------
+//- When a file is pushed to a mirror, each replica
+//- should have a copy and pulls should fall back to
+//- a secondary when the primary fails.
+//---------------------------------------------------
+func TestEnsureMirroredFileIsPulledFromSecondary(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	primary, secondary, opt := pushMirrored(t, f, data)
+
+	for _, p := range []string{primary, secondary} {
+		if b, err := ioutil.ReadFile(p); err != nil || string(b) != data {
+			t.Fatalf("\nEXPECTED: %s \nACTUAL: %s (%v)", data, string(b), err)
+		}
+	}
+
+	if err := os.Remove(primary); err != nil {
+		panic(err)
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	// act
+	cmd.Pull(opt.Catalog, cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter))
+
+	// assert
+	if b, err := ioutil.ReadFile(f); err != nil || string(b) != data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", data, string(b), err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When a replica no longer matches the primary,
+//- verification should report drift.
+//---------------------------------------------------
+func TestEnsureReplicaDriftIsReported(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+
+	_, secondary, opt := pushMirrored(t, f, "ENV=dev")
+
+	if err := cmd.VerifyReplicas(cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter)); err != nil {
+		t.Fatalf("\nEXPECTED: %v \nACTUAL: %s", nil, err)
+	}
+
+	if err := ioutil.WriteFile(secondary, []byte("ENV=changed"), 0600); err != nil {
+		panic(err)
+	}
+
+	// act
+	err := cmd.VerifyReplicas(cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter))
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "replicas are out of sync", err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
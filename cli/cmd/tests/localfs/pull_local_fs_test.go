@@ -0,0 +1,78 @@
+package localfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4"
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+)
+
+//---------------------------------------------------
+//- When a file is pulled using the library, the
+//- remote attributes of the file should be returned
+//- with the file data.
+//---------------------------------------------------
+func TestEnsureLibraryPullReturnsFileAttributes(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	files, err := cstore.PullFiles(opt.Catalog, cstore.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if len(files) != 1 {
+		t.Fatalf("\nEXPECTED: %d \nACTUAL: %d", 1, len(files))
+	}
+
+	sum := sha256.Sum256([]byte(data))
+	checksum := hex.EncodeToString(sum[:])
+
+	attr := files[0].Attributes
+
+	if string(files[0].Data) != data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", data, string(files[0].Data))
+	}
+
+	if attr.Size != len(data) {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d", len(data), attr.Size)
+	}
+
+	if attr.Checksum != checksum {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", checksum, attr.Checksum)
+	}
+
+	if len(attr.Revision) == 0 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "a revision", "none")
+	}
+
+	if attr.Modified.IsZero() {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "a modified time", "none")
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
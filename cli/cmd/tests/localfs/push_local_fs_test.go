@@ -0,0 +1,382 @@
+package localfs
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+)
+
+//---------------------------------------------------
+//- When a file is pushed to the local file system
+//- store, it should be retrieved after the local
+//- file is removed.
+//---------------------------------------------------
+func TestEnsureFileCanBeRetrievedAfterPush(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	data := "ENV=dev"
+
+	if err := ioutil.WriteFile(f, []byte(data), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	// act
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	cmd.Pull(opt.Catalog, opt, makeIO(testWriter, testWriter))
+
+	// assert
+	if file, err := ioutil.ReadFile(f); err != nil {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: file missing", f)
+		t.Error(err)
+	} else if string(file) != data {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", data, string(file))
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When a file is versioned, the version should be
+//- restored when pulled specifically.
+//---------------------------------------------------
+func TestEnsurePushedVersionCanBeRetrieved(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+
+	versions := []file{
+		file{
+			io:      makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir),
+			data:    "VER=1",
+			version: "v1.0.0",
+		},
+		file{
+			io:      makeIO(testWriter, testWriter, StoreDir),
+			data:    "VER=2",
+			version: "v2.0.0",
+		},
+	}
+
+	// act
+	for _, v := range versions {
+		if err := ioutil.WriteFile(f, []byte(v.data), 0644); err != nil {
+			panic(err)
+		}
+
+		opt := cfg.UserOptions{
+			Catalog: fmt.Sprintf("%s.yml", t.Name()),
+			Paths:   []string{f},
+			Store:   Store,
+			Version: v.version,
+		}
+
+		if err := cmd.Push(opt, v.io); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Version: versions[0].version,
+	}
+
+	cmd.Pull(opt.Catalog, opt, makeIO(testWriter, testWriter))
+
+	// assert
+	if file, err := ioutil.ReadFile(f); err != nil {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: file missing", versions[0].version)
+		t.Error(err)
+	} else if string(file) != versions[0].data {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", versions[0].data, string(file))
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When a version is purged, the remaining versions
+//- should still be retrievable.
+//---------------------------------------------------
+func TestEnsurePurgedVersionDoesNotRemoveOtherVersions(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+
+	versions := []file{
+		file{
+			io:      makeIO(testWriter, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir),
+			data:    "VER=1",
+			version: "v1.0.0",
+		},
+		file{
+			io:      makeIO(testWriter, testWriter, StoreDir),
+			data:    "VER=2",
+			version: "v2.0.0",
+		},
+	}
+
+	for _, v := range versions {
+		if err := ioutil.WriteFile(f, []byte(v.data), 0644); err != nil {
+			panic(err)
+		}
+
+		opt := cfg.UserOptions{
+			Catalog: fmt.Sprintf("%s.yml", t.Name()),
+			Paths:   []string{f},
+			Store:   Store,
+			Version: v.version,
+		}
+
+		if err := cmd.Push(opt, v.io); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := os.Remove(f); err != nil {
+		panic(err)
+	}
+
+	// act
+	purgeOpt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Version: versions[0].version,
+	}
+
+	if err := cmd.Purge(purgeOpt, makeIO(testWriter, testWriter, "y")); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Version: versions[1].version,
+	}
+
+	cmd.Pull(opt.Catalog, opt, makeIO(testWriter, testWriter))
+
+	if file, err := ioutil.ReadFile(f); err != nil {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: file missing", versions[1].version)
+		t.Error(err)
+	} else if string(file) != versions[1].data {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", versions[1].data, string(file))
+	}
+
+	if _, err := os.Stat(fmt.Sprintf("%s/%s-%s/%s/%s", StoreDir, Context, t.Name(), versions[0].version, f)); !os.IsNotExist(err) {
+		t.Errorf("\nEXPECTED: %s purged\nACTUAL: %s exists", versions[0].version, versions[0].version)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When the stored file changes after it was pulled,
+//- a push should be rejected instead of overwriting
+//- the other change.
+//---------------------------------------------------
+func TestEnsurePushIsRejectedWhenFileChangedSincePull(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	context := fmt.Sprintf("%s-%s", Context, t.Name())
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	stored := filepath.Join(StoreDir, context, f)
+
+	if err := ioutil.WriteFile(f, []byte("ENV=first"), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, context, StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.Pull(opt.Catalog, cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter))
+
+	if err := ioutil.WriteFile(stored, []byte("ENV=other-user"), 0600); err != nil {
+		panic(err)
+	}
+
+	// the other change is not newer than the pull, like
+	// two pushes within the same second.
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stored, past, past); err != nil {
+		panic(err)
+	}
+
+	if err := ioutil.WriteFile(f, []byte("ENV=stale"), 0644); err != nil {
+		panic(err)
+	}
+
+	// act
+	if err := cmd.Push(cfg.UserOptions{Catalog: opt.Catalog, Paths: []string{f}}, makeIO(testWriter, testWriter)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if b, err := ioutil.ReadFile(stored); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "ENV=other-user" {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", "ENV=other-user", string(b))
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When a push is rejected because the stored file
+//- changed, secrets should not be saved and the local
+//- file should keep its tokens.
+//---------------------------------------------------
+func TestEnsureRejectedPushLeavesSecretsAndLocalFileUnchanged(t *testing.T) {
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	home, err := ioutil.TempDir("", "cstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+
+	previous := os.Getenv("HOME")
+	os.Setenv("HOME", home)
+	homedir.DisableCache = true
+	defer func() {
+		os.Setenv("HOME", previous)
+		homedir.DisableCache = false
+	}()
+
+	context := fmt.Sprintf("%s-%s", Context, t.Name())
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+	stored := filepath.Join(StoreDir, context, f)
+
+	if err := ioutil.WriteFile(f, []byte("ENV=first"), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog:      fmt.Sprintf("%s.yml", t.Name()),
+		Paths:        []string{f},
+		Store:        Store,
+		SecretsVault: "file",
+	}
+
+	if err := cmd.Push(opt, makeIO(testWriter, testWriter, context, StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd.Pull(opt.Catalog, cfg.UserOptions{Catalog: opt.Catalog}, makeIO(testWriter, testWriter))
+
+	if err := ioutil.WriteFile(stored, []byte("ENV=other-user"), 0600); err != nil {
+		panic(err)
+	}
+
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(stored, past, past); err != nil {
+		panic(err)
+	}
+
+	stale := "PASSWORD={{dev/PASSWORD::stale}}"
+
+	if err := ioutil.WriteFile(f, []byte(stale), 0644); err != nil {
+		panic(err)
+	}
+
+	// act
+	if err := cmd.Push(cfg.UserOptions{Catalog: opt.Catalog, Paths: []string{f}, SecretsVault: "file"}, makeIO(testWriter, testWriter)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if b, err := ioutil.ReadFile(stored); err != nil {
+		t.Fatal(err)
+	} else if string(b) != "ENV=other-user" {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", "ENV=other-user", string(b))
+	}
+
+	if b, err := ioutil.ReadFile(f); err != nil || string(b) != stale {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s (%v)", stale, string(b), err)
+	}
+
+	if value, err := vault.Get()["file"].Get(context, "dev/password", "password"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s (%v)", "secret not saved", value, err)
+	}
+
+	cleanupOutput(fmt.Sprintf("%s.yml", t.Name()))
+}
+
+//---------------------------------------------------
+//- When a version would place the file outside of the
+//- store directory, the push should be rejected.
+//---------------------------------------------------
+func TestEnsureVersionCannotLeaveStoreDir(t *testing.T) {
+	os.Remove(fmt.Sprintf("%s.yml", t.Name()))
+
+	// arrange
+	f := fmt.Sprintf("%s/%s.env", TestDataDir, t.Name())
+
+	if err := ioutil.WriteFile(f, []byte("ENV=dev"), 0644); err != nil {
+		panic(err)
+	}
+
+	opt := cfg.UserOptions{
+		Catalog: fmt.Sprintf("%s.yml", t.Name()),
+		Paths:   []string{f},
+		Store:   Store,
+		Version: "../../escaped",
+	}
+
+	var output bytes.Buffer
+
+	// act
+	if err := cmd.Push(opt, makeIO(&output, testWriter, fmt.Sprintf("%s-%s", Context, t.Name()), StoreDir)); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if !strings.Contains(output.String(), "invalid version") {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", "invalid version", output.String())
+	}
+
+	if _, err := os.Stat(filepath.Join(TestDataDir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("\nEXPECTED: %s\nACTUAL: %s", "nothing written outside the context", "escaped exists")
+	}
+
+	os.Remove(opt.Catalog)
+}
@@ -0,0 +1,124 @@
+package localfs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/cli/cmd"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/plugin"
+)
+
+const (
+	Context     = "automated"
+	TestDataDir = "temp"
+	StoreDir    = "temp/store"
+	Store       = "local-fs"
+
+	// NormalizedStore rebuilds env files from their values, like
+	// Parameter Store.
+	NormalizedStore = "normalized"
+)
+
+// uncomment when debugging tests locally
+//var testWriter = os.Stderr
+var testWriter = ioutil.Discard
+
+func TestMain(m *testing.M) {
+	// When the test binary is started through the plugin link, it
+	// serves the normalized store instead of running tests.
+	if strings.HasPrefix(filepath.Base(os.Args[0]), plugin.StorePrefix) {
+		if err := plugin.ServeStore(&normalizedStore{files: map[string][]byte{}}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	setup(m)
+	code := m.Run()
+	teardown()
+	os.Exit(code)
+}
+
+func setup(m *testing.M) {
+	// create a directory for test data
+	if _, err := os.Stat(TestDataDir); os.IsNotExist(err) {
+		if err := os.Mkdir(TestDataDir, 0777); err != nil && !os.IsNotExist(err) {
+			panic(err)
+		}
+	}
+
+	// the secrets vault prepares an AWS session; setting a region
+	// prevents a prompt even though AWS is never called.
+	if len(os.Getenv("AWS_REGION")) == 0 {
+		os.Setenv("AWS_REGION", "us-east-1")
+	}
+
+	// link the test binary as a store plugin on the PATH
+	dir, err := filepath.Abs(filepath.Join(TestDataDir, "plugins"))
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		panic(err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.Symlink(exe, filepath.Join(dir, plugin.StorePrefix+NormalizedStore)); err != nil {
+		panic(err)
+	}
+
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func teardown() {
+	plugin.Close()
+	os.RemoveAll(TestDataDir)
+}
+
+func cleanupOutput(catalog string) {
+
+	if _, err := os.Stat(catalog); !os.IsNotExist(err) {
+		opt := cfg.UserOptions{
+			Catalog: catalog,
+		}
+
+		cmd.Purge(opt, makeIO(testWriter, testWriter, "y"))
+	}
+}
+
+// Input is processed in the order of the args.
+func makeIO(userOutput io.Writer, export io.Writer, args ...interface{}) models.IO {
+	input := ""
+
+	for range args {
+		input += "%s\n"
+	}
+
+	return models.IO{
+		UserOutput: userOutput,
+		UserInput:  bufio.NewReader(bytes.NewReader([]byte(fmt.Sprintf(input, args...)))),
+		Export:     export,
+	}
+
+}
+
+type file struct {
+	io      models.IO
+	data    string
+	tags    string
+	version string
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+)
+
+var (
+	vaultName        string
+	removePassphrase bool
+)
+
+// vaultCmd groups commands that maintain vaults
+var vaultCmd = &cobra.Command{
+	Use:   "vault",
+	Short: "Maintain vaults.",
+	Long:  `Maintain vaults.`,
+}
+
+var vaultUpgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Re-encrypt vault secrets stored in an older format.",
+	Long:  `Re-encrypt vault secrets stored in an older format, like file vaults encrypted without authentication.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		u, ok := getVault().(contract.IUpgradableVault)
+		if !ok {
+			display.Error(fmt.Errorf("%s vault cannot be upgraded", vaultName), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		upgraded, err := u.Upgrade()
+		if err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		if upgraded {
+			fmt.Fprintf(ioStreams.UserOutput, "%s vault upgraded\n", vaultName)
+		} else {
+			fmt.Fprintf(ioStreams.UserOutput, "%s vault is current\n", vaultName)
+		}
+	},
+}
+
+var vaultRotateKeyCmd = &cobra.Command{
+	Use:   "rotate-key",
+	Short: "Re-encrypt vault secrets with a new key.",
+	Long:  `Re-encrypt vault secrets with a new key. Other machines sharing the old key need the new key.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		r, ok := getVault().(contract.IRotatableVault)
+		if !ok {
+			display.Error(fmt.Errorf("%s vault does not manage a key", vaultName), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		if err := r.RotateKey(); err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ioStreams.UserOutput, "%s vault key rotated\n", vaultName)
+	},
+}
+
+var vaultPassphraseCmd = &cobra.Command{
+	Use:   "passphrase",
+	Short: "Protect vault secrets with a passphrase.",
+	Long:  `Protect vault secrets with a key derived from a passphrase instead of a saved key. Run again to change the passphrase.`,
+	Run: func(cmd *cobra.Command, args []string) {
+
+		p, ok := getVault().(contract.IPassphraseVault)
+		if !ok {
+			display.Error(fmt.Errorf("%s vault does not support passphrases", vaultName), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		if removePassphrase {
+			if err := p.RemovePassphrase(); err != nil {
+				display.Error(err, ioStreams.UserOutput)
+				os.Exit(1)
+			}
+
+			fmt.Fprintf(ioStreams.UserOutput, "%s vault passphrase removed\n", vaultName)
+			return
+		}
+
+		if err := p.SetPassphrase(); err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ioStreams.UserOutput, "%s vault passphrase set\n", vaultName)
+	},
+}
+
+var vaultAddRecipientCmd = &cobra.Command{
+	Use:   "add-recipient",
+	Short: "Re-encrypt vault secrets for the owner of a public key.",
+	Long:  `Re-encrypt vault secrets for the owner of a public key, so they can decrypt secrets with their own private key.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		r, ok := getVault().(contract.IRecipientVault)
+		if !ok {
+			display.Error(fmt.Errorf("%s vault does not support recipients", vaultName), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		publicKey, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		added, err := r.AddRecipient(publicKey)
+		if err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		if len(added) == 0 {
+			fmt.Fprintf(ioStreams.UserOutput, "recipient already added\n")
+		}
+
+		for _, recipient := range added {
+			fmt.Fprintf(ioStreams.UserOutput, "added %s\n", recipient)
+		}
+	},
+}
+
+var vaultRemoveRecipientCmd = &cobra.Command{
+	Use:   "remove-recipient",
+	Short: "Re-encrypt vault secrets without a recipient.",
+	Long:  `Re-encrypt vault secrets without a recipient identified by key id, fingerprint, or email. Secrets the recipient could read before should be changed.`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		r, ok := getVault().(contract.IRecipientVault)
+		if !ok {
+			display.Error(fmt.Errorf("%s vault does not support recipients", vaultName), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		removed, err := r.RemoveRecipient(args[0])
+		if err != nil {
+			display.Error(err, ioStreams.UserOutput)
+			os.Exit(1)
+		}
+
+		fmt.Fprintf(ioStreams.UserOutput, "removed %s\n", removed)
+	},
+}
+
+func getVault() contract.IVault {
+	v, found := vault.Get()[vaultName]
+	if !found {
+		display.Error(fmt.Errorf("%s vault not found (use 'vaults' command to view available vaults)", vaultName), ioStreams.UserOutput)
+		os.Exit(1)
+	}
+
+	//------------------------------------------
+	//- Vaults saved with the catalog need its
+	//- location, when there is a catalog.
+	//------------------------------------------
+	clog, _ := catalog.Get(viper.GetString(catalogToken))
+
+	if err := v.Pre(clog, &catalog.File{Data: map[string]string{}}, vault.Get()["env"], uo, ioStreams); err != nil {
+		display.Error(err, ioStreams.UserOutput)
+		os.Exit(1)
+	}
+
+	return v
+}
+
+func init() {
+	RootCmd.AddCommand(vaultCmd)
+	vaultCmd.AddCommand(vaultUpgradeCmd)
+	vaultCmd.AddCommand(vaultRotateKeyCmd)
+	vaultCmd.AddCommand(vaultPassphraseCmd)
+	vaultCmd.AddCommand(vaultAddRecipientCmd)
+	vaultCmd.AddCommand(vaultRemoveRecipientCmd)
+
+	vaultCmd.PersistentFlags().StringVar(&vaultName, "vault", "file", "Vault to maintain. The 'vaults' command lists options.")
+	vaultPassphraseCmd.Flags().BoolVar(&removePassphrase, "remove", false, "Replace the passphrase with a saved key.")
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/remote"
+	"github.com/turnerlabs/cstore/v4/components/store"
+)
+
+// verifyReplicasCmd represents the verify-replicas command
+var verifyReplicasCmd = &cobra.Command{
+	Use:   "verify-replicas",
+	Short: "Report drift between mirror store replicas.",
+	Long: `Report drift between mirror store replicas.
+
+Each version of a file stored in the mirror store is pulled from every replica and compared with the copy in the primary store.`,
+	Run: func(cmd *cobra.Command, userSpecifiedFilePaths []string) {
+		setupUserOptions(userSpecifiedFilePaths)
+
+		if err := VerifyReplicas(uo, ioStreams); err != nil {
+			display.Error(fmt.Errorf("%s for %s", err, uo.Catalog), ioStreams.UserOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+// VerifyReplicas ...
+func VerifyReplicas(opt cfg.UserOptions, io models.IO) error {
+	drift := 0
+
+	clog, err := catalog.Get(opt.Catalog)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(io.UserOutput)
+	for _, fileEntry := range clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, "") {
+		if fileEntry.IsRef || fileEntry.Store != (store.MirrorStore{}).Name() {
+			continue
+		}
+
+		fileEntryTemp := remote.OverrideFileSettings(fileEntry, opt)
+
+		remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, io)
+		if err != nil {
+			return err
+		}
+
+		mirror, ok := store.Unwrap(remoteComp.Store).(*store.MirrorStore)
+		if !ok {
+			continue
+		}
+
+		fmt.Fprintf(io.UserOutput, "|-")
+		color.New(color.FgBlue).Fprintf(io.UserOutput, " %s ", fileEntry.ActualPath())
+		color.New(color.Bold).Fprintf(io.UserOutput, "[%s]\n", fileEntry.Store)
+
+		for _, version := range append([]string{none}, fileEntry.Versions...) {
+			fmt.Fprintf(io.UserOutput, "|    |- %s\n", formatVersion(version))
+
+			for _, status := range mirror.Verify(&fileEntryTemp, version) {
+				name := status.Store
+				if status.Primary {
+					name = fmt.Sprintf("%s (primary)", name)
+				}
+
+				switch {
+				case status.Err != nil:
+					display.ErrorText(fmt.Sprintf("|    |    |- %s %s", name, status.Err), io.UserOutput)
+				case status.InSync:
+					fmt.Fprintf(io.UserOutput, "|    |    |- %s %s ", name, status.Checksum)
+					color.New(color.FgGreen).Fprintln(io.UserOutput, "in sync")
+				default:
+					fmt.Fprintf(io.UserOutput, "|    |    |- %s %s ", name, status.Checksum)
+					color.New(color.FgRed).Fprintln(io.UserOutput, "drift")
+				}
+
+				if !status.InSync {
+					drift++
+				}
+			}
+		}
+
+		fmt.Fprintln(io.UserOutput, "|")
+	}
+
+	fmt.Fprintln(io.UserOutput)
+
+	if drift > 0 {
+		return errors.New("replicas are out of sync")
+	}
+
+	return nil
+}
+
+func init() {
+	RootCmd.AddCommand(verifyReplicasCmd)
+}
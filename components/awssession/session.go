@@ -0,0 +1,394 @@
+package awssession
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/prompt"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	// RegionSetting is the catalog data key for a file specific region.
+	// Without it, the region is read from the environment.
+	RegionSetting = "AWS_REGION"
+
+	// ProfileSetting is the catalog data key for the shared config profile.
+	ProfileSetting = "AWS_PROFILE"
+
+	// RoleARNSetting is the catalog data key for the role to assume.
+	RoleARNSetting = "AWS_ROLE_ARN"
+
+	// ExternalIDSetting is the catalog data key for the external id
+	// required by the role trust policy.
+	ExternalIDSetting = "AWS_ROLE_EXTERNAL_ID"
+
+	// SessionNameSetting is the catalog data key for the role session name.
+	SessionNameSetting = "AWS_ROLE_SESSION_NAME"
+
+	// MFASerialSetting is the catalog data key for the MFA device required
+	// to assume the role.
+	MFASerialSetting = "AWS_MFA_SERIAL"
+
+	// WebIdentityTokenFileSetting is the catalog data key for the OIDC
+	// token file exchanged for role credentials in CI.
+	WebIdentityTokenFileSetting = "AWS_WEB_IDENTITY_TOKEN_FILE"
+
+	// DefaultRegion is used when no region is configured.
+	DefaultRegion = "us-east-1"
+
+	accessKeyID     = "AWS_ACCESS_KEY_ID"
+	secretAccessKey = "AWS_SECRET_ACCESS_KEY"
+	sessionToken    = "AWS_SESSION_TOKEN"
+
+	envVault = "env"
+)
+
+// ErrMFARequired is returned when an MFA code is needed, but prompts are
+// silenced.
+var ErrMFARequired = errors.New("MFA code required to assume role")
+
+var (
+	sessions = map[string]*session.Session{}
+	mu       sync.Mutex
+)
+
+// identity describes where the credentials for a session come from.
+type identity struct {
+	access      string
+	profile     string
+	role        string
+	externalID  string
+	sessionName string
+	serial      string
+	tokenFile   string
+
+	static    *credentials.Credentials
+	staticKey string
+}
+
+// Get returns the session shared by AWS stores and vaults for the region
+// and credentials configured for the file.
+//
+// Profiles, roles, and web identity token files are saved in the catalog
+// with the file, so a pull in a pipeline assumes the same role. New files
+// default to the settings of other files in the catalog. Without settings,
+// credentials are retrieved from the environment when the access vault is
+// env or from the access vault otherwise.
+//
+// Sessions are cached by region and credential identity to avoid building
+// a session and assuming a role for every file in large catalogs.
+func Get(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) (*session.Session, error) {
+	region, err := getRegion(clog, file, uo, io)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := getIdentity(clog, file, access, uo, io)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s|%s", region, id.key())
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if sess, found := sessions[key]; found {
+		return sess, nil
+	}
+
+	sess, err := id.session(session.Options{
+		Config: aws.Config{Region: aws.String(region)},
+	}, uo, io)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions[key] = sess
+
+	return sess, nil
+}
+
+//------------------------------------------
+//- A region saved with the file overrides
+//- the region in the environment. With -p,
+//- the region is prompted for each file.
+//------------------------------------------
+func getRegion(clog catalog.Catalog, file *catalog.File, uo cfg.UserOptions, io models.IO) (string, error) {
+	if value := file.Data[RegionSetting]; len(value) > 0 {
+		return value, nil
+	}
+
+	if uo.Prompt && !uo.Silent {
+		value := os.Getenv(RegionSetting)
+		if len(value) == 0 {
+			value = DefaultRegion
+		}
+
+		return setting.Setting{
+			Description:  "AWS region of the file.",
+			Prop:         RegionSetting,
+			Prompt:       true,
+			AutoSave:     true,
+			DefaultValue: value,
+			Vault:        file,
+		}.Get(clog.Context, io)
+	}
+
+	value, err := setting.Setting{
+		Description:  "Export as an environment variable to silence this prompt.",
+		Group:        clog.Context,
+		Prop:         RegionSetting,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		PromptOnce:   true,
+		DefaultValue: DefaultRegion,
+		Vault:        environment{},
+	}.Get(clog.Context, io)
+	if err != nil {
+		return "", err
+	}
+
+	if len(value) == 0 {
+		return DefaultRegion, nil
+	}
+
+	return value, nil
+}
+
+func getIdentity(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) (identity, error) {
+	id := identity{access: envVault}
+
+	if access != nil {
+		id.access = access.Name()
+	}
+
+	var err error
+
+	if id.profile, err = get(clog, file, ProfileSetting, "AWS shared config profile used for credentials. Leave blank to use the default credential chain.", uo, io); err != nil {
+		return id, err
+	}
+
+	if id.role, err = get(clog, file, RoleARNSetting, "ARN of the AWS role to assume. Leave blank to use the credentials as is.", uo, io); err != nil {
+		return id, err
+	}
+
+	if len(id.role) > 0 {
+		if id.externalID, err = get(clog, file, ExternalIDSetting, "External id required by the role trust policy. Leave blank if not required.", uo, io); err != nil {
+			return id, err
+		}
+
+		if id.sessionName, err = get(clog, file, SessionNameSetting, "Name of the role session shown in CloudTrail. Leave blank to generate one.", uo, io); err != nil {
+			return id, err
+		}
+
+		if id.serial, err = get(clog, file, MFASerialSetting, "Serial number or ARN of the MFA device required by the role. Leave blank if not required.", uo, io); err != nil {
+			return id, err
+		}
+
+		if id.tokenFile, err = get(clog, file, WebIdentityTokenFileSetting, "Path to an OIDC token file exchanged for role credentials, like in CI. Leave blank if not used.", uo, io); err != nil {
+			return id, err
+		}
+	}
+
+	if len(id.tokenFile) == 0 && len(id.profile) == 0 && id.access != envVault {
+		values, err := staticCredentials(clog, access, uo, io)
+		if err != nil {
+			return id, err
+		}
+
+		h := sha256.Sum256([]byte(strings.Join(values, "|")))
+
+		id.static = credentials.NewStaticCredentials(values[0], values[1], values[2])
+		id.staticKey = hex.EncodeToString(h[:])
+	}
+
+	return id, nil
+}
+
+func (id identity) key() string {
+	return strings.Join([]string{id.access, id.profile, id.role, id.externalID, id.sessionName, id.serial, id.tokenFile, id.staticKey}, "|")
+}
+
+func (id identity) session(opts session.Options, uo cfg.UserOptions, io models.IO) (*session.Session, error) {
+	tokenProvider := mfaTokenProvider(id.serial, uo, io)
+
+	//------------------------------------------
+	//- Get Base Credentials
+	//------------------------------------------
+	opts.AssumeRoleTokenProvider = tokenProvider
+
+	switch {
+	case len(id.tokenFile) > 0:
+		base, err := session.NewSessionWithOptions(opts)
+		if err != nil {
+			return nil, err
+		}
+
+		return base.Copy(&aws.Config{
+			Credentials: stscreds.NewWebIdentityCredentials(base, id.role, id.sessionName, id.tokenFile),
+		}), nil
+	case len(id.profile) > 0:
+		opts.Profile = id.profile
+		opts.SharedConfigState = session.SharedConfigEnable
+	case id.static != nil:
+		opts.Config.Credentials = id.static
+	}
+
+	base, err := session.NewSessionWithOptions(opts)
+	if err != nil || len(id.role) == 0 {
+		return base, err
+	}
+
+	//------------------------------------------
+	//- Assume Role
+	//------------------------------------------
+	return base.Copy(&aws.Config{
+		Credentials: stscreds.NewCredentials(base, id.role, func(p *stscreds.AssumeRoleProvider) {
+			if len(id.externalID) > 0 {
+				p.ExternalID = aws.String(id.externalID)
+			}
+
+			if len(id.sessionName) > 0 {
+				p.RoleSessionName = id.sessionName
+			}
+
+			if len(id.serial) > 0 {
+				p.SerialNumber = aws.String(id.serial)
+				p.TokenProvider = tokenProvider
+			}
+		}),
+	}), nil
+}
+
+//------------------------------------------
+//- Settings are read from the file or other
+//- files in the catalog and only prompted
+//- for when requested.
+//------------------------------------------
+func get(clog catalog.Catalog, file *catalog.File, prop, description string, uo cfg.UserOptions, io models.IO) (string, error) {
+	if value, found := file.Data[prop]; found {
+		return value, nil
+	}
+
+	value := clog.GetDataByStore("", prop, "")
+
+	if uo.Prompt && !uo.Silent {
+		v, err := setting.Setting{
+			Description:  description,
+			Prop:         prop,
+			Prompt:       true,
+			Silent:       uo.Silent,
+			AutoSave:     true,
+			PromptOnce:   true,
+			DefaultValue: value,
+			Vault:        file,
+		}.Get(clog.Context, io)
+		if err != nil || len(v) > 0 {
+			return v, err
+		}
+	}
+
+	if len(value) > 0 {
+		file.AddData(map[string]string{
+			prop: value,
+		})
+	}
+
+	return value, nil
+}
+
+func staticCredentials(clog catalog.Catalog, access contract.IVault, uo cfg.UserOptions, io models.IO) ([]string, error) {
+	values := []string{}
+
+	for _, prop := range []string{accessKeyID, secretAccessKey, sessionToken} {
+		value, err := setting.Setting{
+			Description: fmt.Sprintf("Save credential in %s.", access.Name()),
+			Group:       clog.Context,
+			Prop:        prop,
+			Prompt:      uo.Prompt,
+			Silent:      uo.Silent,
+			AutoSave:    true,
+			PromptOnce:  true,
+			Vault:       access,
+		}.Get(clog.Context, io)
+		if err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+//------------------------------------------
+//- MFA codes are requested when the role
+//- credentials are first retrieved.
+//------------------------------------------
+func mfaTokenProvider(serial string, uo cfg.UserOptions, io models.IO) func() (string, error) {
+	return func() (string, error) {
+		if uo.Silent {
+			return "", ErrMFARequired
+		}
+
+		description := "Enter the code from the MFA device."
+		if len(serial) > 0 {
+			description = fmt.Sprintf("Enter the code from MFA device %s.", serial)
+		}
+
+		code := prompt.GetValFromUser("MFA_CODE", prompt.Options{
+			Description: description,
+		}, io)
+
+		if len(code) == 0 {
+			return "", ErrMFARequired
+		}
+
+		return code, nil
+	}
+}
+
+//------------------------------------------
+//- The region is read from and saved in the
+//- environment when not set for the file.
+//------------------------------------------
+type environment struct{}
+
+func (e environment) Name() string {
+	return "env"
+}
+
+func (e environment) BuildKey(contextID, group, prop string) string {
+	return strings.ToUpper(prop)
+}
+
+func (e environment) Get(contextID, group, prop string) (string, error) {
+	if value := os.Getenv(e.BuildKey(contextID, group, prop)); len(value) > 0 {
+		return value, nil
+	}
+
+	return "", contract.ErrSecretNotFound
+}
+
+func (e environment) Set(contextID, group, prop, value string) error {
+	return os.Setenv(e.BuildKey(contextID, group, prop), value)
+}
+
+func (e environment) Delete(contextID, group, prop string) error {
+	return os.Unsetenv(e.BuildKey(contextID, group, prop))
+}
@@ -0,0 +1,332 @@
+package awssession
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// fakeSTS returns temporary credentials and keeps the last request.
+type fakeSTS struct {
+	sync.Mutex
+	form url.Values
+}
+
+func (f *fakeSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	r.ParseForm()
+	f.form = r.PostForm
+
+	action := r.PostForm.Get("Action")
+
+	fmt.Fprintf(w, `<%[1]sResponse><%[1]sResult><Credentials><AccessKeyId>ROLE_ID</AccessKeyId><SecretAccessKey>ROLE_SECRET</SecretAccessKey><SessionToken>ROLE_TOKEN</SessionToken><Expiration>2100-01-01T00:00:00Z</Expiration></Credentials></%[1]sResult></%[1]sResponse>`, action)
+}
+
+// memoryVault is an access vault holding static credentials.
+type memoryVault map[string]string
+
+func (v memoryVault) Name() string        { return "memory" }
+func (v memoryVault) Description() string { return "" }
+func (v memoryVault) BuildKey(contextID, group, prop string) string {
+	return prop
+}
+func (v memoryVault) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	return nil
+}
+func (v memoryVault) Get(contextID, group, prop string) (string, error) {
+	if value, found := v[prop]; found {
+		return value, nil
+	}
+	return "", contract.ErrSecretNotFound
+}
+func (v memoryVault) Set(contextID, group, prop, value string) error {
+	v[prop] = value
+	return nil
+}
+func (v memoryVault) Delete(contextID, group, prop string) error {
+	delete(v, prop)
+	return nil
+}
+
+func setupSTS(t *testing.T) (*fakeSTS, session.Options, func()) {
+	fake := &fakeSTS{}
+	server := httptest.NewServer(fake)
+
+	return fake, session.Options{
+		Config: aws.Config{
+			Region:   aws.String("us-east-1"),
+			Endpoint: aws.String(server.URL),
+		},
+	}, server.Close
+}
+
+// newSession builds an uncached session with the options under test.
+func newSession(opts session.Options, clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) (*session.Session, error) {
+	id, err := getIdentity(clog, file, access, uo, io)
+	if err != nil {
+		return nil, err
+	}
+
+	return id.session(opts, uo, io)
+}
+
+func makeIO(input string) models.IO {
+	return models.IO{
+		UserOutput: ioutil.Discard,
+		UserInput:  bufio.NewReader(bytes.NewReader([]byte(input))),
+		Export:     ioutil.Discard,
+	}
+}
+
+func TestEnsureRoleIsAssumedWithExternalIDAndMFA(t *testing.T) {
+	// arrange
+	fake, opts, cleanup := setupSTS(t)
+	defer cleanup()
+
+	access := memoryVault{
+		accessKeyID:     "id",
+		secretAccessKey: "secret",
+		sessionToken:    "",
+	}
+
+	file := &catalog.File{
+		Data: map[string]string{
+			RoleARNSetting:     "arn:aws:iam::123456789012:role/deploy",
+			ExternalIDSetting:  "partner",
+			SessionNameSetting: "pipeline",
+			MFASerialSetting:   "arn:aws:iam::123456789012:mfa/user",
+		},
+	}
+
+	sess, err := newSession(opts, catalog.Catalog{Context: t.Name()}, file, access, cfg.UserOptions{}, makeIO("123456\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	creds, err := sess.Config.Credentials.Get()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creds.AccessKeyID != "ROLE_ID" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ROLE_ID", creds.AccessKeyID)
+	}
+
+	for key, expected := range map[string]string{
+		"Action":          "AssumeRole",
+		"RoleArn":         "arn:aws:iam::123456789012:role/deploy",
+		"ExternalId":      "partner",
+		"RoleSessionName": "pipeline",
+		"SerialNumber":    "arn:aws:iam::123456789012:mfa/user",
+		"TokenCode":       "123456",
+	} {
+		if actual := fake.form.Get(key); actual != expected {
+			t.Errorf("\nEXPECTED: %s=%s \nACTUAL: %s=%s", key, expected, key, actual)
+		}
+	}
+}
+
+func TestEnsureMFAIsNotPromptedForWhenSilent(t *testing.T) {
+	// arrange
+	_, opts, cleanup := setupSTS(t)
+	defer cleanup()
+
+	access := memoryVault{
+		accessKeyID:     "id",
+		secretAccessKey: "secret",
+		sessionToken:    "",
+	}
+
+	file := &catalog.File{
+		Data: map[string]string{
+			RoleARNSetting:   "arn:aws:iam::123456789012:role/deploy",
+			MFASerialSetting: "arn:aws:iam::123456789012:mfa/user",
+		},
+	}
+
+	sess, err := newSession(opts, catalog.Catalog{Context: t.Name()}, file, access, cfg.UserOptions{Silent: true}, makeIO("123456\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, err = sess.Config.Credentials.Get()
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", ErrMFARequired, err)
+	}
+}
+
+func TestEnsureWebIdentityTokenIsExchangedForRoleCredentials(t *testing.T) {
+	// arrange
+	fake, opts, cleanup := setupSTS(t)
+	defer cleanup()
+
+	token, err := ioutil.TempFile("", "token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(token.Name())
+
+	token.WriteString("oidc-token")
+	token.Close()
+
+	file := &catalog.File{
+		Data: map[string]string{
+			RoleARNSetting:              "arn:aws:iam::123456789012:role/ci",
+			WebIdentityTokenFileSetting: token.Name(),
+		},
+	}
+
+	sess, err := newSession(opts, catalog.Catalog{Context: t.Name()}, file, memoryVault{}, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	creds, err := sess.Config.Credentials.Get()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if creds.AccessKeyID != "ROLE_ID" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ROLE_ID", creds.AccessKeyID)
+	}
+
+	if fake.form.Get("Action") != "AssumeRoleWithWebIdentity" || fake.form.Get("WebIdentityToken") != "oidc-token" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "AssumeRoleWithWebIdentity oidc-token", fake.form)
+	}
+}
+
+func TestEnsureCatalogRoleIsRecordedForNewFiles(t *testing.T) {
+	// arrange
+	clog := catalog.Catalog{
+		Context: t.Name(),
+		Files: map[string]catalog.File{
+			"existing": {
+				Data: map[string]string{
+					RoleARNSetting: "arn:aws:iam::123456789012:role/deploy",
+				},
+			},
+		},
+	}
+
+	file := &catalog.File{}
+
+	// act
+	_, err := newSession(session.Options{Config: aws.Config{Region: aws.String("us-east-1")}}, clog, file, memoryVault{}, cfg.UserOptions{Silent: true}, makeIO(""))
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Data[RoleARNSetting] != "arn:aws:iam::123456789012:role/deploy" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "arn:aws:iam::123456789012:role/deploy", file.Data[RoleARNSetting])
+	}
+
+	if _, found := file.Data[ProfileSetting]; found {
+		t.Errorf("\nEXPECTED: %s not saved \nACTUAL: %s", ProfileSetting, file.Data[ProfileSetting])
+	}
+}
+
+func TestEnsureSessionIsSharedForSameRegionAndCredentials(t *testing.T) {
+	// arrange
+	access := memoryVault{
+		accessKeyID:     "id",
+		secretAccessKey: "secret",
+		sessionToken:    "",
+	}
+
+	clog := catalog.Catalog{Context: t.Name()}
+
+	first, err := Get(clog, &catalog.File{Data: map[string]string{RegionSetting: "us-west-2"}}, access, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	same, err := Get(clog, &catalog.File{Data: map[string]string{RegionSetting: "us-west-2"}}, access, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other, err := Get(clog, &catalog.File{Data: map[string]string{RegionSetting: "eu-west-1"}}, access, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	access[accessKeyID] = "rotated"
+
+	rotated, err := Get(clog, &catalog.File{Data: map[string]string{RegionSetting: "us-west-2"}}, access, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if first != same {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "shared session", "new session")
+	}
+
+	if first == other {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "session per region", "shared session")
+	}
+
+	if first == rotated {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "session per credentials", "shared session")
+	}
+
+	if actual := aws.StringValue(other.Config.Region); actual != "eu-west-1" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "eu-west-1", actual)
+	}
+}
+
+func TestEnsureFileRegionOverridesEnvironment(t *testing.T) {
+	// arrange
+	os.Setenv(RegionSetting, "us-east-2")
+	defer os.Unsetenv(RegionSetting)
+
+	clog := catalog.Catalog{Context: t.Name()}
+
+	// act
+	override, err := getRegion(clog, &catalog.File{Data: map[string]string{RegionSetting: "ap-south-1"}}, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := getRegion(clog, &catalog.File{}, cfg.UserOptions{Silent: true}, makeIO(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if override != "ap-south-1" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ap-south-1", override)
+	}
+
+	if env != "us-east-2" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "us-east-2", env)
+	}
+}
@@ -0,0 +1,121 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	tenantIDSetting     = "AZURE_TENANT_ID"
+	clientIDSetting     = "AZURE_CLIENT_ID"
+	clientSecretSetting = "AZURE_CLIENT_SECRET"
+
+	authorityHostEnv     = "AZURE_AUTHORITY_HOST"
+	defaultAuthorityHost = "https://login.microsoftonline.com"
+)
+
+type token struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	tokens = map[string]token{}
+	mu     sync.Mutex
+)
+
+// Token returns an Azure Active Directory access token for the scope using
+// the service principal client credentials flow. The tenant id, client id,
+// and client secret are retrieved from the access vault.
+//
+// Tokens are cached until shortly before they expire to avoid requesting
+// a token for every file in large catalogs.
+func Token(clog catalog.Catalog, access setting.IKeyValueStore, scope string, uo cfg.UserOptions, io models.IO) (string, error) {
+
+	tenant, err := Credential(tenantIDSetting, clog, access, uo, io)
+	if err != nil {
+		return "", err
+	}
+
+	clientID, err := Credential(clientIDSetting, clog, access, uo, io)
+	if err != nil {
+		return "", err
+	}
+
+	clientSecret, err := Credential(clientSecretSetting, clog, access, uo, io)
+	if err != nil {
+		return "", err
+	}
+
+	key := fmt.Sprintf("%s|%s|%s", tenant, clientID, scope)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if t, found := tokens[key]; found && time.Now().Before(t.expires) {
+		return t.value, nil
+	}
+
+	authority := os.Getenv(authorityHostEnv)
+	if len(authority) == 0 {
+		authority = defaultAuthorityHost
+	}
+
+	resp, err := http.PostForm(fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authority, "/"), tenant), url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {clientSecret},
+		"scope":         {scope},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body := struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}{}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("Azure AD token request failed (%d): %s", resp.StatusCode, err)
+	}
+
+	if resp.StatusCode != http.StatusOK || len(body.AccessToken) == 0 {
+		return "", fmt.Errorf("Azure AD token request failed (%d): %s", resp.StatusCode, body.ErrorDescription)
+	}
+
+	tokens[key] = token{
+		value:   body.AccessToken,
+		expires: time.Now().Add(time.Duration(body.ExpiresIn-60) * time.Second),
+	}
+
+	return body.AccessToken, nil
+}
+
+// Credential retrieves a credential from the access vault prompting the
+// user when it is missing.
+func Credential(prop string, clog catalog.Catalog, access setting.IKeyValueStore, uo cfg.UserOptions, io models.IO) (string, error) {
+	return setting.Setting{
+		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
+		Group:       clog.Context,
+		Prop:        prop,
+		Prompt:      uo.Prompt,
+		Silent:      uo.Silent,
+		AutoSave:    true,
+		PromptOnce:  true,
+		Vault:       access,
+	}.Get(clog.Context, io)
+}
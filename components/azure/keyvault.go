@@ -0,0 +1,158 @@
+package azure
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/contract"
+)
+
+const (
+	// KeyVaultScope is the access token scope for the Key Vault data plane.
+	KeyVaultScope = "https://vault.azure.net/.default"
+
+	keyVaultAPIVersion = "7.0"
+)
+
+// KeyVaultURL builds the data plane url for a Key Vault name.
+func KeyVaultURL(name string) string {
+	return fmt.Sprintf("https://%s.vault.azure.net", name)
+}
+
+// GetSecret gets the current value of a Key Vault secret.
+// contract.ErrSecretNotFound is returned when the secret does not exist.
+func GetSecret(vaultURL, token, name string) (string, error) {
+
+	body := struct {
+		Value string `json:"value"`
+	}{}
+
+	if err := call(http.MethodGet, secretURL(vaultURL, name), token, nil, &body); err != nil {
+		return "", err
+	}
+
+	return body.Value, nil
+}
+
+// RecoverWait is the time between attempts to set a deleted secret while
+// it is recovered.
+var RecoverWait = 2 * time.Second
+
+const recoverAttempts = 15
+
+// SetSecret creates a new version of a Key Vault secret. A deleted secret
+// that can still be recovered is recovered first, because its name cannot
+// be reused until it is purged.
+func SetSecret(vaultURL, token, name, value string) error {
+	err := call(http.MethodPut, secretURL(vaultURL, name), token, map[string]string{"value": value}, nil)
+	if !isDeleted(err) {
+		return err
+	}
+
+	recoverURL := fmt.Sprintf("%s/deletedsecrets/%s/recover?api-version=%s", strings.TrimSuffix(vaultURL, "/"), name, keyVaultAPIVersion)
+
+	if err := call(http.MethodPost, recoverURL, token, nil, nil); err != nil {
+		return fmt.Errorf("%s was deleted and could not be recovered: %s", name, err)
+	}
+
+	//------------------------------------------
+	//- Recovery completes in the background, so
+	//- conflicts are retried until it is done.
+	//------------------------------------------
+	for attempt := 1; ; attempt++ {
+		time.Sleep(RecoverWait)
+
+		err = call(http.MethodPut, secretURL(vaultURL, name), token, map[string]string{"value": value}, nil)
+
+		if kerr, ok := err.(keyVaultError); !ok || kerr.status != http.StatusConflict || attempt == recoverAttempts {
+			return err
+		}
+	}
+}
+
+// DeleteSecret deletes all versions of a Key Vault secret.
+// contract.ErrSecretNotFound is returned when the secret does not exist.
+func DeleteSecret(vaultURL, token, name string) error {
+	return call(http.MethodDelete, secretURL(vaultURL, name), token, nil, nil)
+}
+
+func secretURL(vaultURL, name string) string {
+	return fmt.Sprintf("%s/secrets/%s?api-version=%s", strings.TrimSuffix(vaultURL, "/"), name, keyVaultAPIVersion)
+}
+
+func call(method, url, token string, in, out interface{}) error {
+
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, &body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return contract.ErrSecretNotFound
+	}
+
+	if resp.StatusCode >= 300 {
+		failure := struct {
+			Error struct {
+				Code       string `json:"code"`
+				Message    string `json:"message"`
+				InnerError struct {
+					Code string `json:"code"`
+				} `json:"innererror"`
+			} `json:"error"`
+		}{}
+
+		json.NewDecoder(resp.Body).Decode(&failure)
+
+		return keyVaultError{
+			method:  method,
+			status:  resp.StatusCode,
+			code:    failure.Error.Code,
+			inner:   failure.Error.InnerError.Code,
+			message: failure.Error.Message,
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type keyVaultError struct {
+	method  string
+	status  int
+	code    string
+	inner   string
+	message string
+}
+
+func (e keyVaultError) Error() string {
+	return fmt.Sprintf("Key Vault %s failed (%d): %s %s", e.method, e.status, e.code, e.message)
+}
+
+func isDeleted(err error) bool {
+	kerr, ok := err.(keyVaultError)
+	return ok && kerr.status == http.StatusConflict && kerr.inner == "ObjectIsDeletedButRecoverable"
+}
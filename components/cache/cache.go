@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cipher"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/local"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+)
+
+// KeyName is the access vault secret used to encrypt cached files.
+const KeyName = "CSTORE_CACHE_KEY"
+
+const dir = "cache"
+
+// ErrNotCached is returned when a file has not been cached.
+var ErrNotCached = errors.New("file not cached")
+
+// StaleError is returned when the cached copy is older than allowed.
+type StaleError struct {
+	Pulled time.Time
+	MaxAge time.Duration
+}
+
+func (e StaleError) Error() string {
+	return fmt.Sprintf("cached copy from %s is older than %s", e.Pulled.Format(time.RFC3339), e.MaxAge)
+}
+
+// Entry is the last successful pull of a file version.
+type Entry struct {
+	Data       []byte
+	Attributes contract.Attributes
+	Pulled     time.Time
+}
+
+// Age is the time since the file was pulled from the remote store.
+func (e Entry) Age() time.Duration {
+	return time.Since(e.Pulled).Round(time.Second)
+}
+
+// Save encrypts and stores a successfully pulled file version. A key is
+// generated the first time when the access vault can keep it. The env
+// vault cannot keep a key, so CSTORE_CACHE_KEY must be set.
+func Save(clog catalog.Catalog, file catalog.File, version string, data []byte, attr contract.Attributes, access contract.IVault) error {
+	key, err := getKey(clog, access, true)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(Entry{
+		Data:       data,
+		Attributes: attr,
+		Pulled:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	sealed, err := cipher.Seal(key, b)
+	if err != nil {
+		return err
+	}
+
+	p := path(clog, file, version)
+
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+
+	//------------------------------------------
+	//- Replace the previous copy in one step,
+	//- so a failed write cannot corrupt it.
+	//------------------------------------------
+	tmp := p + ".tmp"
+
+	if err := ioutil.WriteFile(tmp, sealed, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, p)
+}
+
+// Get returns the cached copy of a file version when it was pulled
+// within maxAge. A zero maxAge accepts any cached copy.
+func Get(clog catalog.Catalog, file catalog.File, version string, maxAge time.Duration, access contract.IVault) (Entry, error) {
+	entry := Entry{}
+
+	sealed, err := ioutil.ReadFile(path(clog, file, version))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entry, ErrNotCached
+		}
+		return entry, err
+	}
+
+	key, err := getKey(clog, access, false)
+	if err != nil {
+		return entry, err
+	}
+
+	b, err := cipher.Open(key, sealed)
+	if err != nil {
+		return entry, fmt.Errorf("failed to decrypt cached copy (%s)", err)
+	}
+
+	if err := json.Unmarshal(b, &entry); err != nil {
+		return entry, err
+	}
+
+	if maxAge > 0 && time.Since(entry.Pulled) > maxAge {
+		return entry, StaleError{Pulled: entry.Pulled, MaxAge: maxAge}
+	}
+
+	return entry, nil
+}
+
+// Purge removes the cached copy of a file version.
+func Purge(clog catalog.Catalog, file catalog.File, version string) error {
+	if err := os.Remove(path(clog, file, version)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+//------------------------------------------
+//- Cached files are named by a hash, so
+//- paths and versions are not revealed.
+//------------------------------------------
+func path(clog catalog.Catalog, file catalog.File, version string) string {
+	h := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s", clog.Context, file.Key(), version)))
+
+	return local.BuildPath(filepath.Join(dir, hex.EncodeToString(h[:])))
+}
+
+func getKey(clog catalog.Catalog, access contract.IVault, create bool) ([]byte, error) {
+	value, err := access.Get(clog.Context, clog.Context, KeyName)
+	if err != nil && err != contract.ErrSecretNotFound {
+		return nil, err
+	}
+
+	if len(value) == 0 {
+		//------------------------------------------
+		//- The env vault cannot save a generated
+		//- key, so the key must be provided.
+		//------------------------------------------
+		if _, env := access.(vault.EnvVault); env {
+			return nil, fmt.Errorf("%s not found in %s, set it to a base64 encoded 256 bit key like 'export %s=$(openssl rand -base64 32)' to cache files", KeyName, access.Name(), KeyName)
+		}
+
+		if !create {
+			return nil, fmt.Errorf("%s not found in %s", KeyName, access.Name())
+		}
+
+		if value, err = cipher.GenerateKey(); err != nil {
+			return nil, err
+		}
+
+		if err := access.Set(clog.Context, clog.Context, KeyName, value); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := cipher.ParseKey(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", KeyName, err)
+	}
+
+	return key, nil
+}
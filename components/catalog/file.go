@@ -33,7 +33,7 @@ func (c Catalog) RemoveRecords(fileName string) error {
 }
 
 // RecordPull ...
-func (c Catalog) RecordPull(fileName string, lastPull time.Time, version string) error {
+func (c Catalog) RecordPull(fileName string, lastPull time.Time, version, revision string) error {
 	if lastPull.IsZero() {
 		return errors.New("invalid time")
 	}
@@ -48,8 +48,9 @@ func (c Catalog) RecordPull(fileName string, lastPull time.Time, version string)
 	}
 
 	s := State{
-		Pulled:  lastPull.UTC(),
-		Version: version,
+		Pulled:   lastPull.UTC(),
+		Version:  version,
+		Revision: revision,
 	}
 
 	pulls[c.ContextKey(fileName)] = s
@@ -87,8 +88,32 @@ func (f File) IsCurrent(lastChange time.Time, context string) (bool, string) {
 	return false, ""
 }
 
+// PulledRevision returns the remote revision of the file when the version
+// was last pulled or pushed. An empty string is returned when the revision
+// is unknown.
+func (f File) PulledRevision(context, version string) string {
+
+	b, err := local.Get(name, "")
+	if err != nil {
+		return ""
+	}
+
+	pulls := map[string]State{}
+	if err = yaml.Unmarshal(b, &pulls); err != nil {
+		logger.L.Print(err)
+		return ""
+	}
+
+	if filePulled, found := pulls[f.ContextKey(context)]; found && filePulled.Version == version {
+		return filePulled.Revision
+	}
+
+	return ""
+}
+
 // State contains information that is relavant to the pulled file.
 type State struct {
-	Pulled  time.Time
-	Version string `yaml:"version,omitempty"`
+	Pulled   time.Time
+	Version  string `yaml:"version,omitempty"`
+	Revision string `yaml:"revision,omitempty"`
 }
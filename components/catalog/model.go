@@ -362,7 +362,7 @@ func (c *Catalog) UpdateEntry(newFile File) error {
 
 	if oldFile, found := c.Files[key]; found {
 		if len(newFile.Store) > 0 && newFile.Store != oldFile.Store {
-			return fmt.Errorf("AreadyStoredException: Purge %s from %s before pushing to %s", newFile.ActualPath(), oldFile.Store, newFile.Store)
+			return fmt.Errorf("AreadyStoredException: Migrate %s from %s to %s with 'cstore migrate --to %s' or purge it before pushing", newFile.ActualPath(), oldFile.Store, newFile.Store, newFile.Store)
 		}
 	}
 
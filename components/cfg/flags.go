@@ -3,6 +3,7 @@ package cfg
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // UserOptions ...
@@ -20,12 +21,16 @@ type UserOptions struct {
 	InjectSecrets        bool
 	NoOverwrite          bool
 	DeleteLocalFiles     string
+	Encrypt              string
 	ExportEnv            bool
 	Catalog              string
 	AccessVault          string
 	SecretsVault         string
 	ViewTags             bool
 	ViewVersions         bool
+	ViewAttributes       bool
+	Cache                bool
+	CacheMaxAge          time.Duration
 	Prompt               bool
 	Silent               bool
 }
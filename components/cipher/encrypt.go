@@ -1,12 +1,13 @@
 package cipher
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
-	"io"
-	mrand "math/rand"
-	"time"
+	"errors"
+	"fmt"
+	"math/big"
 )
 
 // AESKeyName is the environment variable that holds the encryption key. It
@@ -14,12 +15,51 @@ import (
 // bit encryption respectively.
 var AESKeyName = "CSTORE_AES_KEY"
 
-// Decrypt ...
+// Ciphertext created by Encrypt starts with a header followed by the
+// format version. Data without the header is legacy AES-CFB ciphertext,
+// which is version 1.
+var cipherHeader = []byte("\x00cstore")
+
+const gcmVersion byte = 2
+
+// ErrCiphertextTooShort is returned when data is too short to have been
+// created by Encrypt.
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// IsCurrent checks if data was encrypted with the current format.
+func IsCurrent(cipherData []byte) bool {
+	return bytes.HasPrefix(cipherData, cipherHeader) && len(cipherData) > len(cipherHeader) && cipherData[len(cipherHeader)] == gcmVersion
+}
+
+// Decrypt authenticates and decrypts data created by Encrypt. Legacy
+// AES-CFB data is decrypted without authentication.
 func Decrypt(k string, cipherData []byte) ([]byte, error) {
 	key := []byte(k)
-	//ciphertext, _ := hex.DecodeString("22277966616d9bc47177bd02603d08c9a67d5380d0fe8cf3b44438dff7b9")
-	//cipherText := string(cipherData)
 
+	if !bytes.HasPrefix(cipherData, cipherHeader) {
+		return decryptCFB(key, cipherData)
+	}
+
+	if len(cipherData) <= len(cipherHeader) {
+		return nil, ErrCiphertextTooShort
+	}
+
+	header := cipherData[:len(cipherHeader)+1]
+
+	switch version := header[len(header)-1]; version {
+	case gcmVersion:
+		data, err := gcmOpen(key, cipherData[len(header):], header)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt, the key is wrong or the data was modified (%s)", err)
+		}
+
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported ciphertext version %d", version)
+	}
+}
+
+func decryptCFB(key, cipherData []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
 		return []byte{}, err
@@ -28,58 +68,66 @@ func Decrypt(k string, cipherData []byte) ([]byte, error) {
 	// The IV needs to be unique, but not secure. Therefore it's common to
 	// include it at the beginning of the ciphertext.
 	if len(cipherData) < aes.BlockSize {
-		panic("ciphertext too short")
+		return nil, ErrCiphertextTooShort
 	}
-	iv := cipherData[:aes.BlockSize]
-	cipherData = cipherData[aes.BlockSize:]
 
-	stream := cipher.NewCFBDecrypter(block, iv)
+	plainData := make([]byte, len(cipherData)-aes.BlockSize)
 
-	// XORKeyStream can work in-place if the two arguments are the same.
-	stream.XORKeyStream(cipherData, cipherData)
+	stream := cipher.NewCFBDecrypter(block, cipherData[:aes.BlockSize])
+	stream.XORKeyStream(plainData, cipherData[aes.BlockSize:])
 
-	return cipherData, nil
+	return plainData, nil
 }
 
-// Encrypt ...
+// Encrypt encrypts and authenticates data with AES-GCM. A 32 character
+// key uses AES-256.
 func Encrypt(k string, plainData []byte) ([]byte, error) {
-	key := []byte(k)
+	header := append(append([]byte{}, cipherHeader...), gcmVersion)
 
-	block, err := aes.NewCipher(key)
+	sealed, err := gcmSeal([]byte(k), plainData, header)
 	if err != nil {
 		return []byte{}, err
 	}
 
-	// The IV needs to be unique, but not secure. Therefore it's common to
-	// include it at the beginning of the ciphertext.
-	cipherData := make([]byte, aes.BlockSize+len(plainData))
-	iv := cipherData[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
-		return []byte{}, err
-	}
-
-	stream := cipher.NewCFBEncrypter(block, iv)
-	stream.XORKeyStream(cipherData[aes.BlockSize:], plainData)
-
-	return cipherData, nil
+	return append(header, sealed...), nil
 }
 
-// GenerateAES256Key ...
+// GenerateAES256Key returns a random 32 character key for AES-256. It
+// panics when the system random number generator fails; use NewAES256Key
+// to handle the error.
 func GenerateAES256Key() string {
+	return must(NewAES256Key())
+}
+
+// NewAES256Key returns a random 32 character key for AES-256.
+func NewAES256Key() (string, error) {
 	const charset = "abcdefghijklmnopqrstuvwxyz" +
 		"ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789" +
-		"1234567890" +
 		"!@#$%^&*()_+=-~<>?/.,:;"
 
 	return stringWithCharset(32, charset)
 }
 
-var seededRand = mrand.New(mrand.NewSource(time.Now().UnixNano()))
+func must(key string, err error) string {
+	if err != nil {
+		panic(fmt.Sprintf("crypto/rand: %s", err))
+	}
+
+	return key
+}
+
+func stringWithCharset(length int, charset string) (string, error) {
+	max := big.NewInt(int64(len(charset)))
 
-func stringWithCharset(length int, charset string) string {
 	b := make([]byte, length)
 	for i := range b {
-		b[i] = charset[seededRand.Intn(len(charset))]
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+
+		b[i] = charset[n.Int64()]
 	}
-	return string(b)
+
+	return string(b), nil
 }
@@ -1,6 +1,8 @@
 package cipher
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"fmt"
 	"testing"
 )
@@ -20,3 +22,97 @@ func TestCipher(t *testing.T) {
 
 	fmt.Printf("Result: %s", string(data))
 }
+
+func TestEnsureTamperedCiphertextIsNotDecrypted(t *testing.T) {
+	// arrange
+	key := "AES256Key-32Characters1234567890"
+
+	data, err := Encrypt(key, []byte("ENV=dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	data[len(data)-1] ^= 1
+	_, err = Decrypt(key, data)
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: error \nACTUAL: tampered data decrypted")
+	}
+}
+
+func TestEnsureLegacyCiphertextIsDecrypted(t *testing.T) {
+	// arrange
+	key := "AES256Key-32Characters1234567890"
+	data := []byte("ENV=dev")
+
+	block, _ := aes.NewCipher([]byte(key))
+
+	legacy := make([]byte, aes.BlockSize+len(data))
+	copy(legacy, "0123456789abcdef")
+	cipher.NewCFBEncrypter(block, legacy[:aes.BlockSize]).XORKeyStream(legacy[aes.BlockSize:], data)
+
+	// act
+	plain, err := Decrypt(key, legacy)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if IsCurrent(legacy) || string(plain) != string(data) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", string(data), string(plain))
+	}
+}
+
+func TestEnsureShortCiphertextReturnsError(t *testing.T) {
+	// arrange
+	key := "AES256Key-32Characters1234567890"
+
+	for _, data := range [][]byte{[]byte("short"), cipherHeader, append(append([]byte{}, cipherHeader...), gcmVersion)} {
+		// act
+		_, err := Decrypt(key, data)
+
+		// assert
+		if err == nil {
+			t.Errorf("\nEXPECTED: error \nACTUAL: %q decrypted", data)
+		}
+	}
+}
+
+func TestEnsureGeneratedKeysAreUnique(t *testing.T) {
+	// arrange
+	keys := map[string]bool{}
+
+	for i := 0; i < 100; i++ {
+		// act
+		key, err := NewAES256Key()
+
+		// assert
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(key) != 32 || keys[key] {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "unique 32 character key", key)
+		}
+
+		keys[key] = true
+	}
+}
+
+func TestEnsureKeyWrappersKeepTheirSignatures(t *testing.T) {
+	// arrange
+	var generate func() string = GenerateAES256Key
+	var gen func(int) string = GenKey
+
+	// act
+	key := generate()
+	short := gen(8)
+
+	// assert
+	if len(key) != 32 || len(short) != 8 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %s", "32 and 8 character keys", key, short)
+	}
+}
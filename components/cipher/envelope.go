@@ -0,0 +1,186 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// EnvelopeAlgorithm is used to encrypt file data with a random data key
+// and to wrap the data key with the key encryption key.
+const EnvelopeAlgorithm = "AES-256-GCM"
+
+const envelopeVersion = 1
+
+// gcmOverhead is the size of the nonce and tag added to sealed data.
+const gcmOverhead = 12 + 16
+
+var envelopePrefix = []byte(`{"cstoreEnvelope":`)
+
+// ErrKeyMismatch is returned when data was encrypted with a different key.
+var ErrKeyMismatch = errors.New("data was encrypted with a different key")
+
+type envelope struct {
+	Version   int    `json:"cstoreEnvelope"`
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"keyId"`
+	DataKey   []byte `json:"dataKey"`
+	Data      []byte `json:"data"`
+}
+
+// GenerateKey returns a random base64 encoded 256 bit key.
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseKey accepts a base64 encoded 256 bit key or a 32 character key.
+func ParseKey(value string) ([]byte, error) {
+
+	if key, err := base64.StdEncoding.DecodeString(value); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	if len(value) == 32 {
+		return []byte(value), nil
+	}
+
+	return nil, errors.New("key must be a base64 encoded 256 bit key or 32 characters long")
+}
+
+// KeyID identifies a key without revealing it.
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// IsEnvelope checks if data was created by Seal.
+func IsEnvelope(data []byte) bool {
+	return bytes.HasPrefix(data, envelopePrefix)
+}
+
+// Seal encrypts data with a random data key and wraps the data key with
+// the key encryption key. The wrapped data key is stored with the data.
+func Seal(key, plainData []byte) ([]byte, error) {
+
+	dataKey := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return nil, err
+	}
+
+	e := envelope{
+		Version:   envelopeVersion,
+		Algorithm: EnvelopeAlgorithm,
+		KeyID:     KeyID(key),
+	}
+
+	var err error
+
+	if e.Data, err = gcmSeal(dataKey, plainData, e.aad()); err != nil {
+		return nil, err
+	}
+
+	if e.DataKey, err = gcmSeal(key, dataKey, e.aad()); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(e)
+}
+
+// SealedSize returns the size of the data created by Seal for plain data
+// of the given size. Stores limiting the size of a file receive the
+// sealed data.
+func SealedSize(size int) int {
+	e := envelope{
+		Version:   envelopeVersion,
+		Algorithm: EnvelopeAlgorithm,
+		KeyID:     strings.Repeat("0", 16),
+		DataKey:   make([]byte, gcmOverhead+32),
+		Data:      []byte{},
+	}
+
+	b, _ := json.Marshal(e)
+
+	return len(b) + base64.StdEncoding.EncodedLen(gcmOverhead+size)
+}
+
+// Open unwraps the data key with the key encryption key and decrypts data
+// created by Seal.
+func Open(key, data []byte) ([]byte, error) {
+
+	e := envelope{}
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("invalid envelope: %s", err)
+	}
+
+	if e.Version != envelopeVersion || e.Algorithm != EnvelopeAlgorithm {
+		return nil, fmt.Errorf("unsupported envelope %d %s", e.Version, e.Algorithm)
+	}
+
+	if e.KeyID != KeyID(key) {
+		return nil, ErrKeyMismatch
+	}
+
+	dataKey, err := gcmOpen(key, e.DataKey, e.aad())
+	if err != nil {
+		return nil, err
+	}
+
+	return gcmOpen(dataKey, e.Data, e.aad())
+}
+
+func (e envelope) aad() []byte {
+	return []byte(fmt.Sprintf("%d|%s|%s", e.Version, e.Algorithm, e.KeyID))
+}
+
+func gcmSeal(key, plainData, aad []byte) ([]byte, error) {
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plainData, aad), nil
+}
+
+func gcmOpen(key, cipherData, aad []byte) ([]byte, error) {
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cipherData) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return gcm.Open(nil, cipherData[:gcm.NonceSize()], cipherData[gcm.NonceSize():], aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
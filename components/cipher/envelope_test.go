@@ -0,0 +1,99 @@
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEnsureEnvelopeIsDecryptedWithTheSameKey(t *testing.T) {
+	// arrange
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := ParseKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("DB_PASSWORD=shh...")
+
+	// act
+	sealed, err := Seal(k, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opened, err := Open(k, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if !IsEnvelope(sealed) || bytes.Contains(sealed, data) {
+		t.Errorf("\nEXPECTED: envelope \nACTUAL: %s", string(sealed))
+	}
+
+	if !bytes.Equal(opened, data) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", string(data), string(opened))
+	}
+}
+
+func TestEnsureEnvelopeIsNotDecryptedWithADifferentKey(t *testing.T) {
+	// arrange
+	k1, _ := ParseKey("AES256Key-32Characters1234567890")
+	k2, _ := ParseKey("AES256Key-32Characters0987654321")
+
+	sealed, err := Seal(k1, []byte("ENV=dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, err = Open(k2, sealed)
+
+	// assert
+	if err != ErrKeyMismatch {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", ErrKeyMismatch, err)
+	}
+}
+
+func TestEnsureTamperedEnvelopeIsNotDecrypted(t *testing.T) {
+	// arrange
+	k, _ := ParseKey("AES256Key-32Characters1234567890")
+
+	sealed, err := Seal(k, []byte("ENV=dev"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	tampered := bytes.Replace(sealed, []byte(`"data":"`), []byte(`"data":"AA`), 1)
+	_, err = Open(k, tampered)
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: error \nACTUAL: tampered data decrypted")
+	}
+}
+
+func TestEnsureSealedSizeMatchesTheEnvelope(t *testing.T) {
+	// arrange
+	k, _ := ParseKey("AES256Key-32Characters1234567890")
+
+	for _, size := range []int{0, 1, 2, 3, 100, 4096} {
+		sealed, err := Seal(k, bytes.Repeat([]byte("A"), size))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// act
+		actual := SealedSize(size)
+
+		// assert
+		if actual != len(sealed) {
+			t.Errorf("\nEXPECTED: %d \nACTUAL: %d", len(sealed), actual)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// KDFAlgorithm derives keys from passphrases.
+const KDFAlgorithm = "argon2id"
+
+var kdfPrefix = []byte(`{"kdf":`)
+
+// KDFParams are the settings used to derive a key from a passphrase. They
+// are not secret and are stored in place of the key.
+type KDFParams struct {
+	KDF     string `json:"kdf"`
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// NewKDFParams returns settings with a random salt.
+func NewKDFParams() (KDFParams, error) {
+	p := KDFParams{
+		KDF:     KDFAlgorithm,
+		Salt:    make([]byte, 16),
+		Time:    3,
+		Memory:  64 * 1024,
+		Threads: 4,
+	}
+
+	if _, err := io.ReadFull(rand.Reader, p.Salt); err != nil {
+		return p, err
+	}
+
+	return p, nil
+}
+
+// IsKDFParams checks if data was created by KDFParams.Marshal.
+func IsKDFParams(data []byte) bool {
+	return bytes.HasPrefix(data, kdfPrefix)
+}
+
+// ParseKDFParams reads settings created by KDFParams.Marshal.
+func ParseKDFParams(data []byte) (KDFParams, error) {
+	p := KDFParams{}
+
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("invalid key derivation settings: %s", err)
+	}
+
+	if p.KDF != KDFAlgorithm || len(p.Salt) == 0 || p.Time == 0 || p.Memory == 0 || p.Threads == 0 {
+		return p, fmt.Errorf("unsupported key derivation settings %s", p.KDF)
+	}
+
+	return p, nil
+}
+
+// Marshal encodes the settings.
+func (p KDFParams) Marshal() ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// DeriveKey returns a 32 byte key for AES-256.
+func (p KDFParams) DeriveKey(passphrase string) string {
+	return string(argon2.IDKey([]byte(passphrase), p.Salt, p.Time, p.Memory, p.Threads, 32))
+}
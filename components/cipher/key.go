@@ -1,21 +1,14 @@
 package cipher
 
-import (
-	"math/rand"
-	"time"
-)
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890"
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// GenKey returns a random alphanumeric key of length n. It panics when the
+// system random number generator fails; use NewKey to handle the error.
+func GenKey(n int) string {
+	return must(NewKey(n))
 }
 
-var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ1234567890")
-
-// GenKey ...
-func GenKey(n int) string {
-	b := make([]rune, n)
-	for i := range b {
-		b[i] = letterRunes[rand.Intn(len(letterRunes))]
-	}
-	return string(b)
+// NewKey returns a random alphanumeric key of length n.
+func NewKey(n int) (string, error) {
+	return stringWithCharset(n, alphanumeric)
 }
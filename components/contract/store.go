@@ -2,6 +2,7 @@ package contract
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/turnerlabs/cstore/v4/components/catalog"
@@ -23,13 +24,11 @@ type IStore interface {
 	// flag.
 	Name() string
 
-	// SupportsFeature should return true or false depending on which storage
-	// feature is passed. An example would be support for versioning.
-	SupportsFeature(feature string) bool
-
-	// SupportsFileType should return true or false depending on which file
-	// type is passed. Examples would be support for .env or .json files.
-	SupportsFileType(fileType string) bool
+	// Capabilities describes what the store is able to do, like
+	// versioning files or storing .env and .json files. The CLI uses
+	// the capabilities to validate a request before any changes are
+	// made.
+	Capabilities() Capabilities
 
 	// Description provides details on how to use the store and is
 	// displayed on the command line when store details are requested.
@@ -74,7 +73,7 @@ type IStore interface {
 	// there is no need to return the file for data to be saved.
 	//
 	// "version" contains the version that the file contents should be
-	// stored under, but does not need to be used if the "Supports"
+	// stored under, but does not need to be used if the "Capabilities"
 	// function does not indicate the store supports versioning.
 	// Versioning is the ability for a store copy the file contents
 	// and store/retrieve it separately from the working copy.
@@ -85,12 +84,13 @@ type IStore interface {
 	// Pull is called when a file needs to be retrieved from the remote store.
 	//
 	// "version" contains the version of the file contents that should
-	// be retrieved, but does not need to be used if the "Supports"
+	// be retrieved, but does not need to be used if the "Capabilities"
 	// function does not indicate the store supports versioning.
 	//
 	// The "[]byte"" array should be the contents of the retrieved file.
 	//
-	// "Attributes" should return the time the file was last updated.
+	// "Attributes" should describe the remote copy of the file that was
+	// retrieved, like the time the file was last updated.
 	//
 	// "error" should return nil if the operation was successful.
 	Pull(file *catalog.File, version string) ([]byte, Attributes, error)
@@ -98,7 +98,7 @@ type IStore interface {
 	// Purge is called when a file needs to be deleted from the remote store.
 	//
 	// "version" contains the version of the file contents that should
-	// be deleted, but does not need to be used if the "Supports"
+	// be deleted, but does not need to be used if the "Capabilities"
 	// function does not indicate the store supports versioning. Purge
 	// should not delete the working copy of the file if len(version) > 0.
 	//
@@ -112,10 +112,161 @@ type IStore interface {
 	//
 	// "error" should return nil if the operation was successful.
 	Changed(file *catalog.File, fileData []byte, version string) (time.Time, error)
+
+	// Revision is called to get an opaque token identifying the remote
+	// copy of a file, like an ETag or version id. The token is saved
+	// when a file is pulled and compared before the file is pushed to
+	// detect changes made by other users.
+	//
+	// "string" should return "" when the file is not found or the store
+	// cannot identify revisions.
+	//
+	// "error" should return nil if the operation was successful.
+	Revision(file *catalog.File, version string) (string, error)
+}
+
+// Capabilities describe what a store is able to do with a file.
+type Capabilities struct {
+	// Versioning stores copies of a file separately by version.
+	Versioning bool
+
+	// History keeps prior copies of a file that can be listed and
+	// restored. Stores with history implement IHistory.
+	History bool
+
+	// ConditionalWrites atomically reject a push when the file changed
+	// remotely. Stores with conditional writes implement
+	// IConditionalStore.
+	ConditionalWrites bool
+
+	// ClientEncryption allows files to be encrypted before a push.
+	ClientEncryption bool
+
+	// SourceControl indicates files are kept in the repository instead
+	// of a remote location.
+	SourceControl bool
+
+	// Binary allows files that are not valid UTF-8 text.
+	Binary bool
+
+	// MaxSize is the largest file in bytes the store accepts. Zero
+	// means there is no limit.
+	MaxSize int
+
+	// FileTypes lists the file types the store accepts. An empty list
+	// means any file type is accepted, unless NoFileTypes is set.
+	FileTypes []string
+
+	// NoFileTypes is set when the store accepts no file types, like a
+	// mirror of stores without a file type in common.
+	NoFileTypes bool
+}
+
+// SupportsFileType ...
+func (c Capabilities) SupportsFileType(fileType string) bool {
+	if c.NoFileTypes {
+		return false
+	}
+
+	if len(c.FileTypes) == 0 {
+		return true
+	}
+
+	for _, t := range c.FileTypes {
+		if t == fileType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnsupportedError is returned when a store is missing a capability
+// an operation requires.
+type UnsupportedError struct {
+	Store      string
+	Capability string
+}
+
+func (e UnsupportedError) Error() string {
+	return fmt.Sprintf("%s store does not support %s", e.Store, e.Capability)
+}
+
+// IConditionalStore is an optional store abstraction implemented by
+// stores that can atomically reject a write when the remote copy of a
+// file changed.
+type IConditionalStore interface {
+
+	// PushIf stores the file like Push, but only when the remote copy
+	// of the file is still at "revision". When "revision" is empty, the
+	// write is not conditional.
+	//
+	// "string" should return the revision of the stored file.
+	//
+	// "error" should return ErrRevisionConflict when the remote copy
+	// of the file changed.
+	PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error)
 }
 
+// ErrRevisionConflict is returned when a file changed remotely since
+// it was last pulled.
+var ErrRevisionConflict = errors.New("file changed remotely since it was last pulled")
+
 // ErrStoreNotFound is returned when the store is not implemented.
 var ErrStoreNotFound = errors.New("store not found")
 
-// Attributes ...
-type Attributes struct{}
+// Attributes describe the remote copy of a file. Fields are empty when
+// the store does not provide them.
+type Attributes struct {
+	// Modified is when the remote copy was last changed.
+	Modified time.Time
+
+	// Revision identifies the remote copy like an ETag or version id.
+	Revision string
+
+	// Size is the number of bytes retrieved.
+	Size int
+
+	// Checksum is the hex encoded SHA-256 of the data retrieved.
+	Checksum string
+
+	// KMSKeyID is the key used by the store to encrypt the file.
+	KMSKeyID string
+
+	// LastWriter identifies who last changed the remote copy.
+	LastWriter string
+}
+
+// IStatStore is an optional store abstraction implemented by stores
+// that can describe the remote copy of a file without retrieving it.
+type IStatStore interface {
+
+	// Stat gets the attributes of the remote copy of a file, like Pull,
+	// without retrieving the file. Size and Checksum are empty when the
+	// store cannot provide them without the file.
+	Stat(file *catalog.File, version string) (Attributes, error)
+}
+
+// IHistory is an optional store abstraction implemented by stores
+// that keep prior copies of a file each time it is pushed.
+type IHistory interface {
+
+	// History lists the prior copies of a file, newest first.
+	History(file *catalog.File) ([]Revision, error)
+
+	// Rollback restores a prior copy of a file as the working copy.
+	// "revision" can be the store's revision id or a user version.
+	Rollback(file *catalog.File, revision string) error
+}
+
+// ErrHistoryNotSupported is returned when the store does not keep
+// prior copies of a file.
+var ErrHistoryNotSupported = errors.New("store does not keep file history")
+
+// Revision describes a prior copy of a file.
+type Revision struct {
+	ID       string
+	Version  string
+	Modified time.Time
+	Latest   bool
+}
@@ -102,3 +102,53 @@ type IVault interface {
 // ErrSecretNotFound is returned by the vault when the
 // requested key cannot be found in the vault.
 var ErrSecretNotFound = errors.New("not found")
+
+// IUpgradableVault is an optional vault abstraction implemented by
+// vaults that can rewrite secrets stored in an older format.
+type IUpgradableVault interface {
+
+	// Upgrade rewrites secrets stored in an older format using the
+	// current format.
+	//
+	// "bool" should return true when secrets were rewritten.
+	Upgrade() (bool, error)
+}
+
+// IRotatableVault is an optional vault abstraction implemented by
+// vaults encrypting secrets with a key they manage.
+type IRotatableVault interface {
+
+	// RotateKey re-encrypts the secrets with a new key. Secrets must
+	// not be lost when rotation is interrupted.
+	RotateKey() error
+}
+
+// IPassphraseVault is an optional vault abstraction implemented by
+// vaults that can derive their key from a passphrase.
+type IPassphraseVault interface {
+
+	// SetPassphrase re-encrypts the secrets with a key derived from a
+	// new passphrase.
+	SetPassphrase() error
+
+	// RemovePassphrase re-encrypts the secrets with a key that is not
+	// derived from a passphrase.
+	RemovePassphrase() error
+}
+
+// IRecipientVault is an optional vault abstraction implemented by
+// vaults encrypting secrets to the public keys of their recipients.
+type IRecipientVault interface {
+
+	// AddRecipient re-encrypts the secrets for the recipients and the
+	// owners of "publicKey".
+	//
+	// "[]string" should describe the recipients added.
+	AddRecipient(publicKey []byte) ([]string, error)
+
+	// RemoveRecipient re-encrypts the secrets without the recipient
+	// identified by "id".
+	//
+	// "string" should describe the recipient removed.
+	RemoveRecipient(id string) (string, error)
+}
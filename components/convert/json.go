@@ -0,0 +1,213 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of JSON values recorded when flattening a document. A value
+// without a type is a string.
+const (
+	ObjectType  = "object"
+	ArrayType   = "array"
+	StringType  = "string"
+	NumberType  = "number"
+	BooleanType = "boolean"
+	NullType    = "null"
+)
+
+// PathSeparator delimits keys in a flattened JSON path.
+const PathSeparator = "/"
+
+// FlattenJSON returns the leaf values of a JSON object or array keyed by
+// their path. Types are returned for arrays, empty objects, and values that
+// are not strings, so the document can be rebuilt. Empty strings and nulls
+// only have a type.
+func FlattenJSON(file []byte) (map[string]string, map[string]string, error) {
+	values := map[string]string{}
+	types := map[string]string{}
+
+	d := json.NewDecoder(bytes.NewReader(file))
+	d.UseNumber()
+
+	var doc interface{}
+	if err := d.Decode(&doc); err != nil {
+		return values, types, err
+	}
+
+	switch doc.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return values, types, errors.New("JSON document must be an object or array")
+	}
+
+	return values, types, flatten("", doc, values, types)
+}
+
+func flatten(path string, v interface{}, values, types map[string]string) error {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		if len(t) == 0 {
+			types[path] = ObjectType
+		}
+
+		for key, child := range t {
+			if len(key) == 0 || strings.Contains(key, PathSeparator) {
+				return fmt.Errorf("JSON key %q at %q cannot be used in a path", key, path)
+			}
+
+			if err := flatten(join(path, key), child, values, types); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		types[path] = ArrayType
+
+		for i, child := range t {
+			if err := flatten(join(path, strconv.Itoa(i)), child, values, types); err != nil {
+				return err
+			}
+		}
+	case string:
+		if len(t) == 0 {
+			types[path] = StringType
+		} else {
+			values[path] = t
+		}
+	case json.Number:
+		values[path] = t.String()
+		types[path] = NumberType
+	case bool:
+		values[path] = strconv.FormatBool(t)
+		types[path] = BooleanType
+	case nil:
+		types[path] = NullType
+	}
+
+	return nil
+}
+
+func join(path, key string) string {
+	if len(path) == 0 {
+		return key
+	}
+
+	return path + PathSeparator + key
+}
+
+// UnflattenJSON rebuilds a JSON document from the values and types
+// returned by FlattenJSON.
+func UnflattenJSON(values, types map[string]string) ([]byte, error) {
+	root := &node{kind: types[""]}
+
+	paths := []string{}
+	for path := range values {
+		paths = append(paths, path)
+	}
+	for path := range types {
+		if _, found := values[path]; !found && len(path) > 0 {
+			paths = append(paths, path)
+		}
+	}
+
+	for _, path := range paths {
+		n := root
+		keys := strings.Split(path, PathSeparator)
+
+		for i := range keys {
+			child, found := n.children[keys[i]]
+			if !found {
+				child = &node{kind: types[strings.Join(keys[:i+1], PathSeparator)]}
+
+				if n.children == nil {
+					n.children = map[string]*node{}
+				}
+				n.children[keys[i]] = child
+			}
+
+			n = child
+		}
+
+		value, err := leaf(path, values[path], n.kind)
+		if err != nil {
+			return nil, err
+		}
+
+		n.value = value
+	}
+
+	var buff bytes.Buffer
+
+	e := json.NewEncoder(&buff)
+	e.SetEscapeHTML(false)
+	e.SetIndent("", "    ")
+
+	if err := e.Encode(root.build()); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(buff.Bytes(), "\n"), nil
+}
+
+func leaf(path, value, kind string) (interface{}, error) {
+	switch kind {
+	case ObjectType, ArrayType, NullType:
+		return nil, nil
+	case NumberType:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, fmt.Errorf("invalid number at %q: %s", path, value)
+		}
+
+		return json.Number(value), nil
+	case BooleanType:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean at %q: %s", path, value)
+		}
+
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// node is a value in a document being rebuilt.
+type node struct {
+	kind     string
+	children map[string]*node
+	value    interface{}
+}
+
+func (n *node) build() interface{} {
+	switch {
+	case n.kind == ArrayType:
+		indexes := []int{}
+		for key := range n.children {
+			if i, err := strconv.Atoi(key); err == nil {
+				indexes = append(indexes, i)
+			}
+		}
+		sort.Ints(indexes)
+
+		array := []interface{}{}
+		for _, i := range indexes {
+			array = append(array, n.children[strconv.Itoa(i)].build())
+		}
+
+		return array
+	case n.kind == ObjectType || n.children != nil:
+		object := map[string]interface{}{}
+		for key, child := range n.children {
+			object[key] = child.build()
+		}
+
+		return object
+	default:
+		return n.value
+	}
+}
@@ -0,0 +1,76 @@
+package convert
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestEnsureFlattenedJSONIsRebuiltWithTypes(t *testing.T) {
+	// arrange
+	doc := `{
+		"db": {"host": "localhost", "port": 5432, "ssl": true, "password": null, "user": ""},
+		"hosts": ["a", "b", {"weight": 1.50}],
+		"empty": {},
+		"none": [],
+		"html": "<b>&</b>"
+	}`
+
+	values, types, err := FlattenJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	b, err := UnflattenJSON(values, types)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if values["db/host"] != "localhost" || values["hosts/2/weight"] != "1.50" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "leaf values by path", values)
+	}
+
+	var expected, actual interface{}
+	json.Unmarshal([]byte(doc), &expected)
+	json.Unmarshal(b, &actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", doc, string(b))
+	}
+}
+
+func TestEnsureRootArrayIsRebuilt(t *testing.T) {
+	// arrange
+	values, types, err := FlattenJSON([]byte(`[1, "2", [3]]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	b, err := UnflattenJSON(values, types)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var actual interface{}
+	json.Unmarshal(b, &actual)
+
+	if !reflect.DeepEqual(actual, []interface{}{1.0, "2", []interface{}{3.0}}) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", `[1, "2", [3]]`, string(b))
+	}
+}
+
+func TestEnsureKeysContainingSeparatorAreRejected(t *testing.T) {
+	// act
+	_, _, err := FlattenJSON([]byte(`{"a/b": "c"}`))
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "error", err)
+	}
+}
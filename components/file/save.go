@@ -3,6 +3,7 @@ package file
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -19,6 +20,47 @@ func Save(path string, b []byte) error {
 	return nil
 }
 
+// SaveAtomic writes to a temporary file and renames it over the file, so
+// an interrupted save leaves either the old or the new file.
+func SaveAtomic(path string, b []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	//------------------------------------------
+	//- Remove the temporary file unless it was
+	//- renamed.
+	//------------------------------------------
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
 func notForwardSlash(char rune) bool {
 	if char != '/' {
 		return true
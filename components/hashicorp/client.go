@@ -0,0 +1,194 @@
+package hashicorp
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	addrSetting       = "VAULT_ADDR"
+	authMethodSetting = "VAULT_AUTH_METHOD"
+	tokenSetting      = "VAULT_TOKEN"
+	roleIDSetting     = "VAULT_ROLE_ID"
+	secretIDSetting   = "VAULT_SECRET_ID"
+	k8sRoleSetting    = "VAULT_K8S_ROLE"
+
+	defaultAddr = "http://127.0.0.1:8200"
+
+	tokenAuth      = "token"
+	appRoleAuth    = "approle"
+	kubernetesAuth = "kubernetes"
+
+	k8sTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+var clients = map[string]*api.Client{}
+
+// Client returns an authenticated HashiCorp Vault client. The address and
+// auth method are saved in the catalog file entry while credentials are
+// retrieved from the access vault.
+//
+// Clients are cached by address, auth method, and the context and access
+// vault credentials are read from to avoid authenticating for every file
+// in large catalogs.
+func Client(clog catalog.Catalog, file *catalog.File, access setting.IKeyValueStore, uo cfg.UserOptions, io models.IO) (*api.Client, error) {
+
+	//------------------------------------------
+	//- Get Vault Address
+	//------------------------------------------
+	addr, err := setting.Setting{
+		Description:  "HashiCorp Vault server address.",
+		Prop:         addrSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		PromptOnce:   true,
+		DefaultValue: clog.GetDataByStore("", addrSetting, defaultAddr),
+		Vault:        file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(addr) == 0 {
+		addr = clog.GetDataByStore("", addrSetting, defaultAddr)
+	}
+
+	//------------------------------------------
+	//- Get Vault Auth Method
+	//------------------------------------------
+	method, err := setting.Setting{
+		Description:  fmt.Sprintf("HashiCorp Vault auth method. (%s|%s|%s)", tokenAuth, appRoleAuth, kubernetesAuth),
+		Prop:         authMethodSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		PromptOnce:   true,
+		DefaultValue: clog.GetDataByStore("", authMethodSetting, tokenAuth),
+		Vault:        file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(method) == 0 {
+		method = tokenAuth
+	}
+
+	//------------------------------------------
+	//- Linked catalogs read credentials for each
+	//- context, so clients are not shared across
+	//- contexts.
+	//------------------------------------------
+	key := fmt.Sprintf("%s|%s|%s|%s", addr, method, access.Name(), clog.Context)
+
+	if client, found := clients[key]; found {
+		return client, nil
+	}
+
+	config := api.DefaultConfig()
+	config.Address = addr
+
+	client, err := api.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	//------------------------------------------
+	//- Authenticate
+	//------------------------------------------
+	switch strings.ToLower(method) {
+	case tokenAuth:
+		token, err := credential(tokenSetting, clog, access, uo, io)
+		if err != nil {
+			return nil, err
+		}
+
+		client.SetToken(token)
+	case appRoleAuth:
+		roleID, err := credential(roleIDSetting, clog, access, uo, io)
+		if err != nil {
+			return nil, err
+		}
+
+		secretID, err := credential(secretIDSetting, clog, access, uo, io)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := login(client, "auth/approle/login", map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		}); err != nil {
+			return nil, err
+		}
+	case kubernetesAuth:
+		role, err := setting.Setting{
+			Description:  "HashiCorp Vault role bound to the Kubernetes service account.",
+			Prop:         k8sRoleSetting,
+			Prompt:       uo.Prompt,
+			Silent:       uo.Silent,
+			AutoSave:     true,
+			PromptOnce:   true,
+			DefaultValue: clog.GetDataByStore("", k8sRoleSetting, ""),
+			Vault:        file,
+		}.Get(clog.Context, io)
+		if err != nil {
+			return nil, err
+		}
+
+		jwt, err := ioutil.ReadFile(k8sTokenPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := login(client, "auth/kubernetes/login", map[string]interface{}{
+			"role": role,
+			"jwt":  strings.TrimSpace(string(jwt)),
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported HashiCorp Vault auth method: %s", method)
+	}
+
+	clients[key] = client
+
+	return client, nil
+}
+
+func credential(prop string, clog catalog.Catalog, access setting.IKeyValueStore, uo cfg.UserOptions, io models.IO) (string, error) {
+	return setting.Setting{
+		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
+		Group:       clog.Context,
+		Prop:        prop,
+		Prompt:      uo.Prompt,
+		Silent:      uo.Silent,
+		AutoSave:    true,
+		PromptOnce:  true,
+		Vault:       access,
+	}.Get(clog.Context, io)
+}
+
+func login(client *api.Client, path string, data map[string]interface{}) error {
+	secret, err := client.Logical().Write(path, data)
+	if err != nil {
+		return err
+	}
+
+	if secret == nil || secret.Auth == nil {
+		return errors.New("HashiCorp Vault login did not return a token")
+	}
+
+	client.SetToken(secret.Auth.ClientToken)
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package hashicorp
+
+import (
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// memVault keeps credentials by context in memory.
+type memVault map[string]string
+
+func (v memVault) Name() string { return "memory" }
+
+func (v memVault) BuildKey(contextID, group, prop string) string {
+	return group + "/" + prop
+}
+
+func (v memVault) Get(contextID, group, prop string) (string, error) {
+	if value, found := v[v.BuildKey(contextID, group, prop)]; found {
+		return value, nil
+	}
+
+	return "", contract.ErrSecretNotFound
+}
+
+func (v memVault) Set(contextID, group, prop, value string) error {
+	v[v.BuildKey(contextID, group, prop)] = value
+	return nil
+}
+
+func (v memVault) Delete(contextID, group, prop string) error {
+	delete(v, v.BuildKey(contextID, group, prop))
+	return nil
+}
+
+func TestEnsureClientsAreNotSharedAcrossContexts(t *testing.T) {
+	// arrange
+	access := memVault{
+		"app/" + tokenSetting:    "app-token",
+		"shared/" + tokenSetting: "shared-token",
+	}
+
+	file := &catalog.File{
+		Data: map[string]string{
+			addrSetting:       "http://127.0.0.1:8200",
+			authMethodSetting: tokenAuth,
+		},
+	}
+
+	uo := cfg.UserOptions{Silent: true}
+
+	// act
+	app, err := Client(catalog.Catalog{Context: "app"}, file, access, uo, models.IO{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shared, err := Client(catalog.Catalog{Context: "shared"}, file, access, uo, models.IO{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if app.Token() != "app-token" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "app-token", app.Token())
+	}
+
+	if shared.Token() != "shared-token" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "shared-token", shared.Token())
+	}
+}
@@ -0,0 +1,117 @@
+// Package hashicorptest provides a fake HashiCorp Vault KV version 2
+// secrets engine for tests that do not have a Vault dev server.
+package hashicorptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NewKV returns a fake KV version 2 engine accepting any token and
+// mount.
+func NewKV() http.Handler {
+	return &kv{secrets: map[string][]kvVersion{}, custom: map[string]interface{}{}}
+}
+
+type kv struct {
+	sync.Mutex
+	secrets map[string][]kvVersion
+	custom  map[string]interface{}
+}
+
+type kvVersion struct {
+	data      map[string]interface{}
+	created   time.Time
+	destroyed bool
+}
+
+func (f *kv) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/v1/"), "/", 3)
+	if len(parts) < 3 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	op, path := parts[1], parts[2]
+	versions := f.secrets[path]
+
+	body := map[string]interface{}{}
+	json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case op == "data" && r.Method == http.MethodPut:
+		if options, ok := body["options"].(map[string]interface{}); ok {
+			if cas, ok := options["cas"].(float64); ok && int(cas) != len(versions) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]interface{}{"errors": []string{"check-and-set parameter did not match the current version"}})
+				return
+			}
+		}
+
+		data, _ := body["data"].(map[string]interface{})
+		versions = append(versions, kvVersion{data: data, created: time.Now().UTC()})
+		f.secrets[path] = versions
+
+		respond(w, map[string]interface{}{"version": len(versions), "created_time": versions[len(versions)-1].created.Format(time.RFC3339Nano)})
+	case op == "data" && r.Method == http.MethodGet:
+		n := len(versions)
+		if v := r.URL.Query().Get("version"); len(v) > 0 {
+			n, _ = strconv.Atoi(v)
+		}
+
+		if n == 0 || n > len(versions) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		version := versions[n-1]
+		meta := map[string]interface{}{"version": n, "created_time": version.created.Format(time.RFC3339Nano), "destroyed": version.destroyed}
+
+		if version.destroyed {
+			respond(w, map[string]interface{}{"data": nil, "metadata": meta})
+			return
+		}
+
+		respond(w, map[string]interface{}{"data": version.data, "metadata": meta})
+	case op == "metadata" && r.Method == http.MethodGet:
+		if len(versions) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		meta := map[string]interface{}{}
+		for i, v := range versions {
+			meta[strconv.Itoa(i+1)] = map[string]interface{}{"created_time": v.created.Format(time.RFC3339Nano), "destroyed": v.destroyed}
+		}
+
+		respond(w, map[string]interface{}{"current_version": len(versions), "updated_time": versions[len(versions)-1].created.Format(time.RFC3339Nano), "versions": meta, "custom_metadata": f.custom[path]})
+	case op == "metadata" && r.Method == http.MethodPut:
+		f.custom[path] = body["custom_metadata"]
+		w.WriteHeader(http.StatusNoContent)
+	case op == "metadata" && r.Method == http.MethodDelete:
+		delete(f.secrets, path)
+		delete(f.custom, path)
+		w.WriteHeader(http.StatusNoContent)
+	case op == "destroy":
+		list, _ := body["versions"].([]interface{})
+		for _, n := range list {
+			if i := int(n.(float64)); i > 0 && i <= len(versions) {
+				versions[i-1].destroyed = true
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func respond(w http.ResponseWriter, data map[string]interface{}) {
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+}
@@ -0,0 +1,203 @@
+package hashicorp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+)
+
+const (
+	// MountSetting is the catalog data key for the KV v2 secrets engine mount.
+	MountSetting = "VAULT_KV_MOUNT"
+
+	// DefaultMount is the mount of the KV v2 engine on Vault dev servers.
+	DefaultMount = "secret"
+)
+
+// Version describes a single version of a KV v2 secret.
+type Version struct {
+	Number    int
+	Created   time.Time
+	Deleted   bool
+	Destroyed bool
+}
+
+// Metadata describes all versions of a KV v2 secret.
+type Metadata struct {
+	CurrentVersion int
+	Updated        time.Time
+	Versions       map[int]Version
+	Custom         map[string]string
+}
+
+// Read gets the data stored in a KV v2 secret. When version is zero, the
+// current version is returned. contract.ErrSecretNotFound is returned when
+// the secret or version does not exist or was deleted.
+func Read(client *api.Client, mount, path string, version int) (map[string]interface{}, Version, error) {
+	params := map[string][]string{}
+
+	if version > 0 {
+		params["version"] = []string{strconv.Itoa(version)}
+	}
+
+	secret, err := client.Logical().ReadWithData(fmt.Sprintf("%s/data/%s", mount, path), params)
+	if err != nil {
+		return nil, Version{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return nil, Version{}, contract.ErrSecretNotFound
+	}
+
+	meta := Version{}
+	if m, ok := secret.Data["metadata"].(map[string]interface{}); ok {
+		meta = toVersion(m)
+	}
+
+	data, ok := secret.Data["data"].(map[string]interface{})
+	if !ok || data == nil {
+		return nil, meta, contract.ErrSecretNotFound
+	}
+
+	return data, meta, nil
+}
+
+// Write creates a new version of a KV v2 secret. When cas is greater
+// than -1, the write only succeeds when the current version of the
+// secret matches cas; zero requires the secret to not exist.
+func Write(client *api.Client, mount, path string, data map[string]interface{}, cas int) (Version, error) {
+	body := map[string]interface{}{
+		"data": data,
+	}
+
+	if cas > -1 {
+		body["options"] = map[string]interface{}{
+			"cas": cas,
+		}
+	}
+
+	secret, err := client.Logical().Write(fmt.Sprintf("%s/data/%s", mount, path), body)
+	if err != nil {
+		return Version{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return Version{}, nil
+	}
+
+	return toVersion(secret.Data), nil
+}
+
+// ReadMetadata gets the version history of a KV v2 secret.
+// contract.ErrSecretNotFound is returned when the secret does not exist.
+func ReadMetadata(client *api.Client, mount, path string) (Metadata, error) {
+	secret, err := client.Logical().Read(fmt.Sprintf("%s/metadata/%s", mount, path))
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if secret == nil || secret.Data == nil {
+		return Metadata{}, contract.ErrSecretNotFound
+	}
+
+	meta := Metadata{
+		CurrentVersion: toInt(secret.Data["current_version"]),
+		Updated:        toTime(secret.Data["updated_time"]),
+		Versions:       map[int]Version{},
+		Custom:         map[string]string{},
+	}
+
+	if custom, ok := secret.Data["custom_metadata"].(map[string]interface{}); ok {
+		for k, v := range custom {
+			if value, ok := v.(string); ok {
+				meta.Custom[k] = value
+			}
+		}
+	}
+
+	if versions, ok := secret.Data["versions"].(map[string]interface{}); ok {
+		for number, v := range versions {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			version := toVersion(m)
+			version.Number, _ = strconv.Atoi(number)
+
+			meta.Versions[version.Number] = version
+		}
+	}
+
+	return meta, nil
+}
+
+// WriteCustomMetadata replaces the custom metadata of a KV v2 secret.
+// Custom metadata requires Vault 1.9 or later.
+func WriteCustomMetadata(client *api.Client, mount, path string, custom map[string]string) error {
+	_, err := client.Logical().Write(fmt.Sprintf("%s/metadata/%s", mount, path), map[string]interface{}{
+		"custom_metadata": custom,
+	})
+
+	return err
+}
+
+// Destroy permanently removes the specified versions of a KV v2 secret.
+func Destroy(client *api.Client, mount, path string, versions ...int) error {
+	_, err := client.Logical().Write(fmt.Sprintf("%s/destroy/%s", mount, path), map[string]interface{}{
+		"versions": versions,
+	})
+
+	return err
+}
+
+// DeleteAll permanently removes a KV v2 secret and all of its versions.
+func DeleteAll(client *api.Client, mount, path string) error {
+	_, err := client.Logical().Delete(fmt.Sprintf("%s/metadata/%s", mount, path))
+
+	return err
+}
+
+func toVersion(m map[string]interface{}) Version {
+	deleted := toTime(m["deletion_time"])
+	destroyed, _ := m["destroyed"].(bool)
+
+	return Version{
+		Number:    toInt(m["version"]),
+		Created:   toTime(m["created_time"]),
+		Deleted:   !deleted.IsZero(),
+		Destroyed: destroyed,
+	}
+}
+
+func toInt(value interface{}) int {
+	switch v := value.(type) {
+	case json.Number:
+		i, _ := v.Int64()
+		return int(i)
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func toTime(value interface{}) time.Time {
+	s, ok := value.(string)
+	if !ok || len(s) == 0 {
+		return time.Time{}
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return t
+}
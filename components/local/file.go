@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/turnerlabs/cstore/v4/components/cipher"
@@ -33,8 +34,7 @@ func Update(name, key string, data []byte) error {
 		}
 	}
 
-	return file.Save(BuildPath(name), data)
-
+	return file.SaveAtomic(BuildPath(name), data, 0600)
 }
 
 // Missing ...
@@ -44,6 +44,25 @@ func Missing(name string) bool {
 	return os.IsNotExist(err)
 }
 
+// Remove ...
+func Remove(name string) error {
+	if err := os.Remove(BuildPath(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Modified returns when the file was last changed.
+func Modified(name string) (time.Time, error) {
+	info, err := os.Stat(BuildPath(name))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
 // Get ...
 func Get(name, key string) ([]byte, error) {
 
@@ -0,0 +1,47 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Lock blocks until an exclusive advisory lock is held on the name. The
+// returned func releases the lock. Locks are held by open files, so they
+// are released when a process exits.
+func Lock(name string) (func(), error) {
+	path := BuildPath(name + ".lock")
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// List returns the names of files matching the pattern.
+func List(pattern string) ([]string, error) {
+	paths, err := filepath.Glob(BuildPath(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+	}
+
+	return names, nil
+}
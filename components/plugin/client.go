@@ -0,0 +1,245 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// closeTimeout is how long a plugin has to exit after stdin is closed
+// before it is killed.
+var closeTimeout = 5 * time.Second
+
+// handler answers requests a plugin sends back to the CLI while the
+// CLI waits for a response.
+type handler func(method string, params Params) (Result, error)
+
+//------------------------------------------
+//- A client starts a plugin executable on
+//- the first request and keeps it running
+//- until the client is closed.
+//------------------------------------------
+type client struct {
+	sync.Mutex
+
+	path    string
+	handler handler
+
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+	id  int
+}
+
+// running tracks the clients with a started plugin, so every plugin
+// can be stopped when the CLI is done.
+var running = struct {
+	sync.Mutex
+	clients map[*client]bool
+}{clients: map[*client]bool{}}
+
+// Close stops every plugin started by this process. A plugin is started
+// again by its next request.
+func Close() error {
+	running.Lock()
+	clients := []*client{}
+	for c := range running.clients {
+		clients = append(clients, c)
+	}
+	running.Unlock()
+
+	var closeErr error
+	for _, c := range clients {
+		if err := c.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+func (c *client) start() error {
+	cmd := exec.Command(c.path)
+	cmd.Stderr = os.Stderr
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin %s (%s)", filepath.Base(c.path), err)
+	}
+
+	c.cmd = cmd
+	c.in = in
+	c.out = bufio.NewReader(out)
+
+	running.Lock()
+	running.clients[c] = true
+	running.Unlock()
+
+	return nil
+}
+
+// Close closes stdin, so the plugin exits, and waits for the plugin
+// process to exit.
+func (c *client) Close() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.in == nil {
+		return nil
+	}
+
+	c.in.Close()
+
+	return c.stop(closeTimeout)
+}
+
+//------------------------------------------
+//- Wait for the plugin to exit and kill it
+//- when it does not exit in time.
+//------------------------------------------
+func (c *client) stop(timeout time.Duration) error {
+	cmd := c.cmd
+
+	c.cmd = nil
+	c.in = nil
+	c.out = nil
+
+	running.Lock()
+	delete(running.clients, c)
+	running.Unlock()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("plugin %s exited with an error (%s)", filepath.Base(c.path), err)
+		}
+
+		return nil
+	case <-time.After(timeout):
+		cmd.Process.Kill()
+		<-done
+
+		return fmt.Errorf("plugin %s was killed after failing to exit", filepath.Base(c.path))
+	}
+}
+
+func (c *client) call(method string, params Params) (Result, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.in == nil {
+		if err := c.start(); err != nil {
+			return Result{}, err
+		}
+	}
+
+	c.id++
+	id := c.id
+
+	if err := write(c.in, message{JSONRPC: protocolVersion, ID: id, Method: method, Params: &params}); err != nil {
+		return Result{}, c.failed(err)
+	}
+
+	for {
+		msg, err := read(c.out)
+		if err != nil {
+			return Result{}, c.failed(err)
+		}
+
+		//------------------------------------------
+		//- Answer requests from the plugin, like
+		//- getting credentials from a vault.
+		//------------------------------------------
+		if len(msg.Method) > 0 {
+			reply := message{JSONRPC: protocolVersion, ID: msg.ID}
+
+			if c.handler == nil {
+				reply.Error = &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("%s not supported", msg.Method)}
+			} else if result, err := c.handler(msg.Method, paramsOf(msg)); err != nil {
+				reply.Error = toError(err)
+			} else {
+				reply.Result = &result
+			}
+
+			if err := write(c.in, reply); err != nil {
+				return Result{}, c.failed(err)
+			}
+
+			continue
+		}
+
+		if msg.ID != id {
+			continue
+		}
+
+		if msg.Error != nil {
+			return Result{}, msg.Error.toError()
+		}
+
+		if msg.Result == nil {
+			return Result{}, nil
+		}
+
+		return *msg.Result, nil
+	}
+}
+
+//------------------------------------------
+//- Restart the plugin on the next request
+//- when the connection is broken.
+//------------------------------------------
+func (c *client) failed(err error) error {
+	c.in.Close()
+	c.stop(0)
+
+	return fmt.Errorf("plugin %s failed (%s)", filepath.Base(c.path), err)
+}
+
+func paramsOf(msg message) Params {
+	if msg.Params == nil {
+		return Params{}
+	}
+
+	return *msg.Params
+}
+
+func write(w io.Writer, msg message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+func read(r *bufio.Reader) (message, error) {
+	msg := message{}
+
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return msg, err
+	}
+
+	return msg, json.Unmarshal(line, &msg)
+}
@@ -0,0 +1,245 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/local"
+)
+
+const (
+	// StorePrefix is the executable name prefix of store plugins.
+	StorePrefix = "cstore-store-"
+
+	// VaultPrefix is the executable name prefix of vault plugins.
+	VaultPrefix = "cstore-vault-"
+
+	pluginsDir = "plugins"
+
+	protocolVersion = "2.0"
+)
+
+// Error codes returned by plugins to identify errors the CLI handles.
+const (
+	CodeError              = -32000
+	CodeNotFound           = -32001
+	CodeRevisionConflict   = -32002
+	CodeHistoryUnsupported = -32003
+	CodeMethodNotFound     = -32601
+)
+
+//------------------------------------------
+//- JSON-RPC messages exchanged over stdin
+//- and stdout, one message per line.
+//------------------------------------------
+type message struct {
+	JSONRPC string  `json:"jsonrpc"`
+	ID      int     `json:"id"`
+	Method  string  `json:"method,omitempty"`
+	Params  *Params `json:"params,omitempty"`
+	Result  *Result `json:"result,omitempty"`
+	Error   *Error  `json:"error,omitempty"`
+}
+
+// Params are the arguments of a request.
+type Params struct {
+	Context  string   `json:"context,omitempty"`
+	CWD      string   `json:"cwd,omitempty"`
+	File     *File    `json:"file,omitempty"`
+	Options  *Options `json:"options,omitempty"`
+	Version  string   `json:"version,omitempty"`
+	Revision string   `json:"revision,omitempty"`
+	Content  []byte   `json:"content,omitempty"`
+	Group    string   `json:"group,omitempty"`
+	Prop     string   `json:"prop,omitempty"`
+	Value    string   `json:"value,omitempty"`
+}
+
+// Result is the response to a request.
+type Result struct {
+	File         *File         `json:"file,omitempty"`
+	Content      []byte        `json:"content,omitempty"`
+	Attributes   *Attributes   `json:"attributes,omitempty"`
+	Modified     time.Time     `json:"modified,omitempty"`
+	Revision     string        `json:"revision,omitempty"`
+	Revisions    []Revision    `json:"revisions,omitempty"`
+	Value        string        `json:"value,omitempty"`
+	Description  string        `json:"description,omitempty"`
+	Capabilities *Capabilities `json:"capabilities,omitempty"`
+}
+
+// Error is returned when a request fails.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Options are the user options a plugin may need.
+type Options struct {
+	Prompt       bool   `json:"prompt,omitempty"`
+	Silent       bool   `json:"silent,omitempty"`
+	Version      string `json:"version,omitempty"`
+	StoreCommand string `json:"storeCommand,omitempty"`
+	AccessVault  string `json:"accessVault,omitempty"`
+}
+
+// File describes a catalog file. Plugins return the file to save
+// changes to the file data in the catalog.
+type File struct {
+	Path          string            `json:"path"`
+	AlternatePath string            `json:"alternatePath,omitempty"`
+	Type          string            `json:"type"`
+	Tags          []string          `json:"tags,omitempty"`
+	Versions      []string          `json:"versions,omitempty"`
+	Data          map[string]string `json:"data,omitempty"`
+}
+
+// Capabilities ...
+type Capabilities struct {
+	Versioning        bool     `json:"versioning,omitempty"`
+	History           bool     `json:"history,omitempty"`
+	ConditionalWrites bool     `json:"conditionalWrites,omitempty"`
+	ClientEncryption  bool     `json:"clientEncryption,omitempty"`
+	SourceControl     bool     `json:"sourceControl,omitempty"`
+	Binary            bool     `json:"binary,omitempty"`
+	MaxSize           int      `json:"maxSize,omitempty"`
+	FileTypes         []string `json:"fileTypes,omitempty"`
+	NoFileTypes       bool     `json:"noFileTypes,omitempty"`
+}
+
+// Attributes ...
+type Attributes struct {
+	Modified   time.Time `json:"modified,omitempty"`
+	Revision   string    `json:"revision,omitempty"`
+	Size       int       `json:"size,omitempty"`
+	Checksum   string    `json:"checksum,omitempty"`
+	KMSKeyID   string    `json:"kmsKeyId,omitempty"`
+	LastWriter string    `json:"lastWriter,omitempty"`
+}
+
+// Revision ...
+type Revision struct {
+	ID       string    `json:"id"`
+	Version  string    `json:"version,omitempty"`
+	Modified time.Time `json:"modified,omitempty"`
+	Latest   bool      `json:"latest,omitempty"`
+}
+
+//------------------------------------------
+//- Discover plugin executables in the cstore
+//- plugins directory and on the PATH.
+//------------------------------------------
+func find(prefix string) map[string]string {
+	found := map[string]string{}
+
+	dirs := append([]string{local.BuildPath(pluginsDir)}, filepath.SplitList(os.Getenv("PATH"))...)
+
+	for _, dir := range dirs {
+		entries, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, e := range entries {
+			if !strings.HasPrefix(e.Name(), prefix) || !executable(dir, e) {
+				continue
+			}
+
+			name := strings.TrimPrefix(e.Name(), prefix)
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+
+			if _, exists := found[name]; !exists && len(name) > 0 {
+				found[name] = filepath.Join(dir, e.Name())
+			}
+		}
+	}
+
+	return found
+}
+
+func executable(dir string, info os.FileInfo) bool {
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Stat(filepath.Join(dir, info.Name()))
+		if err != nil {
+			return false
+		}
+		info = target
+	}
+
+	if info.IsDir() {
+		return false
+	}
+
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(info.Name()), ".exe")
+	}
+
+	return info.Mode()&0111 != 0
+}
+
+//------------------------------------------
+//- Convert between catalog and wire types.
+//------------------------------------------
+func toFile(f catalog.File) *File {
+	return &File{
+		Path:          f.Path,
+		AlternatePath: f.AlternatePath,
+		Type:          f.Type,
+		Tags:          f.Tags,
+		Versions:      f.Versions,
+		Data:          f.Data,
+	}
+}
+
+func (f *File) toCatalog() catalog.File {
+	if f == nil {
+		return catalog.File{}
+	}
+
+	return catalog.File{
+		Path:          f.Path,
+		AlternatePath: f.AlternatePath,
+		Type:          f.Type,
+		Tags:          f.Tags,
+		Versions:      f.Versions,
+		Data:          f.Data,
+	}
+}
+
+func toError(err error) *Error {
+	switch err {
+	case contract.ErrSecretNotFound:
+		return &Error{Code: CodeNotFound, Message: err.Error()}
+	case contract.ErrRevisionConflict:
+		return &Error{Code: CodeRevisionConflict, Message: err.Error()}
+	case contract.ErrHistoryNotSupported:
+		return &Error{Code: CodeHistoryUnsupported, Message: err.Error()}
+	default:
+		return &Error{Code: CodeError, Message: err.Error()}
+	}
+}
+
+func (e Error) toError() error {
+	switch e.Code {
+	case CodeNotFound:
+		return contract.ErrSecretNotFound
+	case CodeRevisionConflict:
+		return contract.ErrRevisionConflict
+	case CodeHistoryUnsupported:
+		return contract.ErrHistoryNotSupported
+	default:
+		return &e
+	}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
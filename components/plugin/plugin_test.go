@@ -0,0 +1,285 @@
+package plugin
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// When the test binary is started through a plugin link, it serves the
+// in memory store or vault instead of running tests.
+func TestMain(m *testing.M) {
+	name := filepath.Base(os.Args[0])
+
+	switch {
+	case strings.HasPrefix(name, StorePrefix):
+		if err := ServeStore(&memStore{files: map[string][]byte{}}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	case strings.HasPrefix(name, VaultPrefix):
+		if err := ServeVault(&memVault{secrets: map[string]string{}}); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// link creates a plugin executable on the PATH.
+func link(t *testing.T, name string) func() {
+	dir, err := ioutil.TempDir("", "cstore-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(exe, filepath.Join(dir, name)); err != nil {
+		t.Fatal(err)
+	}
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	return func() {
+		os.Setenv("PATH", path)
+		os.RemoveAll(dir)
+	}
+}
+
+//---------------------------------------------------
+//- When a store plugin is on the PATH, it should be
+//- discovered and store files over the protocol.
+//---------------------------------------------------
+func TestEnsureStorePluginPushesAndPullsFiles(t *testing.T) {
+	defer link(t, StorePrefix+"memory")()
+
+	// arrange
+	s, found := Stores()["memory"]
+	if !found {
+		t.Fatalf("\nEXPECTED: %s \nACTUAL: %s", "memory store plugin", "not found")
+	}
+
+	access := &memVault{secrets: map[string]string{"ctx/plugin/TOKEN": "secret"}}
+	file := catalog.File{Path: "dev/.env", Type: "env"}
+	data := []byte("ENV=dev")
+
+	// act
+	if err := s.Pre(catalog.Catalog{Context: "ctx"}, &file, access, cfg.UserOptions{}, models.IO{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Push(&file, data, "v1"); err != nil {
+		t.Fatal(err)
+	}
+
+	pulled, attr, err := s.Pull(&file, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if string(pulled) != string(data) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", data, pulled)
+	}
+
+	if attr.Size != len(data) {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d", len(data), attr.Size)
+	}
+
+	if !s.Capabilities().Versioning {
+		t.Errorf("\nEXPECTED: %t \nACTUAL: %t", true, false)
+	}
+
+	if file.Data["TOKEN"] != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "secret", file.Data["TOKEN"])
+	}
+
+	if _, _, err := s.Pull(&catalog.File{Path: "missing"}, ""); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "error", err)
+	}
+}
+
+//---------------------------------------------------
+//- When the CLI is done, started plugins should exit
+//- and be started again by the next request.
+//---------------------------------------------------
+func TestEnsureClosedStorePluginExitsAndRestarts(t *testing.T) {
+	defer link(t, StorePrefix+"memory")()
+
+	// arrange
+	s := Stores()["memory"]
+	file := catalog.File{Path: "dev/.env", Type: "env"}
+
+	if err := s.Push(&file, []byte("ENV=dev"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := s.client.cmd
+
+	// act
+	err := Close()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.ProcessState == nil || !cmd.ProcessState.Exited() {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "plugin exited", cmd.ProcessState)
+	}
+
+	if err := s.Push(&file, []byte("ENV=dev"), ""); err != nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "plugin restarted", err)
+	}
+
+	if err := s.client.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+//---------------------------------------------------
+//- When a vault plugin is on the PATH, it should be
+//- discovered and map errors to vault errors.
+//---------------------------------------------------
+func TestEnsureVaultPluginGetsAndSetsSecrets(t *testing.T) {
+	defer link(t, VaultPrefix+"memory")()
+
+	// arrange
+	v, found := Vaults()["memory"]
+	if !found {
+		t.Fatalf("\nEXPECTED: %s \nACTUAL: %s", "memory vault plugin", "not found")
+	}
+
+	if err := v.Pre(catalog.Catalog{Context: "ctx"}, &catalog.File{}, nil, cfg.UserOptions{}, models.IO{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	if err := v.Set("ctx", "group", "KEY", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := v.Get("ctx", "group", "KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, missing := v.Get("ctx", "group", "MISSING")
+
+	// assert
+	if value != "value" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "value", value)
+	}
+
+	if missing != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, missing)
+	}
+
+	if key := v.BuildKey("ctx", "group", "KEY"); key != "ctx/group/KEY" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ctx/group/KEY", key)
+	}
+}
+
+type memStore struct {
+	files map[string][]byte
+	clog  catalog.Catalog
+}
+
+func (s memStore) Name() string        { return "memory" }
+func (s memStore) Description() string { return "in memory store" }
+
+func (s memStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{Versioning: true, Binary: true}
+}
+
+func (s *memStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+
+	token, err := access.Get(clog.Context, "plugin", "TOKEN")
+	if err != nil {
+		return err
+	}
+
+	file.AddData(map[string]string{"TOKEN": token})
+
+	return nil
+}
+
+func (s memStore) key(file *catalog.File, version string) string {
+	return s.clog.Context + "/" + version + "/" + file.Path
+}
+
+func (s memStore) Push(file *catalog.File, fileData []byte, version string) error {
+	s.files[s.key(file, version)] = fileData
+	return nil
+}
+
+func (s memStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+	b, found := s.files[s.key(file, version)]
+	if !found {
+		return nil, contract.Attributes{}, os.ErrNotExist
+	}
+
+	return b, contract.Attributes{Size: len(b)}, nil
+}
+
+func (s memStore) Purge(file *catalog.File, version string) error {
+	delete(s.files, s.key(file, version))
+	return nil
+}
+
+func (s memStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+	return time.Time{}, nil
+}
+
+func (s memStore) Revision(file *catalog.File, version string) (string, error) {
+	return "", nil
+}
+
+type memVault struct {
+	secrets map[string]string
+}
+
+func (v memVault) Name() string        { return "memory" }
+func (v memVault) Description() string { return "in memory vault" }
+
+func (v memVault) BuildKey(contextID, group, prop string) string {
+	return contextID + "/" + group + "/" + prop
+}
+
+func (v memVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	return nil
+}
+
+func (v memVault) Get(contextID, group, prop string) (string, error) {
+	value, found := v.secrets[v.BuildKey(contextID, group, prop)]
+	if !found {
+		return "", contract.ErrSecretNotFound
+	}
+
+	return value, nil
+}
+
+func (v memVault) Set(contextID, group, prop, value string) error {
+	v.secrets[v.BuildKey(contextID, group, prop)] = value
+	return nil
+}
+
+func (v memVault) Delete(contextID, group, prop string) error {
+	delete(v.secrets, v.BuildKey(contextID, group, prop))
+	return nil
+}
@@ -0,0 +1,291 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// ServeStore answers CLI requests on stdin and stdout using the store
+// until stdin is closed. It allows store plugins to be written in Go
+// using the same interface as the built in stores.
+func ServeStore(store contract.IStore) error {
+	return newServer(os.Stdin, os.Stdout).serve(func(srv *server, msg message) (Result, error) {
+		return srv.store(store, msg.Method, paramsOf(msg))
+	})
+}
+
+// ServeVault answers CLI requests on stdin and stdout using the vault
+// until stdin is closed.
+func ServeVault(vault contract.IVault) error {
+	return newServer(os.Stdin, os.Stdout).serve(func(srv *server, msg message) (Result, error) {
+		return srv.vault(vault, msg.Method, paramsOf(msg))
+	})
+}
+
+type server struct {
+	in  *bufio.Reader
+	out io.Writer
+	id  int
+}
+
+func newServer(in io.Reader, out io.Writer) *server {
+	return &server{
+		in:  bufio.NewReader(in),
+		out: out,
+	}
+}
+
+func (srv *server) serve(dispatch func(*server, message) (Result, error)) error {
+	for {
+		msg, err := read(srv.in)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		reply := message{JSONRPC: protocolVersion, ID: msg.ID}
+
+		if result, err := dispatch(srv, msg); err != nil {
+			reply.Error = toError(err)
+		} else {
+			reply.Result = &result
+		}
+
+		if err := write(srv.out, reply); err != nil {
+			return err
+		}
+	}
+}
+
+func (srv *server) store(s contract.IStore, method string, p Params) (Result, error) {
+	file := p.File.toCatalog()
+
+	result := Result{}
+	var err error
+
+	switch method {
+	case "Store.Describe":
+		c := s.Capabilities()
+		result.Description = s.Description()
+		result.Capabilities = &Capabilities{
+			Versioning:        c.Versioning,
+			History:           c.History,
+			ConditionalWrites: c.ConditionalWrites,
+			ClientEncryption:  c.ClientEncryption,
+			SourceControl:     c.SourceControl,
+			Binary:            c.Binary,
+			MaxSize:           c.MaxSize,
+			FileTypes:         c.FileTypes,
+			NoFileTypes:       c.NoFileTypes,
+		}
+		return result, nil
+	case "Store.Pre":
+		err = s.Pre(srv.catalog(p), &file, srv.access(p), p.Options.toUserOptions(), srv.io())
+	case "Store.Push":
+		err = s.Push(&file, p.Content, p.Version)
+	case "Store.PushIf":
+		if cs, ok := s.(contract.IConditionalStore); ok {
+			result.Revision, err = cs.PushIf(&file, p.Content, p.Version, p.Revision)
+		} else {
+			err = fmt.Errorf("%s not supported", method)
+		}
+	case "Store.Pull":
+		var attr contract.Attributes
+		result.Content, attr, err = s.Pull(&file, p.Version)
+		result.Attributes = &Attributes{
+			Modified:   attr.Modified,
+			Revision:   attr.Revision,
+			Size:       attr.Size,
+			Checksum:   attr.Checksum,
+			KMSKeyID:   attr.KMSKeyID,
+			LastWriter: attr.LastWriter,
+		}
+	case "Store.Purge":
+		err = s.Purge(&file, p.Version)
+	case "Store.Changed":
+		result.Modified, err = s.Changed(&file, p.Content, p.Version)
+	case "Store.Revision":
+		result.Revision, err = s.Revision(&file, p.Version)
+	case "Store.History", "Store.Rollback":
+		h, ok := s.(contract.IHistory)
+		if !ok {
+			return result, contract.ErrHistoryNotSupported
+		}
+
+		if method == "Store.Rollback" {
+			err = h.Rollback(&file, p.Revision)
+			break
+		}
+
+		var revisions []contract.Revision
+		revisions, err = h.History(&file)
+		for _, r := range revisions {
+			result.Revisions = append(result.Revisions, Revision{
+				ID:       r.ID,
+				Version:  r.Version,
+				Modified: r.Modified,
+				Latest:   r.Latest,
+			})
+		}
+	default:
+		return result, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("%s not supported", method)}
+	}
+
+	result.File = toFile(file)
+
+	return result, err
+}
+
+func (srv *server) vault(v contract.IVault, method string, p Params) (Result, error) {
+	result := Result{}
+	var err error
+
+	switch method {
+	case "Vault.Describe":
+		result.Description = v.Description()
+	case "Vault.Pre":
+		file := p.File.toCatalog()
+		err = v.Pre(srv.catalog(p), &file, srv.access(p), p.Options.toUserOptions(), srv.io())
+		result.File = toFile(file)
+	case "Vault.BuildKey":
+		result.Value = v.BuildKey(p.Context, p.Group, p.Prop)
+	case "Vault.Get":
+		result.Value, err = v.Get(p.Context, p.Group, p.Prop)
+	case "Vault.Set":
+		err = v.Set(p.Context, p.Group, p.Prop, p.Value)
+	case "Vault.Delete":
+		err = v.Delete(p.Context, p.Group, p.Prop)
+	default:
+		return result, &Error{Code: CodeMethodNotFound, Message: fmt.Sprintf("%s not supported", method)}
+	}
+
+	return result, err
+}
+
+//------------------------------------------
+//- Send a request to the CLI while handling
+//- a request from the CLI.
+//------------------------------------------
+func (srv *server) call(method string, params Params) (Result, error) {
+	srv.id++
+
+	if err := write(srv.out, message{JSONRPC: protocolVersion, ID: srv.id, Method: method, Params: &params}); err != nil {
+		return Result{}, err
+	}
+
+	msg, err := read(srv.in)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if msg.Error != nil {
+		return Result{}, msg.Error.toError()
+	}
+
+	if msg.Result == nil {
+		return Result{}, nil
+	}
+
+	return *msg.Result, nil
+}
+
+func (srv *server) catalog(p Params) catalog.Catalog {
+	return catalog.Catalog{
+		Context: p.Context,
+		CWD:     p.CWD,
+		Files:   map[string]catalog.File{},
+	}
+}
+
+func (srv *server) access(p Params) contract.IVault {
+	name := ""
+	if p.Options != nil {
+		name = p.Options.AccessVault
+	}
+
+	return hostVault{name: name, srv: srv}
+}
+
+//------------------------------------------
+//- Plugins cannot prompt users, because
+//- stdin is used for requests.
+//------------------------------------------
+func (srv *server) io() models.IO {
+	return models.IO{
+		UserOutput: os.Stderr,
+		UserInput:  bufio.NewReader(&bytes.Buffer{}),
+		Export:     os.Stderr,
+	}
+}
+
+func (o *Options) toUserOptions() cfg.UserOptions {
+	if o == nil {
+		return cfg.UserOptions{Silent: true}
+	}
+
+	return cfg.UserOptions{
+		Prompt:       o.Prompt,
+		Silent:       o.Silent,
+		Version:      o.Version,
+		StoreCommand: o.StoreCommand,
+		AccessVault:  o.AccessVault,
+	}
+}
+
+// hostVault is the access vault of the CLI made available to plugins.
+type hostVault struct {
+	name string
+	srv  *server
+}
+
+func (v hostVault) Name() string {
+	return v.name
+}
+
+func (v hostVault) Description() string {
+	return "access vault of the cstore CLI"
+}
+
+func (v hostVault) BuildKey(contextID, group, prop string) string {
+	return fmt.Sprintf("%s/%s/%s", contextID, group, prop)
+}
+
+func (v hostVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	return nil
+}
+
+func (v hostVault) Get(contextID, group, prop string) (string, error) {
+	result, err := v.srv.call("Access.Get", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+	})
+
+	return result.Value, err
+}
+
+func (v hostVault) Set(contextID, group, prop, value string) error {
+	_, err := v.srv.call("Access.Set", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+		Value:   value,
+	})
+
+	return err
+}
+
+func (v hostVault) Delete(contextID, group, prop string) error {
+	return fmt.Errorf("Access.Delete not supported")
+}
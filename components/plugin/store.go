@@ -0,0 +1,259 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// Store is a store implemented by a plugin executable named
+// cstore-store-{name}.
+type Store struct {
+	name   string
+	client *client
+
+	clog catalog.Catalog
+
+	described    bool
+	description  string
+	capabilities contract.Capabilities
+}
+
+// Stores returns the store plugins found in the cstore plugins
+// directory and on the PATH.
+func Stores() map[string]*Store {
+	stores := map[string]*Store{}
+
+	for name, path := range find(StorePrefix) {
+		stores[name] = &Store{
+			name:   name,
+			client: &client{path: path},
+		}
+	}
+
+	return stores
+}
+
+// Name ...
+func (s Store) Name() string {
+	return s.name
+}
+
+// Description ...
+func (s *Store) Description() string {
+	if err := s.describe(); err != nil {
+		return err.Error()
+	}
+
+	return s.description
+}
+
+// Capabilities ...
+func (s *Store) Capabilities() contract.Capabilities {
+	if err := s.describe(); err != nil {
+		return contract.Capabilities{}
+	}
+
+	return s.capabilities
+}
+
+// Pre ...
+func (s *Store) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+	s.client.handler = accessHandler(access)
+
+	if err := s.describe(); err != nil {
+		return err
+	}
+
+	_, err := s.call("Store.Pre", file, Params{
+		Options: toOptions(uo, access),
+	})
+
+	return err
+}
+
+// Push ...
+func (s Store) Push(file *catalog.File, fileData []byte, version string) error {
+	_, err := s.call("Store.Push", file, Params{
+		Content: fileData,
+		Version: version,
+	})
+
+	return err
+}
+
+// PushIf ...
+func (s Store) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	if !s.capabilities.ConditionalWrites {
+		if len(revision) > 0 {
+			current, err := s.Revision(file, version)
+			if err != nil {
+				return "", err
+			}
+
+			if current != revision {
+				return "", contract.ErrRevisionConflict
+			}
+		}
+
+		if err := s.Push(file, fileData, version); err != nil {
+			return "", err
+		}
+
+		return s.Revision(file, version)
+	}
+
+	result, err := s.call("Store.PushIf", file, Params{
+		Content:  fileData,
+		Version:  version,
+		Revision: revision,
+	})
+
+	return result.Revision, err
+}
+
+// Pull ...
+func (s Store) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+	result, err := s.call("Store.Pull", file, Params{
+		Version: version,
+	})
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	attr := contract.Attributes{}
+	if a := result.Attributes; a != nil {
+		attr = contract.Attributes{
+			Modified:   a.Modified,
+			Revision:   a.Revision,
+			Size:       a.Size,
+			Checksum:   a.Checksum,
+			KMSKeyID:   a.KMSKeyID,
+			LastWriter: a.LastWriter,
+		}
+	}
+
+	return result.Content, attr, nil
+}
+
+// Purge ...
+func (s Store) Purge(file *catalog.File, version string) error {
+	_, err := s.call("Store.Purge", file, Params{
+		Version: version,
+	})
+
+	return err
+}
+
+// Changed ...
+func (s Store) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+	result, err := s.call("Store.Changed", file, Params{
+		Content: fileData,
+		Version: version,
+	})
+
+	return result.Modified, err
+}
+
+// Revision ...
+func (s Store) Revision(file *catalog.File, version string) (string, error) {
+	result, err := s.call("Store.Revision", file, Params{
+		Version: version,
+	})
+
+	return result.Revision, err
+}
+
+// History ...
+func (s Store) History(file *catalog.File) ([]contract.Revision, error) {
+	if !s.capabilities.History {
+		return nil, contract.ErrHistoryNotSupported
+	}
+
+	result, err := s.call("Store.History", file, Params{})
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := []contract.Revision{}
+	for _, r := range result.Revisions {
+		revisions = append(revisions, contract.Revision{
+			ID:       r.ID,
+			Version:  r.Version,
+			Modified: r.Modified,
+			Latest:   r.Latest,
+		})
+	}
+
+	return revisions, nil
+}
+
+// Rollback ...
+func (s Store) Rollback(file *catalog.File, revision string) error {
+	if !s.capabilities.History {
+		return contract.ErrHistoryNotSupported
+	}
+
+	_, err := s.call("Store.Rollback", file, Params{
+		Revision: revision,
+	})
+
+	return err
+}
+
+//------------------------------------------
+//- Send the file with each request and save
+//- the file data the plugin returns.
+//------------------------------------------
+func (s Store) call(method string, file *catalog.File, params Params) (Result, error) {
+	params.Context = s.clog.Context
+	params.CWD = s.clog.CWD
+	params.File = toFile(*file)
+
+	result, err := s.client.call(method, params)
+	if err != nil {
+		return result, err
+	}
+
+	if result.File != nil {
+		file.Data = result.File.Data
+	}
+
+	return result, nil
+}
+
+func (s *Store) describe() error {
+	if s.described {
+		return nil
+	}
+
+	result, err := s.client.call("Store.Describe", Params{})
+	if err != nil {
+		return err
+	}
+
+	s.description = result.Description
+
+	if c := result.Capabilities; c != nil {
+		s.capabilities = contract.Capabilities{
+			Versioning:        c.Versioning,
+			History:           c.History,
+			ConditionalWrites: c.ConditionalWrites,
+			ClientEncryption:  c.ClientEncryption,
+			SourceControl:     c.SourceControl,
+			Binary:            c.Binary,
+			MaxSize:           c.MaxSize,
+			FileTypes:         c.FileTypes,
+			NoFileTypes:       c.NoFileTypes,
+		}
+	}
+
+	s.described = true
+
+	return nil
+}
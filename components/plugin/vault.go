@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// Vault is a vault implemented by a plugin executable named
+// cstore-vault-{name}.
+type Vault struct {
+	name   string
+	client *client
+
+	described   bool
+	description string
+}
+
+// Vaults returns the vault plugins found in the cstore plugins
+// directory and on the PATH.
+func Vaults() map[string]*Vault {
+	vaults := map[string]*Vault{}
+
+	for name, path := range find(VaultPrefix) {
+		vaults[name] = &Vault{
+			name:   name,
+			client: &client{path: path},
+		}
+	}
+
+	return vaults
+}
+
+// Name ...
+func (v Vault) Name() string {
+	return v.name
+}
+
+// Description ...
+func (v *Vault) Description() string {
+	if !v.described {
+		result, err := v.client.call("Vault.Describe", Params{})
+		if err != nil {
+			return err.Error()
+		}
+
+		v.description = result.Description
+		v.described = true
+	}
+
+	return v.description
+}
+
+// BuildKey ...
+func (v Vault) BuildKey(contextID, group, prop string) string {
+	result, err := v.client.call("Vault.BuildKey", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+	})
+	if err != nil {
+		return ""
+	}
+
+	return result.Value
+}
+
+// Pre ...
+func (v *Vault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	v.client.handler = accessHandler(access)
+
+	result, err := v.client.call("Vault.Pre", Params{
+		Context: clog.Context,
+		CWD:     clog.CWD,
+		File:    toFile(*fileEntry),
+		Options: toOptions(uo, access),
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.File != nil {
+		fileEntry.Data = result.File.Data
+	}
+
+	return nil
+}
+
+// Get ...
+func (v Vault) Get(contextID, group, prop string) (string, error) {
+	result, err := v.client.call("Vault.Get", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+	})
+
+	return result.Value, err
+}
+
+// Set ...
+func (v Vault) Set(contextID, group, prop, value string) error {
+	_, err := v.client.call("Vault.Set", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+		Value:   value,
+	})
+
+	return err
+}
+
+// Delete ...
+func (v Vault) Delete(contextID, group, prop string) error {
+	_, err := v.client.call("Vault.Delete", Params{
+		Context: contextID,
+		Group:   group,
+		Prop:    prop,
+	})
+
+	return err
+}
+
+//------------------------------------------
+//- Plugins get credentials from the access
+//- vault through requests to the CLI.
+//------------------------------------------
+func accessHandler(access contract.IVault) handler {
+	return func(method string, params Params) (Result, error) {
+		if access == nil {
+			return Result{}, fmt.Errorf("%s not supported without an access vault", method)
+		}
+
+		switch method {
+		case "Access.Get":
+			value, err := access.Get(params.Context, params.Group, params.Prop)
+			return Result{Value: value}, err
+		case "Access.Set":
+			return Result{}, access.Set(params.Context, params.Group, params.Prop, params.Value)
+		default:
+			return Result{}, fmt.Errorf("%s not supported", method)
+		}
+	}
+}
+
+func toOptions(uo cfg.UserOptions, access contract.IVault) *Options {
+	o := &Options{
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		Version:      uo.Version,
+		StoreCommand: uo.StoreCommand,
+	}
+
+	if access != nil {
+		o.AccessVault = access.Name()
+	}
+
+	return o
+}
@@ -19,6 +19,24 @@ type Components struct {
 
 // InitComponents ...
 func InitComponents(fileEntry *catalog.File, clog catalog.Catalog, uo cfg.UserOptions, io models.IO) (Components, error) {
+	remote, err := InitVaults(fileEntry, clog, uo, io)
+	if err != nil {
+		return remote, err
+	}
+
+	st, err := store.Select(fileEntry, clog, remote.Access, uo, io)
+	if err != nil {
+		return remote, err
+	}
+	remote.Store = st
+	fileEntry.Store = st.Name()
+
+	return remote, nil
+}
+
+// InitVaults gets the access and secrets vaults ready without the store,
+// so files can be read from the cache when the store is unavailable.
+func InitVaults(fileEntry *catalog.File, clog catalog.Catalog, uo cfg.UserOptions, io models.IO) (Components, error) {
 	remote := Components{}
 
 	v, err := vault.GetBy(fileEntry.Vaults.Access, cfg.DefaultAccessVault, clog, fileEntry, nil, uo, io)
@@ -35,12 +53,5 @@ func InitComponents(fileEntry *catalog.File, clog catalog.Catalog, uo cfg.UserOp
 	remote.Secrets = v
 	fileEntry.Vaults.Secrets = v.Name()
 
-	st, err := store.Select(fileEntry, clog, remote.Access, uo, io)
-	if err != nil {
-		return remote, err
-	}
-	remote.Store = st
-	fileEntry.Store = st.Name()
-
 	return remote, nil
 }
@@ -11,12 +11,37 @@ const (
 
 	awsBucketSetting = "AWS_S3_BUCKET"
 
+	awsS3EndpointSetting           = "AWS_S3_ENDPOINT"
+	awsS3ForcePathStyleSetting     = "AWS_S3_FORCE_PATH_STYLE"
+	awsS3InsecureSkipVerifySetting = "AWS_S3_INSECURE_SKIP_VERIFY"
+	awsS3CABundleSetting           = "AWS_S3_CA_BUNDLE"
+
 	awsStoreKMSKeyID = "AWS_STORE_KMS_KEY_ID"
 
+	awsS3ObjectVersioning = "AWS_S3_OBJECT_VERSIONING"
+	awsS3VersionIDPrefix  = "AWS_S3_VERSION_ID_"
+	awsS3VersionTag       = "cstore-version"
+	awsS3WriterMetadata   = "cstore-writer"
+
 	awsDefaultRegion  = "us-east-1"
 	awsDefaultProfile = "default"
 
 	defaultSMKMSKey = "aws/secretsmanager"
+
+	secretMaxSize = 65536
+
+	paramStandardMaxSize = 4096
+	paramAdvancedMaxSize = 8192
+	paramChunkDir        = ".chunks"
+	paramTypesName       = ".types"
+	paramMaxLevels       = 15
+
+	awsParameterWorkersSetting = "AWS_PARAMETER_WORKERS"
+	awsParameterRateSetting    = "AWS_PARAMETER_RATE"
+
+	defaultParamWorkers = 5
+	defaultParamRate    = 10
+	paramProgressMin    = 20
 )
 
 type kmsKeyID struct {
@@ -30,4 +55,8 @@ type secret struct {
 
 	keyID        string
 	lastModified time.Time
+
+	versionID string
+
+	tags map[string]string
 }
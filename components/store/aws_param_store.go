@@ -2,24 +2,32 @@ package store
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ssm"
 	"github.com/subosito/gotenv"
+	"github.com/turnerlabs/cstore/v4/components/awssession"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/convert"
+	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/prompt"
 	"github.com/turnerlabs/cstore/v4/components/setting"
-	"github.com/turnerlabs/cstore/v4/components/vault"
 )
 
 const (
@@ -45,23 +53,11 @@ func (s AWSParameterStore) Name() string {
 	return "aws-parameter"
 }
 
-// SupportsFeature ...
-func (s AWSParameterStore) SupportsFeature(feature string) bool {
-	switch feature {
-	case VersionFeature:
-		return true
-	default:
-		return false
-	}
-}
-
-// SupportsFileType ...
-func (s AWSParameterStore) SupportsFileType(fileType string) bool {
-	switch fileType {
-	case EnvFeature:
-		return true
-	default:
-		return false
+// Capabilities ...
+func (s AWSParameterStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning: true,
+		FileTypes:  []string{EnvFeature, JSONFeature},
 	}
 }
 
@@ -79,80 +75,10 @@ func (s *AWSParameterStore) Pre(clog catalog.Catalog, file *catalog.File, access
 	s.io = io
 
 	//------------------------------------------
-	//- Get AWS Region
-	//------------------------------------------
-	region, err := setting.Setting{
-		Description:  "Export as an environment variable to silence this prompt.",
-		Group:        clog.Context,
-		Prop:         awsRegion,
-		Prompt:       uo.Prompt,
-		Silent:       uo.Silent,
-		AutoSave:     true,
-		PromptOnce:   true,
-		DefaultValue: awsDefaultRegion,
-		Vault:        vault.EnvVault{},
-	}.Get(clog.Context, io)
-
-	//------------------------------------------
-	//- Get AWS Credentials from Environment
+	//- Get AWS Session
 	//------------------------------------------
-	if _, ok := access.(vault.EnvVault); ok {
-		s.Session, err = session.NewSession(&aws.Config{
-			Region: aws.String(region),
-		})
-
-		return err
-	}
-
-	//------------------------------------------
-	//- Get AWS Credentials from Vault
-	//------------------------------------------
-	id, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsAccessKeyID,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	secret, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSecretAccessKey,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	token, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSessionToken,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	s.Session, err = session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(id, secret, token),
-	})
+	sess, err := awssession.Get(clog, file, access, uo, io)
+	s.Session = sess
 
 	return err
 }
@@ -160,14 +86,15 @@ func (s *AWSParameterStore) Pre(clog catalog.Catalog, file *catalog.File, access
 // Push ...
 func (s AWSParameterStore) Push(file *catalog.File, fileData []byte, version string) error {
 
-	if !file.SupportsConfig() {
-		return fmt.Errorf("store does not support file type: %s", file.Type)
-	}
-
 	if len(fileData) == 0 {
 		return errors.New("empty file")
 	}
 
+	newParams, err := configParams(file, fileData)
+	if err != nil {
+		return err
+	}
+
 	input := ssm.PutParameterInput{
 		Overwrite: aws.Bool(true),
 		Type:      aws.String(ssm.ParameterTypeSecureString),
@@ -193,29 +120,43 @@ func (s AWSParameterStore) Push(file *catalog.File, fileData []byte, version str
 		input.KeyId = &value
 	}
 
+	tags, err := resourceTags(s.clog, file, s.uo, s.io)
+	if err != nil {
+		return err
+	}
+
 	//------------------------------------------
 	//- Push configuration
 	//------------------------------------------
-	newParams := gotenv.Parse(bytes.NewReader(fileData))
-	if len(newParams) == 0 {
-		return errors.New("failed to parse environment variables")
-	}
-
 	svc := ssm.New(s.Session)
 
-	storedParams, err := getStoredParamsWithMetaData(s.clog.Context, file.ActualPath(), version, svc)
+	t, err := s.throttle()
+	if err != nil {
+		return err
+	}
+
+	storedParams, err := getStoredParamsWithMetaData(s.clog.Context, file.ActualPath(), version, svc, t)
 	if err != nil {
 		return err
 	}
 
-	for name, value := range newParams {
-		remoteKey := buildRemoteKey(s.clog.Context, file.ActualPath(), name, version)
+	desiredParams := buildParams(s.clog.Context, file.ActualPath(), version, newParams, storedParams)
 
-		newParam := param{
-			name:  remoteKey,
-			value: value,
-			pType: *input.Type,
-		}
+	if err := validateParamNames(desiredParams); err != nil {
+		return err
+	}
+
+	//------------------------------------------
+	//- Chunks are stored before the manifests
+	//- referencing them.
+	//------------------------------------------
+	chunkPuts := []throttledRequest{}
+	puts := []throttledRequest{}
+
+	untagged := &tagFailures{}
+
+	for _, newParam := range desiredParams {
+		newParam.pType = *input.Type
 
 		if input.KeyId == nil {
 			newParam.keyID = defaultPSKMSKey
@@ -227,36 +168,101 @@ func (s AWSParameterStore) Push(file *catalog.File, fileData []byte, version str
 			continue
 		}
 
-		v := formatValue(newParam.value)
+		in := input
+		in.Name = aws.String(newParam.name)
+		in.Value = aws.String(formatValue(newParam.value))
+		in.Tier = aws.String(newParam.tier)
+
+		//------------------------------------------
+		//- Tags can only be set when a param is
+		//- created without overwriting.
+		//------------------------------------------
+		if !isParamIn(newParam.name, storedParams) {
+			in.Overwrite = aws.Bool(false)
+			in.Tags = ssmTags(tags)
+		}
 
-		input.Name = &newParam.name
-		input.Value = &v
+		r := throttledRequest{
+			name: newParam.name,
+			send: func() error {
+				_, err := svc.PutParameter(&in)
+				if !tagsDenied(err) || len(in.Tags) == 0 {
+					return err
+				}
 
-		_, err := svc.PutParameter(&input)
-		if err != nil {
-			fmt.Fprintf(s.io.UserOutput, "parameter: %s", remoteKey)
-			return err
+				tagErr := err
+
+				retry := in
+				retry.Tags = nil
+
+				if _, err := svc.PutParameter(&retry); err != nil {
+					return err
+				}
+
+				untagged.add(*in.Name, tagErr)
+
+				return nil
+			},
+		}
+
+		if isParamChunk(newParam.name) {
+			chunkPuts = append(chunkPuts, r)
+		} else {
+			puts = append(puts, r)
 		}
 	}
 
+	if err := s.send(t, "stored", chunkPuts); err != nil {
+		return err
+	}
+
+	if err := s.send(t, "stored", puts); err != nil {
+		return err
+	}
+
 	//------------------------------------------
-	//- Delete removed params
+	//- Tags of existing params are only checked
+	//- when the tags changed or an earlier push
+	//- could not tag every param.
 	//------------------------------------------
-	for _, remoteParam := range storedParams {
+	reconciled, checksum := paramTags(tags)
 
-		param := strings.Replace(remoteParam.name, buildRemotePath(s.clog.Context, file.ActualPath(), version)+"/", "", 1)
+	if file.Data[awsParamTagsSetting] != checksum {
+		tagged := []throttledRequest{}
 
-		if !isParamIn(param, newParams) {
-			if _, err := svc.DeleteParameter(&ssm.DeleteParameterInput{
-				Name: aws.String(remoteParam.name),
-			}); err != nil {
-				fmt.Fprintf(s.io.UserOutput, "parameter: %s", remoteParam.name)
-				return err
+		for _, newParam := range desiredParams {
+			if isParamIn(newParam.name, storedParams) {
+				tagged = append(tagged, reconcileParamTagsRequest(svc, newParam.name, reconciled, untagged))
 			}
 		}
+
+		if err := s.send(t, "tagged", tagged); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	if untagged.empty() {
+		file.AddData(map[string]string{
+			awsParamTagsSetting: checksum,
+		})
+	} else {
+		delete(file.Data, awsParamTagsSetting)
+	}
+
+	untagged.warn(s.io)
+
+	//------------------------------------------
+	//- Delete removed params
+	//------------------------------------------
+	deletes := []throttledRequest{}
+
+	for _, remoteParam := range storedParams {
+		if !isParamIn(remoteParam.name, desiredParams) {
+			deletes = append(deletes, deleteParamRequest(svc, remoteParam.name))
+		}
+	}
+
+	return s.send(t, "deleted", deletes)
 }
 
 // Pull ...
@@ -273,20 +279,94 @@ func (s AWSParameterStore) Pull(file *catalog.File, version string) ([]byte, con
 		return []byte{}, contract.Attributes{}, errors.New("parameters not found, verify AWS account and credentials")
 	}
 
+	joinedParams, err := joinParamChunks(storedParams)
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
 	var buffer bytes.Buffer
 
-	for key, value := range toMap(storedParams) {
-		name := key[strings.LastIndex(key, "/")+1 : len(key)]
-		v := value
+	switch file.Type {
+	case JSONFeature:
+		b, err := s.buildJSON(file, version, joinedParams)
+		if err != nil {
+			return []byte{}, contract.Attributes{}, err
+		}
 
-		if s.uo.StoreCommand == cmdRefFormat {
-			buffer.WriteString(fmt.Sprintf("%s=%s\n", name, buildRemoteKey(s.clog.Context, file.ActualPath(), name, version)))
-		} else {
-			buffer.WriteString(fmt.Sprintf("%s=%s\n", name, v))
+		buffer.Write(b)
+	default:
+		for key, value := range toMap(joinedParams) {
+			name := key[strings.LastIndex(key, "/")+1 : len(key)]
+			v := value
+
+			if s.uo.StoreCommand == cmdRefFormat {
+				buffer.WriteString(fmt.Sprintf("%s=%s\n", name, buildRemoteKey(s.clog.Context, file.ActualPath(), name, version)))
+			} else {
+				buffer.WriteString(fmt.Sprintf("%s=%s\n", name, v))
+			}
+		}
+	}
+
+	attr := s.describe(svc, storedParams)
+
+	pulled := attributes(buffer.Bytes())
+	attr.Size = pulled.Size
+	attr.Checksum = pulled.Checksum
+
+	return buffer.Bytes(), attr, nil
+}
+
+// Stat ...
+func (s AWSParameterStore) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+
+	svc := ssm.New(s.Session)
+
+	storedParams, err := getStoredParams(s.clog.Context, file.ActualPath(), version, svc)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	if len(storedParams) == 0 {
+		return contract.Attributes{}, errors.New("parameters not found, verify AWS account and credentials")
+	}
+
+	return s.describe(svc, storedParams), nil
+}
+
+//------------------------------------------
+//- The most recently changed parameter
+//- identifies the key and last writer.
+//- Both are best effort, since reading the
+//- history needs its own permission.
+//------------------------------------------
+func (s AWSParameterStore) describe(svc *ssm.SSM, storedParams []param) contract.Attributes {
+	attr := contract.Attributes{
+		Modified: lastModified(storedParams),
+		Revision: paramRevision(storedParams),
+	}
+
+	for _, p := range storedParams {
+		if !p.lastModified.Equal(attr.Modified) {
+			continue
+		}
+
+		history, err := getParamHistory(svc, aws.String(p.name), "", []*ssm.ParameterHistory{})
+		if err != nil {
+			display.Warn(fmt.Errorf("KMS key and last writer of %s are unavailable (%s)", p.name, err), s.io.UserOutput)
+			break
 		}
+
+		for _, ph := range history {
+			if aws.Int64Value(ph.Version) == p.version {
+				attr.KMSKeyID = strings.Replace(aws.StringValue(ph.KeyId), "alias/", "", 1)
+				attr.LastWriter = aws.StringValue(ph.LastModifiedUser)
+			}
+		}
+
+		break
 	}
 
-	return buffer.Bytes(), contract.Attributes{}, nil
+	return attr
 }
 
 // Purge ...
@@ -299,31 +379,41 @@ func (s AWSParameterStore) Purge(file *catalog.File, version string) error {
 		return err
 	}
 
-	msg := ""
-	for _, p := range storedParams {
-		msg = fmt.Sprintf("%s  - %s\n", msg, p.name)
+	//------------------------------------------
+	//- Silent purges, like a failed migration
+	//- removing its copies, are not confirmed.
+	//------------------------------------------
+	if !s.uo.Silent {
+		msg := ""
+		for _, p := range storedParams {
+			msg = fmt.Sprintf("%s  - %s\n", msg, p.name)
+		}
+		msg = fmt.Sprintf("%s \n  Delete parameters?", msg)
+
+		if !prompt.Confirm(msg, prompt.Danger, s.io) {
+			return errors.New("user aborted")
+		}
 	}
-	msg = fmt.Sprintf("%s \n  Delete parameters?", msg)
 
-	if !prompt.Confirm(msg, prompt.Danger, s.io) {
-		return errors.New("user aborted")
+	t, err := s.throttle()
+	if err != nil {
+		return err
 	}
 
+	deletes := []throttledRequest{}
 	for _, p := range storedParams {
-		if _, err := svc.DeleteParameter(&ssm.DeleteParameterInput{
-			Name: aws.String(p.name),
-		}); err != nil {
-			fmt.Fprintf(s.io.UserOutput, "parameter: %s", p.name)
-			return err
-		}
+		deletes = append(deletes, deleteParamRequest(svc, p.name))
 	}
 
-	return nil
+	return s.send(t, "deleted", deletes)
 }
 
 // Changed ...
 func (s AWSParameterStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
-	config := gotenv.Parse(bytes.NewReader(fileData))
+	config, err := configParams(file, fileData)
+	if err != nil {
+		return time.Time{}, err
+	}
 
 	svc := ssm.New(s.Session)
 
@@ -332,6 +422,11 @@ func (s AWSParameterStore) Changed(file *catalog.File, fileData []byte, version
 		return time.Time{}, err
 	}
 
+	storedParams, err = joinParamChunks(storedParams)
+	if err != nil {
+		return time.Time{}, err
+	}
+
 	changedParams := []param{}
 	for _, p := range storedParams {
 
@@ -347,6 +442,245 @@ func (s AWSParameterStore) Changed(file *catalog.File, fileData []byte, version
 	return lastModified(changedParams), nil
 }
 
+// Revision ...
+func (s AWSParameterStore) Revision(file *catalog.File, version string) (string, error) {
+
+	storedParams, err := getStoredParams(s.clog.Context, file.ActualPath(), version, ssm.New(s.Session))
+	if err != nil {
+		return "", err
+	}
+
+	return paramRevision(storedParams), nil
+}
+
+//------------------------------------------
+//- JSON files are flattened into a param
+//- for each value named by the JSON path.
+//- The types needed to rebuild the file are
+//- saved in an additional param.
+//------------------------------------------
+func configParams(file *catalog.File, fileData []byte) (gotenv.Env, error) {
+	switch file.Type {
+	case EnvFeature:
+		params := gotenv.Parse(bytes.NewReader(fileData))
+		if len(params) == 0 {
+			return nil, errors.New("failed to parse environment variables")
+		}
+
+		return params, nil
+	case JSONFeature:
+		values, types, err := convert.FlattenJSON(fileData)
+		if err != nil {
+			return nil, err
+		}
+
+		params := gotenv.Env{}
+
+		for path, value := range values {
+			params[path] = value
+		}
+
+		for path := range params {
+			for _, key := range strings.Split(path, convert.PathSeparator) {
+				if key == paramChunkDir || key == paramTypesName {
+					return nil, fmt.Errorf("JSON key %s is reserved", key)
+				}
+			}
+		}
+
+		b, err := json.Marshal(types)
+		if err != nil {
+			return nil, err
+		}
+
+		params[paramTypesName] = string(b)
+
+		return params, nil
+	default:
+		return nil, fmt.Errorf("store does not support file type: %s", file.Type)
+	}
+}
+
+func (s AWSParameterStore) buildJSON(file *catalog.File, version string, params []param) ([]byte, error) {
+	remotePath := buildRemotePath(s.clog.Context, file.ActualPath(), version)
+
+	values := map[string]string{}
+	types := map[string]string{}
+
+	for _, p := range params {
+		path := strings.TrimPrefix(p.name, remotePath+"/")
+
+		if path == paramTypesName {
+			if err := json.Unmarshal([]byte(p.value), &types); err != nil {
+				return nil, fmt.Errorf("parameter: %s (%s)", p.name, err)
+			}
+			continue
+		}
+
+		values[path] = p.value
+	}
+
+	//------------------------------------------
+	//- Refs replace each value with the name
+	//- of the param, so only containers keep
+	//- their types.
+	//------------------------------------------
+	if s.uo.StoreCommand == cmdRefFormat {
+		for path := range values {
+			values[path] = buildRemoteKey(s.clog.Context, file.ActualPath(), path, version)
+		}
+
+		for path, t := range types {
+			if t != convert.ObjectType && t != convert.ArrayType {
+				delete(types, path)
+			}
+		}
+	}
+
+	return convert.UnflattenJSON(values, types)
+}
+
+//------------------------------------------
+//- Requests are sent in parallel at a rate
+//- below the Parameter Store limits, so
+//- large files are not throttled.
+//------------------------------------------
+func (s AWSParameterStore) throttle() (throttle, error) {
+	workers := defaultParamWorkers
+	rate := float64(defaultParamRate)
+
+	if value := os.Getenv(awsParameterWorkersSetting); len(value) > 0 {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return throttle{}, fmt.Errorf("invalid %s: %s", awsParameterWorkersSetting, value)
+		}
+
+		workers = n
+	}
+
+	if value := os.Getenv(awsParameterRateSetting); len(value) > 0 {
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || n <= 0 {
+			return throttle{}, fmt.Errorf("invalid %s: %s", awsParameterRateSetting, value)
+		}
+
+		rate = n
+	}
+
+	return newThrottle(workers, rate, isParamThrottled), nil
+}
+
+func (s AWSParameterStore) send(t throttle, action string, requests []throttledRequest) error {
+	name, err := t.run(requests, func(done, total int) {
+		if total < paramProgressMin || s.io.UserOutput == nil {
+			return
+		}
+
+		if done*10/total != (done-1)*10/total {
+			fmt.Fprintf(s.io.UserOutput, "  %s %d/%d parameters\n", action, done, total)
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(s.io.UserOutput, "parameter: %s", name)
+	}
+
+	return err
+}
+
+func isParamThrottled(err error) bool {
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == ssm.ErrCodeTooManyUpdates {
+		return true
+	}
+
+	return request.IsErrorThrottle(err)
+}
+
+func deleteParamRequest(svc *ssm.SSM, name string) throttledRequest {
+	return throttledRequest{
+		name: name,
+		send: func() error {
+			_, err := svc.DeleteParameter(&ssm.DeleteParameterInput{
+				Name: aws.String(name),
+			})
+			return err
+		},
+	}
+}
+
+func reconcileParamTagsRequest(svc *ssm.SSM, name string, tags map[string]string, untagged *tagFailures) throttledRequest {
+	return throttledRequest{
+		name: name,
+		send: func() error {
+			err := reconcileParamTags(svc, name, tags)
+			if err != nil && !isParamThrottled(err) {
+				untagged.add(name, err)
+				return nil
+			}
+
+			return err
+		},
+	}
+}
+
+// tagFailures collects params that could not be tagged by parallel
+// requests, so a single warning is displayed.
+type tagFailures struct {
+	sync.Mutex
+
+	names []string
+	err   error
+}
+
+func (f *tagFailures) add(name string, err error) {
+	f.Lock()
+	defer f.Unlock()
+
+	f.names = append(f.names, name)
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+func (f *tagFailures) empty() bool {
+	f.Lock()
+	defer f.Unlock()
+
+	return len(f.names) == 0
+}
+
+func (f *tagFailures) warn(io models.IO) {
+	if len(f.names) == 0 {
+		return
+	}
+
+	if len(f.names) == 1 {
+		warnTags(f.names[0], f.err, io)
+		return
+	}
+
+	warnTags(fmt.Sprintf("%d parameters", len(f.names)), f.err, io)
+}
+
+//------------------------------------------
+//- Parameters are versioned individually, so
+//- the file revision identifies the version
+//- of every parameter.
+//------------------------------------------
+func paramRevision(params []param) string {
+	if len(params) == 0 {
+		return ""
+	}
+
+	versions := []string{}
+	for _, p := range params {
+		versions = append(versions, fmt.Sprintf("%s:%d", p.name, p.version))
+	}
+
+	sort.Strings(versions)
+
+	return revisionOf([]byte(strings.Join(versions, "\n")))
+}
+
 func lastModified(params []param) time.Time {
 	mostRecentlyModified := time.Time{}
 	for _, sp := range params {
@@ -530,15 +864,128 @@ func toMap(params []param) map[string]string {
 	return data
 }
 
-func isParamIn(param string, params gotenv.Env) bool {
-	for name := range params {
-		if name == param {
+func isParamIn(name string, params []param) bool {
+	for _, p := range params {
+		if p.name == name {
 			return true
 		}
 	}
 	return false
 }
 
+//------------------------------------------
+//- Values too large for a standard param
+//- use the advanced tier. Values too large
+//- for an advanced param are split into
+//- chunk params and the param holds a
+//- manifest.
+//------------------------------------------
+func buildParams(context, path, version string, env gotenv.Env, storedParams []param) []param {
+	params := []param{}
+
+	for name, value := range env {
+		remoteKey := buildRemoteKey(context, path, name, version)
+
+		if len(value) <= paramAdvancedMaxSize {
+			params = append(params, param{
+				name:  remoteKey,
+				value: value,
+				tier:  paramTier(remoteKey, len(value), storedParams),
+			})
+			continue
+		}
+
+		chunks := splitChunks(value, paramStandardMaxSize)
+
+		for i, chunk := range chunks {
+			chunkName := chunkParamKey(remoteKey, i)
+
+			params = append(params, param{
+				name:  chunkName,
+				value: chunk,
+				tier:  paramTier(chunkName, len(chunk), storedParams),
+			})
+		}
+
+		manifest := newChunkManifest(value, len(chunks))
+
+		params = append(params, param{
+			name:  remoteKey,
+			value: manifest,
+			tier:  paramTier(remoteKey, len(manifest), storedParams),
+		})
+	}
+
+	return params
+}
+
+// paramTier returns the tier needed for a value. Advanced params cannot
+// be changed back to standard, so they stay advanced.
+func paramTier(name string, size int, storedParams []param) string {
+	for _, p := range storedParams {
+		if p.name == name && p.tier == ssm.ParameterTierAdvanced {
+			return ssm.ParameterTierAdvanced
+		}
+	}
+
+	if size > paramStandardMaxSize {
+		return ssm.ParameterTierAdvanced
+	}
+
+	return ssm.ParameterTierStandard
+}
+
+func isParamChunk(name string) bool {
+	return strings.Contains(name, fmt.Sprintf("/%s/", paramChunkDir))
+}
+
+func chunkParamKey(remoteKey string, index int) string {
+	i := strings.LastIndex(remoteKey, "/")
+
+	return fmt.Sprintf("%s/%s/%s/%d", remoteKey[:i], paramChunkDir, remoteKey[i+1:], index)
+}
+
+// joinParamChunks replaces manifests with the reassembled values and
+// removes chunk params.
+func joinParamChunks(params []param) ([]param, error) {
+	values := map[string]string{}
+	for _, p := range params {
+		values[p.name] = p.value
+	}
+
+	joined := []param{}
+
+	for _, p := range params {
+		if isParamChunk(p.name) {
+			continue
+		}
+
+		if m, chunked := parseChunkManifest(p.value); chunked {
+			chunks := []string{}
+
+			for i := 0; i < m.Chunks; i++ {
+				chunk, found := values[chunkParamKey(p.name, i)]
+				if !found {
+					break
+				}
+
+				chunks = append(chunks, chunk)
+			}
+
+			value, err := m.join(chunks)
+			if err != nil {
+				return nil, fmt.Errorf("parameter: %s (%s)", p.name, err)
+			}
+
+			p.value = value
+		}
+
+		joined = append(joined, p)
+	}
+
+	return joined, nil
+}
+
 func noChange(np param, params []param) bool {
 	for _, p := range params {
 		if p.name == np.name {
@@ -556,6 +1003,31 @@ func buildRemoteKey(context, path, name, version string) string {
 	return fmt.Sprintf("/%s/%s/%s", context, path, name)
 }
 
+//------------------------------------------
+//- Parameter Store rejects names with other
+//- characters or too many levels, so every
+//- name is checked before any param is put.
+//------------------------------------------
+var paramLevelRegex = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+func validateParamNames(params []param) error {
+	for _, p := range params {
+		levels := strings.Split(strings.TrimPrefix(p.name, "/"), "/")
+
+		if len(levels) > paramMaxLevels {
+			return fmt.Errorf("%s has %d levels, but Parameter Store allows %d", p.name, len(levels), paramMaxLevels)
+		}
+
+		for _, level := range levels {
+			if !paramLevelRegex.MatchString(level) {
+				return fmt.Errorf("%s is not a valid parameter name, each level can only contain letters, numbers, and _ . -", p.name)
+			}
+		}
+	}
+
+	return nil
+}
+
 func buildRemotePath(context, path, version string) string {
 	if len(version) > 0 {
 		return fmt.Sprintf("/%s/%s/%s", context, version, path)
@@ -577,33 +1049,46 @@ func getStoredParams(context, path, version string, svc *ssm.SSM) ([]param, erro
 			value:        unformatValue(*sp.Value),
 			pType:        *sp.Type,
 			lastModified: *sp.LastModifiedDate,
+			version:      aws.Int64Value(sp.Version),
 		})
 	}
 
 	return parameters, nil
 }
 
-func getStoredParamsWithMetaData(context, path, version string, svc *ssm.SSM) ([]param, error) {
-
-	parameters := []param{}
+func getStoredParamsWithMetaData(context, path, version string, svc *ssm.SSM, t throttle) ([]param, error) {
 
 	storedParams, err := getStoredParams(context, path, version, svc)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, sp := range storedParams {
-		history, err := getParamHistory(svc, &sp.name, "", []*ssm.ParameterHistory{})
-		if err != nil {
-			return nil, err
-		}
+	requests := []throttledRequest{}
+
+	for i := range storedParams {
+		sp := &storedParams[i]
+
+		requests = append(requests, throttledRequest{
+			name: sp.name,
+			send: func() error {
+				history, err := getParamHistory(svc, &sp.name, "", []*ssm.ParameterHistory{})
+				if err != nil {
+					return err
+				}
 
-		sp.keyID = getCurrentKMSKeyID(history)
+				sp.keyID = getCurrentKMSKeyID(history)
+				sp.tier = getCurrentTier(history)
 
-		parameters = append(parameters, sp)
+				return nil
+			},
+		})
 	}
 
-	return parameters, nil
+	if _, err := t.run(requests, nil); err != nil {
+		return nil, err
+	}
+
+	return storedParams, nil
 }
 
 func getCurrentKMSKeyID(history []*ssm.ParameterHistory) (keyID string) {
@@ -621,14 +1106,32 @@ func getCurrentKMSKeyID(history []*ssm.ParameterHistory) (keyID string) {
 	return keyID
 }
 
+func getCurrentTier(history []*ssm.ParameterHistory) (tier string) {
+
+	var maxVersion int64 = 0
+
+	for _, ph := range history {
+
+		if ph.Version != nil && (*ph.Version) > maxVersion {
+			maxVersion = *ph.Version
+			tier = aws.StringValue(ph.Tier)
+		}
+	}
+
+	return tier
+}
+
 type param struct {
 	name  string
 	value string
 
 	keyID string
 	pType string
+	tier  string
 
 	lastModified time.Time
+
+	version int64
 }
 
 func init() {
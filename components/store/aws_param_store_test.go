@@ -0,0 +1,367 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// fakeSSM is a minimal stand-in for Parameter Store that throttles every
+// third write and denies the actions in denied.
+type fakeSSM struct {
+	sync.Mutex
+	params   map[string]fakeParam
+	tags     map[string]map[string]string
+	denied   map[string]bool
+	requests int
+	listed   int
+}
+
+type fakeParam struct {
+	Name             string
+	Value            string
+	Type             string
+	KeyId            string
+	Tier             string
+	Version          int64
+	LastModifiedDate int64
+}
+
+func (f *fakeSSM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	input := struct {
+		Name       string
+		Path       string
+		Value      string
+		Type       string
+		KeyId      string
+		Tier       string
+		NextToken  string
+		Overwrite  bool
+		Tags       []fakeTag
+		TagKeys    []string
+		ResourceId string
+	}{}
+
+	json.NewDecoder(r.Body).Decode(&input)
+
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonSSM.")
+
+	if f.denied[action] {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"AccessDeniedException","message":"%s"}`, action)
+		return
+	}
+
+	switch action {
+	case "PutParameter", "DeleteParameter":
+		f.requests++
+		if f.requests%3 == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"ThrottlingException","message":"Rate exceeded"}`)
+			return
+		}
+	}
+
+	switch action {
+	case "PutParameter":
+		if len(input.Tags) > 0 && f.denied["AddTagsToResource"] {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"AccessDeniedException","message":"AddTagsToResource"}`)
+			return
+		}
+
+		p, found := f.params[input.Name]
+
+		if found && !input.Overwrite {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"ParameterAlreadyExists","message":"exists"}`)
+			return
+		}
+
+		if !found {
+			f.tags[input.Name] = map[string]string{}
+			for _, tag := range input.Tags {
+				f.tags[input.Name][tag.Key] = tag.Value
+			}
+		}
+
+		p.Name, p.Value, p.Type, p.KeyId, p.Tier = input.Name, input.Value, input.Type, input.KeyId, input.Tier
+		p.Version++
+		p.LastModifiedDate = time.Now().Unix()
+		f.params[input.Name] = p
+
+		fmt.Fprintf(w, `{"Version":%d,"Tier":"%s"}`, p.Version, p.Tier)
+	case "DeleteParameter":
+		delete(f.params, input.Name)
+		delete(f.tags, input.Name)
+
+		fmt.Fprint(w, `{}`)
+	case "ListTagsForResource":
+		f.listed++
+		tags := []fakeTag{}
+		for key, value := range f.tags[input.ResourceId] {
+			tags = append(tags, fakeTag{Key: key, Value: value})
+		}
+
+		b, _ := json.Marshal(map[string][]fakeTag{"TagList": tags})
+		w.Write(b)
+	case "AddTagsToResource":
+		for _, tag := range input.Tags {
+			f.tags[input.ResourceId][tag.Key] = tag.Value
+		}
+
+		fmt.Fprint(w, `{}`)
+	case "RemoveTagsFromResource":
+		for _, key := range input.TagKeys {
+			delete(f.tags[input.ResourceId], key)
+		}
+
+		fmt.Fprint(w, `{}`)
+	case "GetParameterHistory":
+		b, _ := json.Marshal(map[string][]fakeParam{"Parameters": {f.params[input.Name]}})
+		w.Write(b)
+	case "GetParametersByPath":
+		names := []string{}
+		for name := range f.params {
+			if strings.HasPrefix(name, input.Path+"/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		start, _ := strconv.Atoi(input.NextToken)
+		end := start + 10
+
+		output := map[string]interface{}{}
+		if end < len(names) {
+			output["NextToken"] = strconv.Itoa(end)
+		} else {
+			end = len(names)
+		}
+
+		params := []fakeParam{}
+		for _, name := range names[start:end] {
+			params = append(params, f.params[name])
+		}
+		output["Parameters"] = params
+
+		b, _ := json.Marshal(output)
+		w.Write(b)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"InvalidAction","message":"%s"}`, action)
+	}
+}
+
+func setupParamStore(t *testing.T) (*AWSParameterStore, *fakeSSM, *bytes.Buffer, func()) {
+	fake := &fakeSSM{params: map[string]fakeParam{}, tags: map[string]map[string]string{}, denied: map[string]bool{}}
+	sess, cleanup := fakeAWSSession(t, fake)
+
+	output := &bytes.Buffer{}
+
+	s := &AWSParameterStore{
+		Session: sess,
+		clog:    testCatalog(t),
+		io:      models.IO{UserOutput: output},
+	}
+	s.uo.Silent = true
+
+	return s, fake, output, cleanup
+}
+
+func envFile(keys int, value string) []byte {
+	var buffer bytes.Buffer
+
+	for i := 0; i < keys; i++ {
+		buffer.WriteString(fmt.Sprintf("KEY_%03d=%s%d\n", i, value, i))
+	}
+
+	return buffer.Bytes()
+}
+
+func TestEnsureParamsArePushedInParallelWhenThrottled(t *testing.T) {
+	// arrange
+	s, fake, output, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	os.Setenv(awsParameterRateSetting, "1000")
+	defer os.Unsetenv(awsParameterRateSetting)
+
+	file := &catalog.File{Path: "dev/.env", Type: "env", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+
+	if err := s.Push(file, envFile(60, "first"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := s.Push(file, envFile(40, "second"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.params) != 40 {
+		t.Errorf("\nEXPECTED: %d params \nACTUAL: %d params", 40, len(fake.params))
+	}
+
+	b, _, err := s.Pull(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	sort.Strings(lines)
+
+	expected := strings.Split(strings.TrimSpace(string(envFile(40, "second"))), "\n")
+
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", expected, lines)
+	}
+
+	if !strings.Contains(output.String(), "stored 40/40 parameters") || !strings.Contains(output.String(), "deleted 20/20 parameters") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "progress", output.String())
+	}
+}
+
+func TestEnsureSilentParamPurgeIsNotConfirmed(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/.env", Type: "env", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := s.Purge(file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.params) != 0 {
+		t.Errorf("\nEXPECTED: %d params \nACTUAL: %d params", 0, len(fake.params))
+	}
+}
+
+func TestEnsureJSONIsStoredAsParamHierarchy(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+	data := `{"db":{"host":"localhost","port":5432,"replicas":["a","b"]},"debug":false,"name":"5432"}`
+
+	// act
+	if err := s.Push(file, []byte(data), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _, err := s.Pull(file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p := fake.params[buildRemoteKey(t.Name(), file.Path, "db/host", "")]; p.Value != "localhost" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "localhost", p.Value)
+	}
+
+	if p := fake.params[buildRemoteKey(t.Name(), file.Path, "db/replicas/1", "")]; p.Value != "b" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "b", p.Value)
+	}
+
+	var expected, actual interface{}
+	json.Unmarshal([]byte(data), &expected)
+	json.Unmarshal(b, &actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", data, string(b))
+	}
+}
+
+func TestEnsureParamsArePulledWithoutHistoryPermission(t *testing.T) {
+	// arrange
+	s, fake, output, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/.env", Type: "env", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+
+	if err := s.Push(file, []byte("ENV=dev\n"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.denied["GetParameterHistory"] = true
+
+	// act
+	b, attr, err := s.Pull(file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "ENV=dev\n" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ENV=dev\n", string(b))
+	}
+
+	if attr.Modified.IsZero() || len(attr.LastWriter) > 0 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %+v", "modified time without last writer", attr)
+	}
+
+	if !strings.Contains(output.String(), "AccessDeniedException") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "AccessDeniedException warning", output.String())
+	}
+}
+
+func TestEnsureInvalidParamNamesAreRejectedBeforeAnyPut(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"character", `{"db":{"host":"localhost","user name":"admin"}}`},
+		{"levels", `{"a":{"b":{"c":{"d":{"e":{"f":{"g":{"h":{"i":{"j":{"k":{"l":{"m":"deep"}}}}}}}}}}}},"ok":"1"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// arrange
+			s, fake, _, cleanup := setupParamStore(t)
+			defer cleanup()
+
+			file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+
+			// act
+			err := s.Push(file, []byte(test.data), "")
+
+			// assert
+			if err == nil {
+				t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "invalid name error", err)
+			}
+
+			if len(fake.params) > 0 {
+				t.Errorf("\nEXPECTED: %d params \nACTUAL: %d", 0, len(fake.params))
+			}
+		})
+	}
+}
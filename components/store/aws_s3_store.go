@@ -2,22 +2,30 @@ package store
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/turnerlabs/cstore/v4/components/awssession"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/setting"
-	"github.com/turnerlabs/cstore/v4/components/vault"
 )
 
 // S3Store ...
@@ -37,21 +45,17 @@ func (s S3Store) Name() string {
 	return "aws-s3"
 }
 
-// SupportsFeature ...
-func (s S3Store) SupportsFeature(feature string) bool {
-	switch feature {
-	case VersionFeature:
-		return true
-	default:
-		return false
+// Capabilities ...
+func (s S3Store) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning:        true,
+		History:           true,
+		ConditionalWrites: true,
+		ClientEncryption:  true,
+		Binary:            true,
 	}
 }
 
-// SupportsFileType ...
-func (s S3Store) SupportsFileType(fileType string) bool {
-	return true
-}
-
 // Description ...
 func (s S3Store) Description() string {
 	return `
@@ -80,101 +84,179 @@ func (s *S3Store) Pre(clog catalog.Catalog, file *catalog.File, access contract.
 	}
 
 	//------------------------------------------
-	//- Get AWS Region
+	//- Get S3 Compatible Endpoint
 	//------------------------------------------
-	region, err := setting.Setting{
-		Description:  "Export as an environment variable to silence this prompt.",
-		Group:        clog.Context,
-		Prop:         awsRegion,
-		Prompt:       uo.Prompt,
-		Silent:       uo.Silent,
-		AutoSave:     true,
-		PromptOnce:   true,
-		DefaultValue: awsDefaultRegion,
-		Vault:        vault.EnvVault{},
-	}.Get(clog.Context, io)
+	config, err := s.endpointConfig(clog, file, uo, io)
+	if err != nil {
+		return err
+	}
 
 	//------------------------------------------
-	//- Get AWS Credentials from Environment
+	//- Get AWS Session
 	//------------------------------------------
-	if _, ok := access.(vault.EnvVault); ok {
-		s.Session, err = session.NewSession(&aws.Config{
-			Region: aws.String(region),
-		})
-
+	sess, err := awssession.Get(clog, file, access, uo, io)
+	if err != nil {
 		return err
 	}
 
 	//------------------------------------------
-	//- Get AWS Credentials from Vault
+	//- The endpoint only applies to S3, so the
+	//- shared session keeps using AWS for STS.
 	//------------------------------------------
-	id, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsAccessKeyID,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
+	s.Session = sess.Copy(config)
+
+	return nil
+}
+
+//------------------------------------------
+//- S3 compatible stores like MinIO, Ceph,
+//- Wasabi, or R2 need a custom endpoint and
+//- often path style addressing or a private
+//- certificate authority.
+//------------------------------------------
+func (s S3Store) endpointConfig(clog catalog.Catalog, file *catalog.File, uo cfg.UserOptions, io models.IO) (*aws.Config, error) {
+	config := &aws.Config{}
+
+	endpoint, err := s.optionalSetting(clog, file, awsS3EndpointSetting, "S3 compatible endpoint URL like https://minio.example.com:9000. Leave blank to use AWS.", uo.Prompt, uo, io)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	secret, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSecretAccessKey,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
+	if len(endpoint) > 0 {
+		if _, err := url.ParseRequestURI(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", awsS3EndpointSetting, err)
+		}
+
+		config.Endpoint = aws.String(endpoint)
 	}
 
-	token, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSessionToken,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
+	custom := uo.Prompt && len(endpoint) > 0
+
+	pathStyle, err := s.optionalSetting(clog, file, awsS3ForcePathStyleSetting, "Address buckets in the URL path instead of the host name (true/false). Most S3 compatible stores require true.", custom, uo, io)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	s.Session, err = session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(id, secret, token),
-	})
+	//------------------------------------------
+	//- TLS settings depend on the machine, so
+	//- they are only read from the environment
+	//- and never saved with the file.
+	//------------------------------------------
+	delete(file.Data, awsS3InsecureSkipVerifySetting)
+	delete(file.Data, awsS3CABundleSetting)
 
-	return err
+	insecure := os.Getenv(awsS3InsecureSkipVerifySetting)
+	caBundle := os.Getenv(awsS3CABundleSetting)
+
+	if len(pathStyle) > 0 {
+		value, err := strconv.ParseBool(pathStyle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", awsS3ForcePathStyleSetting, err)
+		}
+
+		config.S3ForcePathStyle = aws.Bool(value)
+	}
+
+	skip := false
+	if len(insecure) > 0 {
+		if skip, err = strconv.ParseBool(insecure); err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", awsS3InsecureSkipVerifySetting, err)
+		}
+	}
+
+	//------------------------------------------
+	//- The S3 bundle is used over the shared
+	//- AWS_CA_BUNDLE environment variable.
+	//------------------------------------------
+	if len(caBundle) == 0 && !skip {
+		return config, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: skip}
+
+	if len(caBundle) > 0 {
+		pem, err := ioutil.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", awsS3CABundleSetting, err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("invalid %s: no certificates found in %s", awsS3CABundleSetting, caBundle)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	config.HTTPClient = &http.Client{Transport: transport}
+
+	return config, nil
+}
+
+//------------------------------------------
+//- Optional settings are read from the file,
+//- the environment, or another file in this
+//- store and only prompted for on request.
+//------------------------------------------
+func (s S3Store) optionalSetting(clog catalog.Catalog, file *catalog.File, prop, description string, prompt bool, uo cfg.UserOptions, io models.IO) (string, error) {
+	if value, found := file.Data[prop]; found {
+		return value, nil
+	}
+
+	value := os.Getenv(prop)
+	if len(value) == 0 {
+		value = clog.GetDataByStore(s.Name(), prop, "")
+	}
+
+	if prompt && !uo.Silent {
+		return setting.Setting{
+			Description:  description,
+			Prop:         prop,
+			Prompt:       true,
+			Silent:       uo.Silent,
+			AutoSave:     true,
+			DefaultValue: value,
+			Vault:        file,
+		}.Get(clog.Context, io)
+	}
+
+	if len(value) > 0 {
+		file.AddData(map[string]string{
+			prop: value,
+		})
+	}
+
+	return value, nil
 }
 
 // Purge ...
 func (s S3Store) Purge(file *catalog.File, version string) error {
 
-	contextKey := s.key(file.ActualPath(), version)
-
 	bucket, err := s.bucket.Get(s.clog.Context, s.io)
 	if err != nil {
 		return err
 	}
 
+	s3svc := s3.New(s.Session)
+
+	if objectVersioning(*file) {
+		return s.purgeVersions(s3svc, file, bucket, version)
+	}
+
+	contextKey := s.key(file.ActualPath(), version)
+
 	input := s3.DeleteObjectInput{
 		Bucket: &bucket,
 		Key:    &contextKey,
 	}
 
-	s3svc := s3.New(s.Session)
-
 	if _, err := s3svc.DeleteObject(&input); err != nil {
 		return err
 	}
@@ -184,24 +266,59 @@ func (s S3Store) Purge(file *catalog.File, version string) error {
 
 // Push ...
 func (s S3Store) Push(file *catalog.File, fileData []byte, version string) error {
+	_, err := s.PushIf(file, fileData, version, "")
+	return err
+}
 
-	contextKey := s.key(file.ActualPath(), version)
+// PushIf ...
+func (s S3Store) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
 
 	bucket, err := s.bucket.Get(s.clog.Context, s.io)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	file.AddData(map[string]string{
 		awsBucketSetting: bucket,
 	})
 
+	native, err := s.useObjectVersioning(file, bucket)
+	if err != nil {
+		return "", err
+	}
+
+	//------------------------------------------
+	//- With object versioning, every push is a
+	//- new version of the working copy and user
+	//- versions are labeled with an object tag.
+	//------------------------------------------
+	contextKey := s.key(file.ActualPath(), version)
+	if native {
+		contextKey = s.key(file.ActualPath(), "")
+	}
+
 	input := &s3manager.UploadInput{
 		Bucket: &bucket,
 		Key:    &contextKey,
 		Body:   bytes.NewReader(fileData),
+		Metadata: map[string]*string{
+			awsS3WriterMetadata: aws.String(writer()),
+		},
+	}
+
+	tags, err := resourceTags(s.clog, file, s.uo, s.io)
+	if err != nil {
+		return "", err
+	}
+
+	if native && len(version) > 0 {
+		tags[awsS3VersionTag] = version
 	}
 
+	s.limitTags(tags)
+
+	input.Tagging = aws.String(s3Tagging(tags))
+
 	//------------------------------------------
 	//- Set server side KMS Key encryption
 	//------------------------------------------
@@ -222,18 +339,62 @@ func (s S3Store) Push(file *catalog.File, fileData []byte, version string) error
 		input.ServerSideEncryption = &etype
 	}
 
+	//------------------------------------------
+	//- Only overwrite the ETag last seen.
+	//------------------------------------------
+	etag := ""
+	options := []request.Option{request.WithGetResponseHeader("ETag", &etag)}
+
+	if len(revision) > 0 {
+		options = append(options, request.WithSetRequestHeaders(map[string]string{"If-Match": revision}))
+	}
+
 	uploader := s3manager.NewUploader(s.Session)
 
-	_, err = uploader.Upload(input)
+	output, err := uploader.Upload(input, s3manager.WithUploaderRequestOptions(options...))
+	if tagsDenied(err) {
+		tagErr := err
 
-	return err
+		input.Body = bytes.NewReader(fileData)
+		input.Tagging = nil
+
+		if output, err = uploader.Upload(input, s3manager.WithUploaderRequestOptions(options...)); err == nil {
+			warnTags(contextKey, tagErr, s.io)
+		}
+	}
+
+	if err != nil {
+		if rerr, ok := err.(awserr.RequestFailure); ok && (rerr.StatusCode() == http.StatusPreconditionFailed || rerr.StatusCode() == http.StatusConflict) {
+			return "", contract.ErrRevisionConflict
+		}
+
+		return "", err
+	}
+
+	if native && len(version) > 0 {
+		if output.VersionID == nil {
+			return "", fmt.Errorf("versioning is not enabled for bucket %s", bucket)
+		}
+
+		file.AddData(map[string]string{
+			awsS3VersionIDPrefix + version: *output.VersionID,
+		})
+	}
+
+	//------------------------------------------
+	//- Multipart uploads do not return the ETag
+	//- of the object in a header.
+	//------------------------------------------
+	if len(output.UploadID) > 0 {
+		return s.Revision(file, version)
+	}
+
+	return etag, nil
 }
 
 // Pull ...
 func (s S3Store) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
 
-	contextKey := s.key(file.ActualPath(), version)
-
 	setting := s.bucket
 	setting.Prompt = false
 
@@ -242,12 +403,18 @@ func (s S3Store) Pull(file *catalog.File, version string) ([]byte, contract.Attr
 		return []byte{}, contract.Attributes{}, err
 	}
 
-	input := s3.GetObjectInput{
-		Bucket: &bucket,
-		Key:    &contextKey,
+	s3svc := s3.New(s.Session)
+
+	key, id, err := s.object(s3svc, file, bucket, version)
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
 	}
 
-	s3svc := s3.New(s.Session)
+	input := s3.GetObjectInput{
+		Bucket:    &bucket,
+		Key:       key,
+		VersionId: id,
+	}
 
 	fileData, err := s3svc.GetObject(&input)
 	if err != nil {
@@ -260,14 +427,88 @@ func (s S3Store) Pull(file *catalog.File, version string) ([]byte, contract.Attr
 		return b, contract.Attributes{}, err
 	}
 
-	return b, contract.Attributes{}, nil
+	attr := attributes(b)
+	attr.Modified = aws.TimeValue(fileData.LastModified)
+	attr.KMSKeyID = aws.StringValue(fileData.SSEKMSKeyId)
+	attr.LastWriter = s3Writer(fileData.Metadata)
+
+	//------------------------------------------
+	//- With object versioning, versions are
+	//- never written, so only the working copy
+	//- has a revision.
+	//------------------------------------------
+	if input.VersionId == nil {
+		attr.Revision = aws.StringValue(fileData.ETag)
+	}
+
+	return b, attr, nil
+}
+
+// Stat ...
+func (s S3Store) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+
+	setting := s.bucket
+	setting.Prompt = false
+
+	bucket, err := setting.Get(s.clog.Context, s.io)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	s3svc := s3.New(s.Session)
+
+	key, id, err := s.object(s3svc, file, bucket, version)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	output, err := s3svc.HeadObject(&s3.HeadObjectInput{
+		Bucket:    &bucket,
+		Key:       key,
+		VersionId: id,
+	})
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	attr := contract.Attributes{
+		Modified:   aws.TimeValue(output.LastModified),
+		Size:       int(aws.Int64Value(output.ContentLength)),
+		KMSKeyID:   aws.StringValue(output.SSEKMSKeyId),
+		LastWriter: s3Writer(output.Metadata),
+	}
+
+	if id == nil {
+		attr.Revision = aws.StringValue(output.ETag)
+	}
+
+	return attr, nil
+}
+
+//------------------------------------------
+//- With object versioning, versions are
+//- S3 object versions of the working copy.
+//------------------------------------------
+func (s S3Store) object(s3svc *s3.S3, file *catalog.File, bucket, version string) (*string, *string, error) {
+	if !objectVersioning(*file) {
+		return aws.String(s.key(file.ActualPath(), version)), nil, nil
+	}
+
+	if len(version) == 0 {
+		return aws.String(s.key(file.ActualPath(), "")), nil, nil
+	}
+
+	id, err := s.versionID(s3svc, file, bucket, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return aws.String(s.key(file.ActualPath(), "")), &id, nil
 }
 
 // Changed ...
 func (s S3Store) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
 
-	contextKey := s.key(file.ActualPath(), version)
-
 	setting := s.bucket
 	setting.Prompt = false
 
@@ -276,28 +517,186 @@ func (s S3Store) Changed(file *catalog.File, fileData []byte, version string) (t
 		return time.Time{}, err
 	}
 
-	input := s3.GetObjectInput{
+	input := s3.HeadObjectInput{
 		Bucket: &bucket,
-		Key:    &contextKey,
+		Key:    aws.String(s.key(file.ActualPath(), version)),
 	}
 
-	s3svc := s3.New(s.Session)
+	if objectVersioning(*file) {
+		input.Key = aws.String(s.key(file.ActualPath(), ""))
 
-	fileMetaData, err := s3svc.GetObject(&input)
+		if len(version) > 0 {
+			id, found := file.Data[awsS3VersionIDPrefix+version]
+			if !found {
+				return time.Time{}, nil
+			}
 
-	if aerr, ok := err.(awserr.Error); ok {
-		if aerr.Code() == s3.ErrCodeNoSuchKey {
+			input.VersionId = &id
+		}
+	}
+
+	s3svc := s3.New(s.Session)
+
+	fileMetaData, err := s3svc.HeadObject(&input)
+	if err != nil {
+		if s3NotFound(err) {
 			return time.Time{}, nil
 		}
 
 		return time.Time{}, err
 	}
 
-	defer fileMetaData.Body.Close()
-
 	return *fileMetaData.LastModified, nil
 }
 
+// Revision ...
+func (s S3Store) Revision(file *catalog.File, version string) (string, error) {
+
+	setting := s.bucket
+	setting.Prompt = false
+
+	bucket, err := setting.Get(s.clog.Context, s.io)
+	if err != nil {
+		return "", err
+	}
+
+	contextKey := s.key(file.ActualPath(), version)
+	if objectVersioning(*file) {
+		contextKey = s.key(file.ActualPath(), "")
+	}
+
+	output, err := s3.New(s.Session).HeadObject(&s3.HeadObjectInput{
+		Bucket: &bucket,
+		Key:    &contextKey,
+	})
+	if err != nil {
+		if s3NotFound(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return aws.StringValue(output.ETag), nil
+}
+
+// History ...
+func (s S3Store) History(file *catalog.File) ([]contract.Revision, error) {
+
+	if !objectVersioning(*file) {
+		return []contract.Revision{}, contract.ErrHistoryNotSupported
+	}
+
+	setting := s.bucket
+	setting.Prompt = false
+
+	bucket, err := setting.Get(s.clog.Context, s.io)
+	if err != nil {
+		return []contract.Revision{}, err
+	}
+
+	versions, _, err := s.objectVersions(s3.New(s.Session), bucket, s.key(file.ActualPath(), ""))
+	if err != nil {
+		return []contract.Revision{}, err
+	}
+
+	labels := map[string]string{}
+	for key, id := range file.Data {
+		if strings.HasPrefix(key, awsS3VersionIDPrefix) {
+			labels[id] = strings.TrimPrefix(key, awsS3VersionIDPrefix)
+		}
+	}
+
+	revisions := []contract.Revision{}
+	for _, v := range versions {
+		revisions = append(revisions, contract.Revision{
+			ID:       *v.VersionId,
+			Version:  labels[*v.VersionId],
+			Modified: *v.LastModified,
+			Latest:   *v.IsLatest,
+		})
+	}
+
+	return revisions, nil
+}
+
+// Rollback ...
+func (s S3Store) Rollback(file *catalog.File, revision string) error {
+
+	if !objectVersioning(*file) {
+		return contract.ErrHistoryNotSupported
+	}
+
+	bucket, err := s.bucket.Get(s.clog.Context, s.io)
+	if err != nil {
+		return err
+	}
+
+	s3svc := s3.New(s.Session)
+
+	id, err := s.versionID(s3svc, file, bucket, revision)
+	if err != nil {
+		return err
+	}
+
+	tags, err := resourceTags(s.clog, file, s.uo, s.io)
+	if err != nil {
+		return err
+	}
+
+	s.limitTags(tags)
+
+	//------------------------------------------
+	//- Copy the prior version over the working
+	//- copy without its user version label.
+	//------------------------------------------
+	contextKey := s.key(file.ActualPath(), "")
+
+	input := s3.CopyObjectInput{
+		Bucket:            &bucket,
+		Key:               &contextKey,
+		CopySource:        aws.String(fmt.Sprintf("%s/%s?versionId=%s", bucket, url.PathEscape(contextKey), url.QueryEscape(id))),
+		TaggingDirective:  aws.String(s3.TaggingDirectiveReplace),
+		Tagging:           aws.String(s3Tagging(tags)),
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+		Metadata: map[string]*string{
+			awsS3WriterMetadata: aws.String(writer()),
+		},
+	}
+
+	if value := file.Data[awsStoreKMSKeyID]; len(value) > 0 {
+		input.SSEKMSKeyId = &value
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+	}
+
+	_, err = s3svc.CopyObject(&input)
+	if !tagsDenied(err) {
+		return err
+	}
+
+	tagErr := err
+	input.Tagging = nil
+
+	if _, err := s3svc.CopyObject(&input); err != nil {
+		return err
+	}
+
+	warnTags(contextKey, tagErr, s.io)
+
+	return nil
+}
+
+//------------------------------------------
+//- S3 objects are limited to 10 tags, so
+//- user defined tags over the limit are not
+//- set instead of failing the push.
+//------------------------------------------
+func (s S3Store) limitTags(tags map[string]string) {
+	if dropped := limitTags(tags, s3MaxTags); len(dropped) > 0 {
+		display.Warn(fmt.Errorf("S3 objects are limited to %d tags, so %s were not set", s3MaxTags, strings.Join(dropped, ", ")), s.io.UserOutput)
+	}
+}
+
 func init() {
 	s := new(S3Store)
 	stores[s.Name()] = s
@@ -314,3 +713,273 @@ func (s S3Store) key(path, version string) string {
 
 	return fmt.Sprintf("%s/%s", s.clog.Context, path)
 }
+
+//------------------------------------------
+//- Object versioning is opted into on the
+//- first push of a file to a bucket with
+//- versioning enabled. Files already stored
+//- keep using key prefixes, since pushing a
+//- version would replace their working copy.
+//------------------------------------------
+func (s S3Store) useObjectVersioning(file *catalog.File, bucket string) (bool, error) {
+
+	if value, found := file.Data[awsS3ObjectVersioning]; found {
+		return strconv.ParseBool(value)
+	}
+
+	s3svc := s3.New(s.Session)
+
+	native, err := bucketVersioning(s3svc, bucket)
+	if err != nil {
+		return false, err
+	}
+
+	if native {
+		stored, err := s.stored(s3svc, file, bucket)
+		if err != nil {
+			return false, err
+		}
+
+		native = !stored
+	}
+
+	if native {
+		value, err := s.optionalSetting(s.clog, file, awsS3ObjectVersioning, "Bucket versioning is enabled. Keep file versions as S3 object versions instead of separate files (true/false)?", true, s.uo, s.io)
+		if err != nil {
+			return false, err
+		}
+
+		native, _ = strconv.ParseBool(value)
+	}
+
+	file.AddData(map[string]string{
+		awsS3ObjectVersioning: strconv.FormatBool(native),
+	})
+
+	return native, nil
+}
+
+//------------------------------------------
+//- Users without permission to read the
+//- bucket versioning and S3 compatible
+//- stores without versioning keep using
+//- key prefixes.
+//------------------------------------------
+func bucketVersioning(s3svc *s3.S3, bucket string) (bool, error) {
+
+	output, err := s3svc.GetBucketVersioning(&s3.GetBucketVersioningInput{
+		Bucket: &bucket,
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && (aerr.Code() == "AccessDenied" || aerr.Code() == "NotImplemented") {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return aws.StringValue(output.Status) == s3.BucketVersioningStatusEnabled, nil
+}
+
+//------------------------------------------
+//- A file is already stored when it has a
+//- working copy or a version stored with a
+//- key prefix. The catalog lists the version
+//- being pushed, so the bucket is checked
+//- instead of the catalog.
+//------------------------------------------
+func (s S3Store) stored(s3svc *s3.S3, file *catalog.File, bucket string) (bool, error) {
+
+	for _, version := range append([]string{""}, file.Versions...) {
+		_, err := s3svc.HeadObject(&s3.HeadObjectInput{
+			Bucket: &bucket,
+			Key:    aws.String(s.key(file.ActualPath(), version)),
+		})
+		if err == nil {
+			return true, nil
+		} else if !s3NotFound(err) {
+			return false, err
+		}
+	}
+
+	return false, nil
+}
+
+func objectVersioning(file catalog.File) bool {
+	native, _ := strconv.ParseBool(file.Data[awsS3ObjectVersioning])
+	return native
+}
+
+//------------------------------------------
+//- Find the object version id for a user
+//- version label or an object version id.
+//------------------------------------------
+func (s S3Store) versionID(s3svc *s3.S3, file *catalog.File, bucket, version string) (string, error) {
+
+	if id, found := file.Data[awsS3VersionIDPrefix+version]; found {
+		return id, nil
+	}
+
+	contextKey := s.key(file.ActualPath(), "")
+
+	versions, _, err := s.objectVersions(s3svc, bucket, contextKey)
+	if err != nil {
+		return "", err
+	}
+
+	for _, v := range versions {
+		if *v.VersionId == version {
+			return version, nil
+		}
+	}
+
+	for _, v := range versions {
+		output, err := s3svc.GetObjectTagging(&s3.GetObjectTaggingInput{
+			Bucket:    &bucket,
+			Key:       &contextKey,
+			VersionId: v.VersionId,
+		})
+		if err != nil {
+			return "", err
+		}
+
+		for _, tag := range output.TagSet {
+			if *tag.Key == awsS3VersionTag && *tag.Value == version {
+				return *v.VersionId, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("version %s not found for %s", version, file.ActualPath())
+}
+
+//------------------------------------------
+//- List object versions and delete markers
+//- for a key, newest first.
+//------------------------------------------
+func (s S3Store) objectVersions(s3svc *s3.S3, bucket, key string) ([]*s3.ObjectVersion, []*s3.DeleteMarkerEntry, error) {
+
+	versions := []*s3.ObjectVersion{}
+	markers := []*s3.DeleteMarkerEntry{}
+
+	input := s3.ListObjectVersionsInput{
+		Bucket: &bucket,
+		Prefix: &key,
+	}
+
+	err := s3svc.ListObjectVersionsPages(&input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, v := range page.Versions {
+			if *v.Key == key {
+				versions = append(versions, v)
+			}
+		}
+
+		for _, m := range page.DeleteMarkers {
+			if *m.Key == key {
+				markers = append(markers, m)
+			}
+		}
+
+		return true
+	})
+
+	return versions, markers, err
+}
+
+func (s S3Store) purgeVersions(s3svc *s3.S3, file *catalog.File, bucket, version string) error {
+
+	contextKey := s.key(file.ActualPath(), "")
+
+	if len(version) > 0 {
+		id, err := s.versionID(s3svc, file, bucket, version)
+		if err != nil {
+			return err
+		}
+
+		//------------------------------------------
+		//- The latest object version is also the
+		//- working copy, so deleting it would roll
+		//- the file back to an older copy.
+		//------------------------------------------
+		versions, _, err := s.objectVersions(s3svc, bucket, contextKey)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range versions {
+			if aws.BoolValue(v.IsLatest) && aws.StringValue(v.VersionId) == id {
+				return fmt.Errorf("%s is the current version of %s and cannot be purged until a newer version is pushed", version, file.ActualPath())
+			}
+		}
+
+		if _, err := s3svc.DeleteObject(&s3.DeleteObjectInput{
+			Bucket:    &bucket,
+			Key:       &contextKey,
+			VersionId: &id,
+		}); err != nil {
+			return err
+		}
+
+		delete(file.Data, awsS3VersionIDPrefix+version)
+
+		return nil
+	}
+
+	versions, markers, err := s.objectVersions(s3svc, bucket, contextKey)
+	if err != nil {
+		return err
+	}
+
+	ids := []*string{}
+	for _, v := range versions {
+		ids = append(ids, v.VersionId)
+	}
+
+	for _, m := range markers {
+		ids = append(ids, m.VersionId)
+	}
+
+	for _, id := range ids {
+		if _, err := s3svc.DeleteObject(&s3.DeleteObjectInput{
+			Bucket:    &bucket,
+			Key:       &contextKey,
+			VersionId: id,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for key := range file.Data {
+		if strings.HasPrefix(key, awsS3VersionIDPrefix) {
+			delete(file.Data, key)
+		}
+	}
+
+	return nil
+}
+
+//------------------------------------------
+//- Metadata keys are returned in canonical
+//- header format.
+//------------------------------------------
+func s3Writer(metadata map[string]*string) string {
+	for key, value := range metadata {
+		if strings.EqualFold(key, awsS3WriterMetadata) {
+			return aws.StringValue(value)
+		}
+	}
+
+	return ""
+}
+
+func s3NotFound(err error) bool {
+	if rerr, ok := err.(awserr.RequestFailure); ok && rerr.StatusCode() == 404 {
+		return true
+	}
+
+	if aerr, ok := err.(awserr.Error); ok {
+		return aerr.Code() == s3.ErrCodeNoSuchKey || aerr.Code() == "NotFound"
+	}
+
+	return false
+}
@@ -0,0 +1,612 @@
+package store
+
+import (
+	"crypto/md5"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+// fakeS3 is a minimal stand-in for a versioned S3 bucket using path
+// style addressing.
+type fakeS3 struct {
+	sync.Mutex
+	versions map[string][]fakeS3Version
+	next     int
+	gets     int
+
+	// denyVersioning rejects reading the bucket versioning like a
+	// policy without s3:GetBucketVersioning.
+	denyVersioning bool
+}
+
+type fakeS3Version struct {
+	id       string
+	etag     string
+	data     []byte
+	tags     url.Values
+	modified time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	query := r.URL.Query()
+
+	if len(parts) == 1 {
+		switch {
+		case query["versioning"] != nil && f.denyVersioning:
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<Error><Code>AccessDenied</Code></Error>`)
+		case query["versioning"] != nil:
+			fmt.Fprint(w, `<VersioningConfiguration><Status>Enabled</Status></VersioningConfiguration>`)
+		case query["versions"] != nil:
+			key := query.Get("prefix")
+			versions := f.versions[key]
+
+			fmt.Fprint(w, `<ListVersionsResult>`)
+			for i := len(versions) - 1; i >= 0; i-- {
+				fmt.Fprintf(w, `<Version><Key>%s</Key><VersionId>%s</VersionId><IsLatest>%t</IsLatest><LastModified>%s</LastModified></Version>`,
+					key, versions[i].id, i == len(versions)-1, versions[i].modified.Format(time.RFC3339))
+			}
+			fmt.Fprint(w, `</ListVersionsResult>`)
+		}
+		return
+	}
+
+	key := parts[1]
+	versions := f.versions[key]
+
+	index := len(versions) - 1
+	if id := query.Get("versionId"); len(id) > 0 {
+		index = -1
+		for i, v := range versions {
+			if v.id == id {
+				index = i
+			}
+		}
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if match := r.Header.Get("If-Match"); len(match) > 0 && (index < 0 || versions[index].etag != match) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code></Error>`)
+			return
+		}
+
+		v := fakeS3Version{modified: time.Now().UTC()}
+
+		if source := r.Header.Get("x-amz-copy-source"); len(source) > 0 {
+			u, _ := url.Parse("/" + strings.TrimPrefix(source, "/"))
+			for _, sv := range versions {
+				if sv.id == u.Query().Get("versionId") {
+					v.data = sv.data
+				}
+			}
+		} else {
+			v.data, _ = ioutil.ReadAll(r.Body)
+			v.tags, _ = url.ParseQuery(r.Header.Get("x-amz-tagging"))
+		}
+
+		f.next++
+		v.id = "id" + strconv.Itoa(f.next)
+		v.etag = fmt.Sprintf(`"%x"`, md5.Sum(v.data))
+		f.versions[key] = append(versions, v)
+
+		w.Header().Set("x-amz-version-id", v.id)
+		w.Header().Set("ETag", v.etag)
+
+		if len(r.Header.Get("x-amz-copy-source")) > 0 {
+			fmt.Fprint(w, `<CopyObjectResult></CopyObjectResult>`)
+		}
+	case http.MethodDelete:
+		if index >= 0 {
+			f.versions[key] = append(versions[:index], versions[index+1:]...)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		if index < 0 {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code></Error>`)
+			return
+		}
+
+		v := versions[index]
+
+		if query["tagging"] != nil {
+			fmt.Fprint(w, `<Tagging><TagSet>`)
+			for k := range v.tags {
+				fmt.Fprintf(w, `<Tag><Key>%s</Key><Value>%s</Value></Tag>`, k, v.tags.Get(k))
+			}
+			fmt.Fprint(w, `</TagSet></Tagging>`)
+			return
+		}
+
+		w.Header().Set("Last-Modified", v.modified.Format(http.TimeFormat))
+		w.Header().Set("x-amz-version-id", v.id)
+		w.Header().Set("ETag", v.etag)
+		w.Header().Set("Content-Length", strconv.Itoa(len(v.data)))
+
+		if r.Method == http.MethodGet {
+			f.gets++
+			w.Write(v.data)
+		}
+	}
+}
+
+func setupS3Store(t *testing.T) (*S3Store, *catalog.File, *fakeS3, func()) {
+	fake := &fakeS3{versions: map[string][]fakeS3Version{}}
+	sess, cleanup := fakeAWSSession(t, fake)
+
+	file := testFile(map[string]string{
+		awsBucketSetting: "cstore-test",
+	})
+
+	s := &S3Store{
+		Session: sess,
+		clog:    testCatalog(t),
+		bucket: setting.Setting{
+			Prop:   awsBucketSetting,
+			Silent: true,
+			Vault:  file,
+		},
+	}
+	s.uo.Silent = true
+
+	return s, file, fake, cleanup
+}
+
+func TestEnsureS3ObjectVersionsAreRetrievedByLabelAndID(t *testing.T) {
+	// arrange
+	s, file, _, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	pushes := []struct {
+		version string
+		data    string
+	}{
+		{"", "ENV=working"},
+		{"v1.0.0", "ENV=v1"},
+		{"", "ENV=latest"},
+	}
+
+	// act
+	for _, p := range pushes {
+		if err := s.Push(file, []byte(p.data), p.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	if !objectVersioning(*file) {
+		t.Fatalf("\nEXPECTED: %s true \nACTUAL: %s", awsS3ObjectVersioning, file.Data[awsS3ObjectVersioning])
+	}
+
+	expected := map[string]string{
+		"":       "ENV=latest",
+		"v1.0.0": "ENV=v1",
+		"id1":    "ENV=working",
+	}
+
+	for version, data := range expected {
+		b, _, err := s.Pull(file, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != data {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", data, string(b))
+		}
+	}
+
+	delete(file.Data, awsS3VersionIDPrefix+"v1.0.0")
+
+	if b, _, err := s.Pull(file, "v1.0.0"); err != nil {
+		t.Error(err)
+	} else if string(b) != "ENV=v1" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ENV=v1", string(b))
+	}
+}
+
+func TestEnsureS3AttributesAreReadWithoutRetrievingTheFile(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+
+	if err := s.Push(file, []byte("ENV=dev"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	attr, err := Stat(s, file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.gets > 0 {
+		t.Errorf("\nEXPECTED: %d object reads \nACTUAL: %d", 0, fake.gets)
+	}
+
+	_, pulled, err := s.Pull(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attr.Revision != pulled.Revision || attr.Size != pulled.Size || attr.Modified.IsZero() {
+		t.Errorf("\nEXPECTED: %+v \nACTUAL: %+v", pulled, attr)
+	}
+}
+
+func TestEnsureS3RollbackRestoresPriorVersion(t *testing.T) {
+	// arrange
+	s, file, _, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	for _, data := range []string{"ENV=good", "ENV=bad"} {
+		if err := s.Push(file, []byte(data), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revisions, err := s.History(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(revisions) != 2 || !revisions[0].Latest {
+		t.Fatalf("\nEXPECTED: 2 revisions, newest first \nACTUAL: %v", revisions)
+	}
+
+	// act
+	if err := s.Rollback(file, revisions[1].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	b, _, err := s.Pull(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "ENV=good" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ENV=good", string(b))
+	}
+
+	if changed, err := s.Changed(file, b, ""); err != nil {
+		t.Error(err)
+	} else if changed.IsZero() {
+		t.Errorf("\nEXPECTED: changed time \nACTUAL: zero time")
+	}
+}
+
+func TestEnsureS3PrefixVersionsAreKeptForVersionedFiles(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+
+	file.Versions = []string{"v1.0.0"}
+	fake.versions[fmt.Sprintf("%s/v1.0.0/%s", t.Name(), file.Path)] = []fakeS3Version{{id: "id0", data: []byte("ENV=v1")}}
+
+	file.Versions = append(file.Versions, "v2.0.0")
+
+	// act
+	if err := s.Push(file, []byte("ENV=v2"), "v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	key := fmt.Sprintf("%s/v2.0.0/%s", t.Name(), file.Path)
+	if _, found := fake.versions[key]; !found {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", key, fake.versions)
+	}
+
+	if objectVersioning(*file) {
+		t.Errorf("\nEXPECTED: %s false \nACTUAL: true", awsS3ObjectVersioning)
+	}
+}
+
+func TestEnsureS3ObjectVersioningIsUsedForFirstVersionedPush(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	// push lists the version in the catalog before the store push
+	file.Versions = []string{"v1.0.0"}
+
+	// act
+	if err := s.Push(file, []byte("ENV=v1"), "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if file.Data[awsS3ObjectVersioning] != "true" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "true", file.Data[awsS3ObjectVersioning])
+	}
+
+	key := fmt.Sprintf("%s/%s", t.Name(), file.Path)
+	if _, found := fake.versions[key]; !found {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", key, fake.versions)
+	}
+
+	if b, _, err := s.Pull(file, "v1.0.0"); err != nil || string(b) != "ENV=v1" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "ENV=v1", string(b), err)
+	}
+}
+
+func TestEnsureS3ObjectVersioningIsNotUsedWithoutOptIn(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+
+	file.Versions = []string{"v1.0.0"}
+
+	// act
+	if err := s.Push(file, []byte("ENV=v1"), "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if file.Data[awsS3ObjectVersioning] != "false" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "false", file.Data[awsS3ObjectVersioning])
+	}
+
+	key := fmt.Sprintf("%s/v1.0.0/%s", t.Name(), file.Path)
+	if _, found := fake.versions[key]; !found {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", key, fake.versions)
+	}
+}
+
+func TestEnsureS3StoredFilesKeepKeyPrefixes(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	working := fmt.Sprintf("%s/%s", t.Name(), file.Path)
+	fake.versions[working] = []fakeS3Version{{id: "id0", data: []byte("ENV=working")}}
+
+	file.Versions = []string{"v1.0.0"}
+
+	// act
+	if err := s.Push(file, []byte("ENV=v1"), "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if objectVersioning(*file) {
+		t.Errorf("\nEXPECTED: %s false \nACTUAL: true", awsS3ObjectVersioning)
+	}
+
+	if b, _, err := s.Pull(file, ""); err != nil || string(b) != "ENV=working" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "ENV=working", string(b), err)
+	}
+}
+
+func TestEnsureS3PushWorksWithoutBucketVersioningPermission(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	fake.denyVersioning = true
+
+	// act
+	err := s.Push(file, []byte("ENV=dev"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if objectVersioning(*file) {
+		t.Errorf("\nEXPECTED: %s false \nACTUAL: true", awsS3ObjectVersioning)
+	}
+}
+
+func TestEnsureS3CurrentObjectVersionIsNotPurged(t *testing.T) {
+	// arrange
+	s, file, _, cleanup := setupS3Store(t)
+	defer cleanup()
+	defer setenv(map[string]string{awsS3ObjectVersioning: "true"})()
+
+	file.Versions = []string{"v1.0.0"}
+
+	for _, version := range []string{"", "v1.0.0"} {
+		if err := s.Push(file, []byte("ENV="+version), version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	err := s.Purge(file, "v1.0.0")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "current version error", err)
+	}
+
+	if b, _, err := s.Pull(file, ""); err != nil || string(b) != "ENV=v1.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "ENV=v1.0.0", string(b), err)
+	}
+
+	if err := s.Purge(file, "id1"); err != nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "older version purged", err)
+	}
+}
+
+func TestEnsureS3PushIsRejectedWhenRevisionChanged(t *testing.T) {
+	// arrange
+	s, file, _, cleanup := setupS3Store(t)
+	defer cleanup()
+
+	revision, err := s.PushIf(file, []byte("ENV=first"), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, attr, err := s.Pull(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if attr.Revision != revision {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", revision, attr.Revision)
+	}
+
+	if _, err := s.PushIf(file, []byte("ENV=other-user"), "", revision); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, err = s.PushIf(file, []byte("ENV=stale"), "", revision)
+
+	// assert
+	if err != contract.ErrRevisionConflict {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrRevisionConflict, err)
+	}
+
+	if b, _, _ := s.Pull(file, ""); string(b) != "ENV=other-user" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "ENV=other-user", string(b))
+	}
+}
+
+// preS3Store prepares an S3 store using the endpoint settings saved with
+// the file and credentials from the environment.
+func preS3Store(t *testing.T, data map[string]string) (*S3Store, *catalog.File, error) {
+	defer setenv(map[string]string{
+		awsRegion:          awsDefaultRegion,
+		awsAccessKeyID:     "id",
+		awsSecretAccessKey: "secret",
+	})()
+
+	data[awsBucketSetting] = "cstore-test"
+
+	s := &S3Store{}
+	file, err := preStore(t, s, data)
+
+	return s, file, err
+}
+
+func TestEnsureS3CompatibleEndpointIsVerifiedWithCABundle(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(&fakeS3{versions: map[string][]fakeS3Version{}})
+	defer server.Close()
+
+	bundle, err := ioutil.TempFile("", "ca-*.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bundle.Name())
+
+	if err := pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}); err != nil {
+		t.Fatal(err)
+	}
+	bundle.Close()
+
+	defer setenv(map[string]string{awsS3CABundleSetting: bundle.Name()})()
+
+	s, file, err := preS3Store(t, map[string]string{
+		awsS3EndpointSetting:       server.URL,
+		awsS3ForcePathStyleSetting: "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err = s.Push(file, []byte("ENV=dev"), "")
+
+	// assert
+	if err != nil {
+		t.Fatalf("\nEXPECTED: %v \nACTUAL: %s", nil, err)
+	}
+
+	if b, _, err := s.Pull(file, ""); err != nil || string(b) != "ENV=dev" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "ENV=dev", string(b), err)
+	}
+}
+
+func TestEnsureS3CompatibleEndpointCertificateCanBeSkipped(t *testing.T) {
+	// arrange
+	server := httptest.NewTLSServer(&fakeS3{versions: map[string][]fakeS3Version{}})
+	defer server.Close()
+
+	verified, file, err := preS3Store(t, map[string]string{
+		awsS3EndpointSetting:       server.URL,
+		awsS3ForcePathStyleSetting: "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := verified.Push(file, []byte("ENV=dev"), ""); err == nil {
+		t.Fatalf("\nEXPECTED: %s \nACTUAL: %v", "certificate error", err)
+	}
+
+	defer setenv(map[string]string{awsS3InsecureSkipVerifySetting: "true"})()
+
+	s, file, err := preS3Store(t, map[string]string{
+		awsS3EndpointSetting:       server.URL,
+		awsS3ForcePathStyleSetting: "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err = s.Push(file, []byte("ENV=dev"), "")
+
+	// assert
+	if err != nil {
+		t.Errorf("\nEXPECTED: %v \nACTUAL: %s", nil, err)
+	}
+}
+
+func TestEnsureS3EndpointSettingsArePersistedFromEnvironment(t *testing.T) {
+	// arrange
+	defer setenv(map[string]string{
+		awsS3EndpointSetting:           "http://127.0.0.1:9000",
+		awsS3InsecureSkipVerifySetting: "true",
+	})()
+
+	// act
+	_, file, err := preS3Store(t, map[string]string{
+		awsS3CABundleSetting: "/saved/by/an/older/version.pem",
+	})
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if file.Data[awsS3EndpointSetting] != "http://127.0.0.1:9000" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "http://127.0.0.1:9000", file.Data[awsS3EndpointSetting])
+	}
+
+	for _, prop := range []string{awsS3InsecureSkipVerifySetting, awsS3CABundleSetting} {
+		if _, found := file.Data[prop]; found {
+			t.Errorf("\nEXPECTED: %s not saved \nACTUAL: %s", prop, file.Data[prop])
+		}
+	}
+}
@@ -8,12 +8,12 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/turnerlabs/cstore/v4/components/awssession"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/contract"
@@ -21,7 +21,6 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/setting"
-	"github.com/turnerlabs/cstore/v4/components/vault"
 )
 
 // AWSSecretManagerStore ...
@@ -39,23 +38,10 @@ func (s AWSSecretManagerStore) Name() string {
 	return "aws-secret"
 }
 
-// SupportsFeature ...
-func (s AWSSecretManagerStore) SupportsFeature(feature string) bool {
-	switch feature {
-	case VersionFeature:
-		return false
-	default:
-		return false
-	}
-}
-
-// SupportsFileType ...
-func (s AWSSecretManagerStore) SupportsFileType(fileType string) bool {
-	switch fileType {
-	case EnvFeature, JSONFeature:
-		return true
-	default:
-		return false
+// Capabilities ...
+func (s AWSSecretManagerStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		FileTypes: []string{EnvFeature, JSONFeature},
 	}
 }
 
@@ -73,80 +59,10 @@ func (s *AWSSecretManagerStore) Pre(clog catalog.Catalog, file *catalog.File, ac
 	s.io = io
 
 	//------------------------------------------
-	//- Get AWS Region
-	//------------------------------------------
-	region, err := setting.Setting{
-		Description:  "Export as an environment variable to silence this prompt.",
-		Group:        clog.Context,
-		Prop:         awsRegion,
-		Prompt:       uo.Prompt,
-		Silent:       uo.Silent,
-		AutoSave:     true,
-		PromptOnce:   true,
-		DefaultValue: awsDefaultRegion,
-		Vault:        vault.EnvVault{},
-	}.Get(clog.Context, io)
-
-	//------------------------------------------
-	//- Get AWS Credentials from Environment
-	//------------------------------------------
-	if _, ok := access.(vault.EnvVault); ok {
-		s.Session, err = session.NewSession(&aws.Config{
-			Region: aws.String(region),
-		})
-
-		return err
-	}
-
-	//------------------------------------------
-	//- Get AWS Credentials from Vault
+	//- Get AWS Session
 	//------------------------------------------
-	id, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsAccessKeyID,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	secret, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSecretAccessKey,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	token, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSessionToken,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	s.Session, err = session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(id, secret, token),
-	})
+	sess, err := awssession.Get(clog, file, access, uo, io)
+	s.Session = sess
 
 	return err
 }
@@ -183,13 +99,18 @@ func (s AWSSecretManagerStore) Push(file *catalog.File, fileData []byte, version
 		KMSKeyID.awsInputValue = ""
 	}
 
+	tags, err := resourceTags(s.clog, file, s.uo, s.io)
+	if err != nil {
+		return err
+	}
+
 	//------------------------------------------
 	//- Push configuration
 	//------------------------------------------
-	return s.pushBlob(file, fileData, KMSKeyID)
+	return s.pushBlob(file, fileData, KMSKeyID, tags)
 }
 
-func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMSKeyID kmsKeyID) error {
+func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMSKeyID kmsKeyID, tags map[string]string) error {
 
 	switch file.Type {
 	case "json":
@@ -213,6 +134,29 @@ func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMS
 		SecretId: aws.String(key),
 	})
 
+	//------------------------------------------
+	//- Files larger than a secret are split
+	//- into chunk secrets and the file secret
+	//- holds a manifest.
+	//------------------------------------------
+	previous := chunkManifest{}
+	if err == nil {
+		previous, _ = parseChunkManifest(aws.StringValue(sv.SecretString))
+	}
+
+	chunks := []string{}
+
+	if len(fileData) > secretMaxSize {
+		chunks = splitChunks(string(fileData), secretMaxSize)
+		fileData = []byte(newChunkManifest(string(fileData), len(chunks)))
+	}
+
+	for i, chunk := range chunks {
+		if err := putSecretChunk(svc, chunkKey(key, i), chunk, KMSKeyID, tags, s.io); err != nil {
+			return err
+		}
+	}
+
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			if aerr.Code() == secretsmanager.ErrCodeInvalidRequestException {
@@ -226,16 +170,13 @@ func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMS
 				return nil
 			} else if aerr.Code() == secretsmanager.ErrCodeResourceNotFoundException {
 
-				if _, err = svc.CreateSecret(&secretsmanager.CreateSecretInput{
+				return createSecret(svc, &secretsmanager.CreateSecretInput{
 					Name:         aws.String(key),
 					SecretString: aws.String(string(fileData)),
 					Description:  aws.String("cStore"),
 					KmsKeyId:     aws.String(KMSKeyID.awsInputValue),
-				}); err != nil {
-					return err
-				}
-
-				return nil
+					Tags:         secretTags(tags),
+				}, s.io)
 			}
 		}
 
@@ -247,6 +188,10 @@ func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMS
 		return err
 	}
 
+	if err := reconcileSecretTags(svc, key, sd.tags, tags); err != nil {
+		warnTags(key, err, s.io)
+	}
+
 	if !bytes.Equal([]byte(*sv.SecretString), fileData) || (KMSKeyID.value != "" && sd.keyID != KMSKeyID.value) {
 
 		if _, err := svc.UpdateSecret(&secretsmanager.UpdateSecretInput{
@@ -258,6 +203,8 @@ func (s AWSSecretManagerStore) pushBlob(file *catalog.File, fileData []byte, KMS
 			return err
 		}
 
+		deleteSecretChunks(svc, key, len(chunks), previous.Chunks)
+
 		return nil
 	}
 
@@ -279,16 +226,40 @@ func (s AWSSecretManagerStore) Pull(file *catalog.File, version string) ([]byte,
 		return []byte{}, contract.Attributes{}, err
 	}
 
-	switch file.Type {
-	case "json":
-		return []byte(*sv.SecretString), contract.Attributes{}, err
-	case "env":
-		envFormat, err := convert.ToENVFileFormat([]byte(*sv.SecretString))
+	b := []byte(*sv.SecretString)
 
-		return envFormat.Bytes(), contract.Attributes{}, err
-	default:
-		return []byte(*sv.SecretString), contract.Attributes{}, err
+	if m, chunked := parseChunkManifest(string(b)); chunked {
+		value, err := getSecretChunks(svc, key, m)
+		if err != nil {
+			return []byte{}, contract.Attributes{}, err
+		}
+
+		b = []byte(value)
 	}
+
+	if file.Type == "env" {
+		envFormat, err := convert.ToENVFileFormat(b)
+		if err != nil {
+			return []byte{}, contract.Attributes{}, err
+		}
+
+		b = envFormat.Bytes()
+	}
+
+	attr := attributes(b)
+	attr.Modified = aws.TimeValue(sv.CreatedDate)
+	attr.Revision = aws.StringValue(sv.VersionId)
+
+	sd, err := describeSecret(key, svc)
+	if err != nil {
+		display.Warn(fmt.Errorf("KMS key of %s is unavailable (%s)", key, err), s.io.UserOutput)
+		return b, attr, nil
+	}
+
+	attr.Modified = sd.lastModified
+	attr.KMSKeyID = sd.keyID
+
+	return b, attr, nil
 }
 
 // Purge ...
@@ -298,6 +269,20 @@ func (s AWSSecretManagerStore) Purge(file *catalog.File, version string) error {
 
 	key := fmt.Sprintf("%s/%s", s.clog.Context, file.ActualPath())
 
+	if sv, err := svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(key),
+	}); err == nil {
+		if m, chunked := parseChunkManifest(aws.StringValue(sv.SecretString)); chunked {
+			for i := 0; i < m.Chunks; i++ {
+				if _, err := svc.DeleteSecret(&secretsmanager.DeleteSecretInput{
+					SecretId: aws.String(chunkKey(key, i)),
+				}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	if _, err := svc.DeleteSecret(&secretsmanager.DeleteSecretInput{
 		SecretId: aws.String(key),
 	}); err != nil {
@@ -307,6 +292,21 @@ func (s AWSSecretManagerStore) Purge(file *catalog.File, version string) error {
 	return nil
 }
 
+// Stat ...
+func (s AWSSecretManagerStore) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+
+	sd, err := describeSecret(fmt.Sprintf("%s/%s", s.clog.Context, file.ActualPath()), secretsmanager.New(s.Session))
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	return contract.Attributes{
+		Modified: sd.lastModified,
+		Revision: sd.versionID,
+		KMSKeyID: sd.keyID,
+	}, nil
+}
+
 // Changed ...
 func (s AWSSecretManagerStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
 	svc := secretsmanager.New(s.Session)
@@ -321,6 +321,113 @@ func (s AWSSecretManagerStore) Changed(file *catalog.File, fileData []byte, vers
 	return secret.lastModified, nil
 }
 
+// Revision ...
+func (s AWSSecretManagerStore) Revision(file *catalog.File, version string) (string, error) {
+	svc := secretsmanager.New(s.Session)
+
+	key := fmt.Sprintf("%s/%s", s.clog.Context, file.ActualPath())
+
+	secret, err := describeSecret(key, svc)
+	if err != nil {
+		if err.Error() == contract.ErrSecretNotFound.Error() {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return secret.versionID, nil
+}
+
+func chunkKey(key string, index int) string {
+	return fmt.Sprintf("%s/chunks/%d", key, index)
+}
+
+func putSecretChunk(svc *secretsmanager.SecretsManager, key, value string, KMSKeyID kmsKeyID, tags map[string]string, io models.IO) error {
+	sv, err := svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(key),
+	})
+
+	if err != nil {
+		aerr, ok := err.(awserr.Error)
+		if !ok {
+			return err
+		}
+
+		switch aerr.Code() {
+		case secretsmanager.ErrCodeResourceNotFoundException:
+			return createSecret(svc, &secretsmanager.CreateSecretInput{
+				Name:         aws.String(key),
+				SecretString: aws.String(value),
+				Description:  aws.String("cStore chunk"),
+				KmsKeyId:     aws.String(KMSKeyID.awsInputValue),
+				Tags:         secretTags(tags),
+			}, io)
+		case secretsmanager.ErrCodeInvalidRequestException:
+			if _, err := svc.RestoreSecret(&secretsmanager.RestoreSecretInput{
+				SecretId: aws.String(key),
+			}); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+
+	if sv == nil || aws.StringValue(sv.SecretString) != value {
+		if _, err := svc.UpdateSecret(&secretsmanager.UpdateSecretInput{
+			SecretId:     aws.String(key),
+			SecretString: aws.String(value),
+			Description:  aws.String("cStore chunk"),
+			KmsKeyId:     aws.String(KMSKeyID.awsInputValue),
+		}); err != nil {
+			return err
+		}
+	}
+
+	sd, err := describeSecret(key, svc)
+	if err != nil {
+		return err
+	}
+
+	if err := reconcileSecretTags(svc, key, sd.tags, tags); err != nil {
+		warnTags(key, err, io)
+	}
+
+	return nil
+}
+
+func getSecretChunks(svc *secretsmanager.SecretsManager, key string, m chunkManifest) (string, error) {
+	chunks := []string{}
+
+	for i := 0; i < m.Chunks; i++ {
+		sv, err := svc.GetSecretValue(&secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(chunkKey(key, i)),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to get chunk %d of %s (%s)", i, key, err)
+		}
+
+		chunks = append(chunks, aws.StringValue(sv.SecretString))
+	}
+
+	return m.join(chunks)
+}
+
+//------------------------------------------
+//- Chunks no longer in the manifest are
+//- removed immediately, so their names can
+//- be reused by the next push.
+//------------------------------------------
+func deleteSecretChunks(svc *secretsmanager.SecretsManager, key string, from, to int) {
+	for i := from; i < to; i++ {
+		svc.DeleteSecret(&secretsmanager.DeleteSecretInput{
+			SecretId:                   aws.String(chunkKey(key, i)),
+			ForceDeleteWithoutRecovery: aws.Bool(true),
+		})
+	}
+}
+
 func init() {
 	s := new(AWSSecretManagerStore)
 	stores[s.Name()] = s
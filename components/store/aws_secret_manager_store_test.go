@@ -0,0 +1,271 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// fakeSecretsManager is a minimal stand-in for Secrets Manager that
+// enforces the secret size limit.
+type fakeSecretsManager struct {
+	sync.Mutex
+	secrets map[string]string
+	tags    map[string]map[string]string
+	denied  map[string]bool
+	next    int
+}
+
+type fakeTag struct {
+	Key   string
+	Value string
+}
+
+func (f *fakeSecretsManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	input := struct {
+		Name         string
+		SecretId     string
+		SecretString string
+		Tags         []fakeTag
+		TagKeys      []string
+	}{}
+
+	json.NewDecoder(r.Body).Decode(&input)
+
+	id := input.SecretId
+	if len(id) == 0 {
+		id = input.Name
+	}
+
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"__type":"%s","message":"%s"}`, code, id)
+	}
+
+	action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager.")
+
+	if f.denied[action] {
+		fail("AccessDeniedException")
+		return
+	}
+
+	value, found := f.secrets[id]
+	if !found && action != "CreateSecret" {
+		fail("ResourceNotFoundException")
+		return
+	}
+
+	switch action {
+	case "CreateSecret", "UpdateSecret":
+		if len(input.Tags) > 0 && f.denied["TagResource"] {
+			fail("AccessDeniedException")
+			return
+		}
+
+		if len(input.SecretString) > secretMaxSize {
+			fail("InvalidParameterException")
+			return
+		}
+
+		f.next++
+		f.secrets[id] = input.SecretString
+
+		if action == "CreateSecret" {
+			f.tags[id] = map[string]string{}
+			for _, tag := range input.Tags {
+				f.tags[id][tag.Key] = tag.Value
+			}
+		}
+
+		fmt.Fprintf(w, `{"Name":"%s","VersionId":"v%d"}`, id, f.next)
+	case "GetSecretValue":
+		b, _ := json.Marshal(map[string]string{
+			"Name":         id,
+			"SecretString": value,
+			"VersionId":    fmt.Sprintf("v%d", f.next),
+		})
+
+		w.Write(b)
+	case "DescribeSecret":
+		tags := []fakeTag{}
+		for key, value := range f.tags[id] {
+			tags = append(tags, fakeTag{Key: key, Value: value})
+		}
+
+		b, _ := json.Marshal(map[string]interface{}{
+			"Name":               id,
+			"LastChangedDate":    time.Now().Unix(),
+			"VersionIdsToStages": map[string][]string{fmt.Sprintf("v%d", f.next): {"AWSCURRENT"}},
+			"Tags":               tags,
+		})
+
+		w.Write(b)
+	case "TagResource":
+		for _, tag := range input.Tags {
+			f.tags[id][tag.Key] = tag.Value
+		}
+
+		fmt.Fprint(w, `{}`)
+	case "UntagResource":
+		for _, key := range input.TagKeys {
+			delete(f.tags[id], key)
+		}
+
+		fmt.Fprint(w, `{}`)
+	case "DeleteSecret":
+		delete(f.secrets, id)
+		delete(f.tags, id)
+
+		fmt.Fprintf(w, `{"Name":"%s"}`, id)
+	default:
+		fail("InvalidRequestException")
+	}
+}
+
+func setupSecretManagerStore(t *testing.T) (*AWSSecretManagerStore, *fakeSecretsManager, func()) {
+	fake := &fakeSecretsManager{secrets: map[string]string{}, tags: map[string]map[string]string{}, denied: map[string]bool{}}
+	sess, cleanup := fakeAWSSession(t, fake)
+
+	s := &AWSSecretManagerStore{
+		Session: sess,
+		clog:    testCatalog(t),
+		io:      models.IO{UserOutput: &bytes.Buffer{}},
+	}
+	s.uo.Silent = true
+
+	return s, fake, cleanup
+}
+
+func largeJSON(size int) []byte {
+	values := map[string]string{}
+
+	for i := 0; len(values)*40 < size; i++ {
+		values[fmt.Sprintf("KEY_%05d", i)] = strings.Repeat("é", 12)
+	}
+
+	b, _ := json.Marshal(values)
+
+	return b
+}
+
+func TestEnsureLargeSecretIsChunkedAndReassembled(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{}}
+	data := largeJSON(secretMaxSize * 2)
+
+	// act
+	if err := s.Push(file, data, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _, err := s.Pull(file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != string(data) {
+		t.Errorf("\nEXPECTED: %d bytes \nACTUAL: %d bytes", len(data), len(b))
+	}
+
+	if len(fake.secrets) < 3 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %d secrets", "manifest and chunks", len(fake.secrets))
+	}
+}
+
+func TestEnsureStaleSecretChunksAreRemoved(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{}}
+
+	if err := s.Push(file, largeJSON(secretMaxSize*2), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := s.Push(file, []byte(`{"ENV":"dev"}`), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.secrets) != 1 {
+		t.Errorf("\nEXPECTED: %d secret \nACTUAL: %d secrets", 1, len(fake.secrets))
+	}
+
+	b, _, err := s.Pull(file, "")
+	if err != nil || string(b) != `{"ENV":"dev"}` {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", `{"ENV":"dev"}`, string(b), err)
+	}
+}
+
+func TestEnsureChangedSecretChunkFailsChecksum(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{}}
+
+	if err := s.Push(file, largeJSON(secretMaxSize*2), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	key := chunkKey(fmt.Sprintf("%s/%s", t.Name(), file.Path), 0)
+	fake.secrets[key] = strings.Replace(fake.secrets[key], "KEY_", "KEY-", 1)
+
+	// act
+	_, _, err := s.Pull(file, "")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "checksum mismatch", err)
+	}
+}
+
+func TestEnsureSecretIsPulledWithoutDescribePermission(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{}}
+
+	if err := s.Push(file, []byte(`{"ENV":"dev"}`), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.denied["DescribeSecret"] = true
+
+	// act
+	b, attr, err := s.Pull(file, "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != `{"ENV":"dev"}` {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", `{"ENV":"dev"}`, string(b))
+	}
+
+	if len(attr.Revision) == 0 || len(attr.KMSKeyID) > 0 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %+v", "revision without KMS key", attr)
+	}
+}
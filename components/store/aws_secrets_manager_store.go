@@ -5,23 +5,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/credentials"
 
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/subosito/gotenv"
+	"github.com/turnerlabs/cstore/v4/components/awssession"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/models"
 	"github.com/turnerlabs/cstore/v4/components/setting"
-	"github.com/turnerlabs/cstore/v4/components/vault"
 )
 
 // AWSSecretsManagerStore ...
@@ -39,23 +40,10 @@ func (s AWSSecretsManagerStore) Name() string {
 	return "aws-secrets"
 }
 
-// SupportsFeature ...
-func (s AWSSecretsManagerStore) SupportsFeature(feature string) bool {
-	switch feature {
-	case VersionFeature:
-		return false
-	default:
-		return false
-	}
-}
-
-// SupportsFileType ...
-func (s AWSSecretsManagerStore) SupportsFileType(fileType string) bool {
-	switch fileType {
-	case EnvFeature, JSONFeature:
-		return true
-	default:
-		return false
+// Capabilities ...
+func (s AWSSecretsManagerStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		FileTypes: []string{EnvFeature, JSONFeature},
 	}
 }
 
@@ -73,80 +61,10 @@ func (s *AWSSecretsManagerStore) Pre(clog catalog.Catalog, file *catalog.File, a
 	s.io = io
 
 	//------------------------------------------
-	//- Get AWS Region
-	//------------------------------------------
-	region, err := setting.Setting{
-		Description:  "Export as an environment variable to silence this prompt.",
-		Group:        clog.Context,
-		Prop:         awsRegion,
-		Prompt:       uo.Prompt,
-		Silent:       uo.Silent,
-		AutoSave:     true,
-		PromptOnce:   true,
-		DefaultValue: awsDefaultRegion,
-		Vault:        vault.EnvVault{},
-	}.Get(clog.Context, io)
-
-	//------------------------------------------
-	//- Get AWS Credentials from Environment
-	//------------------------------------------
-	if _, ok := access.(vault.EnvVault); ok {
-		s.Session, err = session.NewSession(&aws.Config{
-			Region: aws.String(region),
-		})
-
-		return err
-	}
-
-	//------------------------------------------
-	//- Get AWS Credentials from Vault
+	//- Get AWS Session
 	//------------------------------------------
-	id, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsAccessKeyID,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	secret, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSecretAccessKey,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	token, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSessionToken,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	s.Session, err = session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(id, secret, token),
-	})
+	sess, err := awssession.Get(clog, file, access, uo, io)
+	s.Session = sess
 
 	return err
 }
@@ -183,13 +101,18 @@ func (s AWSSecretsManagerStore) Push(file *catalog.File, fileData []byte, versio
 		KMSKeyID.awsInputValue = ""
 	}
 
+	tags, err := resourceTags(s.clog, file, s.uo, s.io)
+	if err != nil {
+		return err
+	}
+
 	//------------------------------------------
 	//- Push configuration
 	//------------------------------------------
-	return s.pushFile(file, fileData, KMSKeyID)
+	return s.pushFile(file, fileData, KMSKeyID, tags)
 }
 
-func (s AWSSecretsManagerStore) pushFile(file *catalog.File, fileData []byte, KMSKeyID kmsKeyID) error {
+func (s AWSSecretsManagerStore) pushFile(file *catalog.File, fileData []byte, KMSKeyID kmsKeyID, tags map[string]string) error {
 	params := map[string]string{}
 
 	switch file.Type {
@@ -298,9 +221,10 @@ func (s AWSSecretsManagerStore) pushFile(file *catalog.File, fileData []byte, KM
 					SecretString: aws.String(value),
 					Description:  aws.String("cStore"),
 					KmsKeyId:     aws.String(KMSKeyID.awsInputValue),
+					Tags:         secretTags(tags),
 				}
 
-				if _, err = svc.CreateSecret(input); err != nil {
+				if err = createSecret(svc, input, s.io); err != nil {
 					return err
 				}
 
@@ -335,6 +259,10 @@ func (s AWSSecretsManagerStore) pushFile(file *catalog.File, fileData []byte, KM
 			}
 		}
 
+		if err := reconcileSecretTags(svc, key, storedSecret.tags, tags); err != nil {
+			warnTags(key, err, s.io)
+		}
+
 		file.AddData(map[string]string{
 			name: "SECRET",
 		})
@@ -414,6 +342,19 @@ func describeSecret(key string, svc *secretsmanager.SecretsManager) (secret, err
 		s.keyID = *o.KmsKeyId
 	}
 
+	s.tags = map[string]string{}
+	for _, tag := range o.Tags {
+		s.tags[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	for id, stages := range o.VersionIdsToStages {
+		for _, stage := range stages {
+			if aws.StringValue(stage) == "AWSCURRENT" {
+				s.versionID = id
+			}
+		}
+	}
+
 	return s, nil
 }
 
@@ -439,15 +380,56 @@ func getSecret(key string, svc *secretsmanager.SecretsManager) (string, error) {
 // Pull ...
 func (s AWSSecretsManagerStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
 
+	b, err := s.pull(file)
+	if err != nil {
+		return b, contract.Attributes{}, err
+	}
+
+	attr, err := s.Stat(file, version)
+	if err != nil {
+		display.Warn(fmt.Errorf("modified time, revision and KMS key of %s are unavailable (%s)", file.ActualPath(), err), s.io.UserOutput)
+	}
+
+	pulled := attributes(b)
+	attr.Size = pulled.Size
+	attr.Checksum = pulled.Checksum
+
+	return b, attr, nil
+}
+
+// Stat ...
+func (s AWSSecretsManagerStore) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+
+	secrets, err := s.describe(file)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	attr := contract.Attributes{
+		Modified: lastModifiedSecret(secrets),
+		Revision: secretsRevision(secrets),
+	}
+
+	for _, sd := range secrets {
+		if sd.lastModified.Equal(attr.Modified) {
+			attr.KMSKeyID = sd.keyID
+		}
+	}
+
+	return attr, nil
+}
+
+func (s AWSSecretsManagerStore) pull(file *catalog.File) ([]byte, error) {
+
 	svc := secretsmanager.New(s.Session)
 
 	storedSecrets, err := getSecrets(s.clog.Context, file.ActualPath(), file.Data, svc)
 	if err != nil {
-		return []byte{}, contract.Attributes{}, err
+		return []byte{}, err
 	}
 
 	if len(storedSecrets) == 0 {
-		return []byte{}, contract.Attributes{}, errors.New("SecretsNotFound: verify correct AWS account and credentials")
+		return []byte{}, errors.New("SecretsNotFound: verify correct AWS account and credentials")
 	}
 
 	switch file.Type {
@@ -468,25 +450,25 @@ func (s AWSSecretsManagerStore) Pull(file *catalog.File, version string) ([]byte
 
 		b, err := json.MarshalIndent(props, "", "   ")
 		if err != nil {
-			return []byte{}, contract.Attributes{}, err
+			return []byte{}, err
 		}
 
-		return b, contract.Attributes{}, nil
+		return b, nil
 	case "env":
 		buffer := bytes.Buffer{}
 		for key, value := range storedSecrets {
 			temp := map[string]string{}
 
 			if err := json.Unmarshal([]byte(value), &temp); err != nil {
-				return []byte{}, contract.Attributes{}, err
+				return []byte{}, err
 			}
 
 			buffer.WriteString(fmt.Sprintf("%s=%s\n", key, temp[key]))
 		}
 
-		return buffer.Bytes(), contract.Attributes{}, nil
+		return buffer.Bytes(), nil
 	default:
-		return []byte{}, contract.Attributes{}, fmt.Errorf("store does not support file type: %s", file.Type)
+		return []byte{}, fmt.Errorf("store does not support file type: %s", file.Type)
 	}
 }
 
@@ -539,6 +521,65 @@ func (s AWSSecretsManagerStore) Changed(file *catalog.File, fileData []byte, ver
 	return lastModifiedSecret(storedSecretMetaData), nil
 }
 
+// Revision ...
+func (s AWSSecretsManagerStore) Revision(file *catalog.File, version string) (string, error) {
+
+	secrets, err := s.describe(file)
+	if err != nil {
+		return "", err
+	}
+
+	return secretsRevision(secrets), nil
+}
+
+//------------------------------------------
+//- Describe the secrets stored for a file
+//- skipping secrets that were not found.
+//------------------------------------------
+func (s AWSSecretsManagerStore) describe(file *catalog.File) ([]secret, error) {
+	svc := secretsmanager.New(s.Session)
+
+	secrets := []secret{}
+	for name, value := range file.Data {
+		if value != "SECRET" {
+			continue
+		}
+
+		sd, err := describeSecret(formatSecretToken(s.clog.Context, file.ActualPath(), name), svc)
+		if err != nil {
+			if err.Error() == contract.ErrSecretNotFound.Error() {
+				continue
+			}
+
+			return secrets, err
+		}
+
+		secrets = append(secrets, sd)
+	}
+
+	return secrets, nil
+}
+
+//------------------------------------------
+//- Secrets are versioned individually, so
+//- the file revision identifies the version
+//- of every secret.
+//------------------------------------------
+func secretsRevision(secrets []secret) string {
+	if len(secrets) == 0 {
+		return ""
+	}
+
+	versions := []string{}
+	for _, sd := range secrets {
+		versions = append(versions, fmt.Sprintf("%s:%s", sd.name, sd.versionID))
+	}
+
+	sort.Strings(versions)
+
+	return revisionOf([]byte(strings.Join(versions, "\n")))
+}
+
 func listSecrets(svc *secretsmanager.SecretsManager, startsWith string, nextToken string, secrets []*secretsmanager.SecretListEntry) ([]*secretsmanager.SecretListEntry, error) {
 
 	input := &secretsmanager.ListSecretsInput{}
@@ -0,0 +1,343 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	awsResourceTagsSetting = "AWS_RESOURCE_TAGS"
+	awsParamTagsSetting    = "AWS_PARAM_TAGS_CHECKSUM"
+
+	awsTagPrefix        = "cstore:"
+	awsContextTag       = "cstore:context"
+	awsPathTag          = "cstore:path"
+	awsCatalogTagsTag   = "cstore:tags"
+	awsCstoreVersionTag = "cstore:version"
+	awsManagedTagsTag   = "cstore:managed"
+
+	s3MaxTags = 10
+)
+
+//------------------------------------------
+//- AWS resources storing a file are tagged
+//- with the context, file path, catalog
+//- tags, cstore version, and user defined
+//- tags saved with the file.
+//------------------------------------------
+func resourceTags(clog catalog.Catalog, file *catalog.File, uo cfg.UserOptions, io models.IO) (map[string]string, error) {
+	value, found := file.Data[awsResourceTagsSetting]
+
+	if !found {
+		value = clog.GetDataByStore("", awsResourceTagsSetting, "")
+
+		if uo.Prompt && !uo.Silent {
+			v, err := setting.Setting{
+				Description:  "AWS resource tags applied to the file, like team=payments&cost-center=42. Leave blank for none.",
+				Prop:         awsResourceTagsSetting,
+				Prompt:       true,
+				AutoSave:     true,
+				DefaultValue: value,
+				Vault:        file,
+			}.Get(clog.Context, io)
+			if err != nil {
+				return nil, err
+			}
+
+			value = v
+		} else if len(value) > 0 {
+			file.AddData(map[string]string{
+				awsResourceTagsSetting: value,
+			})
+		}
+	}
+
+	user, err := url.ParseQuery(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", awsResourceTagsSetting, err)
+	}
+
+	tags := map[string]string{
+		awsContextTag:       clog.Context,
+		awsPathTag:          file.ActualPath(),
+		awsCstoreVersionTag: cfg.Version,
+	}
+
+	if len(file.Tags) > 0 {
+		tags[awsCatalogTagsTag] = strings.Join(file.Tags, " ")
+	}
+
+	keys := []string{}
+
+	for key, values := range user {
+		if strings.HasPrefix(key, awsTagPrefix) || strings.HasPrefix(strings.ToLower(key), "aws:") {
+			return nil, fmt.Errorf("invalid %s: %s is reserved", awsResourceTagsSetting, key)
+		}
+
+		tags[key] = values[0]
+		keys = append(keys, key)
+	}
+
+	//------------------------------------------
+	//- User defined keys are recorded, so tags
+	//- removed from the catalog are removed on
+	//- the next push.
+	//------------------------------------------
+	if len(keys) > 0 {
+		sort.Strings(keys)
+		tags[awsManagedTagsTag] = strings.Join(keys, " ")
+	}
+
+	return tags, nil
+}
+
+//------------------------------------------
+//- Tags are best effort, so a role without
+//- tag permissions can still push files.
+//------------------------------------------
+func tagsDenied(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+
+	switch aerr.Code() {
+	case "AccessDenied", "AccessDeniedException":
+		return true
+	default:
+		return false
+	}
+}
+
+func warnTags(name string, err error, io models.IO) {
+	display.Warn(fmt.Errorf("tags of %s were not updated (%s)", name, err), io.UserOutput)
+}
+
+// limitTags removes user defined tags until no more than max remain and
+// returns the keys removed. Tags set by cstore are kept.
+func limitTags(tags map[string]string, max int) []string {
+	user := []string{}
+
+	for key := range tags {
+		if !strings.HasPrefix(key, awsTagPrefix) && key != awsS3VersionTag {
+			user = append(user, key)
+		}
+	}
+
+	sort.Strings(user)
+
+	dropped := []string{}
+
+	for i := len(user) - 1; i >= 0 && len(tags) > max; i-- {
+		delete(tags, user[i])
+		dropped = append([]string{user[i]}, dropped...)
+	}
+
+	return dropped
+}
+
+// tagChanges returns the tags to set and the tag keys to remove, so the
+// current tags match the desired tags. Tags not set by cstore and the
+// cstore version tag are kept.
+func tagChanges(current, desired map[string]string) (map[string]string, []string) {
+	set := map[string]string{}
+	remove := []string{}
+
+	for key, value := range desired {
+		if v, found := current[key]; !found || v != value {
+			set[key] = value
+		}
+	}
+
+	managed := strings.Fields(current[awsManagedTagsTag])
+
+	for key := range current {
+		if strings.HasPrefix(key, awsTagPrefix) && key != awsCstoreVersionTag {
+			managed = append(managed, key)
+		}
+	}
+
+	for _, key := range managed {
+		if _, found := desired[key]; !found {
+			remove = append(remove, key)
+		}
+	}
+
+	sort.Strings(remove)
+
+	return set, remove
+}
+
+//------------------------------------------
+//- Parameter Store tags
+//------------------------------------------
+func ssmTags(tags map[string]string) []*ssm.Tag {
+	list := []*ssm.Tag{}
+
+	for key, value := range tags {
+		list = append(list, &ssm.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+
+	return list
+}
+
+//------------------------------------------
+//- Existing params keep the cstore version
+//- that created them, so upgrading cstore
+//- does not retag every param. The checksum
+//- of the remaining tags is saved with the
+//- file to skip unchanged tags.
+//------------------------------------------
+func paramTags(tags map[string]string) (map[string]string, string) {
+	reconciled := map[string]string{}
+	keys := []string{}
+
+	for key, value := range tags {
+		if key == awsCstoreVersionTag {
+			continue
+		}
+
+		reconciled[key] = value
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(h, "%s=%s\n", key, reconciled[key])
+	}
+
+	return reconciled, hex.EncodeToString(h.Sum(nil))
+}
+
+func reconcileParamTags(svc *ssm.SSM, name string, desired map[string]string) error {
+	output, err := svc.ListTagsForResource(&ssm.ListTagsForResourceInput{
+		ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+		ResourceId:   aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	current := map[string]string{}
+	for _, tag := range output.TagList {
+		current[aws.StringValue(tag.Key)] = aws.StringValue(tag.Value)
+	}
+
+	set, remove := tagChanges(current, desired)
+
+	if len(set) > 0 {
+		if _, err := svc.AddTagsToResource(&ssm.AddTagsToResourceInput{
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			ResourceId:   aws.String(name),
+			Tags:         ssmTags(set),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(remove) > 0 {
+		if _, err := svc.RemoveTagsFromResource(&ssm.RemoveTagsFromResourceInput{
+			ResourceType: aws.String(ssm.ResourceTypeForTaggingParameter),
+			ResourceId:   aws.String(name),
+			TagKeys:      aws.StringSlice(remove),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//------------------------------------------
+//- Secrets Manager tags
+//------------------------------------------
+func secretTags(tags map[string]string) []*secretsmanager.Tag {
+	list := []*secretsmanager.Tag{}
+
+	for key, value := range tags {
+		list = append(list, &secretsmanager.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		})
+	}
+
+	return list
+}
+
+// createSecret creates a tagged secret. When the caller cannot tag
+// secrets, the secret is created without tags.
+func createSecret(svc *secretsmanager.SecretsManager, input *secretsmanager.CreateSecretInput, io models.IO) error {
+	_, err := svc.CreateSecret(input)
+	if !tagsDenied(err) || len(input.Tags) == 0 {
+		return err
+	}
+
+	tagErr := err
+
+	untagged := *input
+	untagged.Tags = nil
+
+	if _, err := svc.CreateSecret(&untagged); err != nil {
+		return err
+	}
+
+	warnTags(aws.StringValue(input.Name), tagErr, io)
+
+	return nil
+}
+
+func reconcileSecretTags(svc *secretsmanager.SecretsManager, key string, current, desired map[string]string) error {
+	set, remove := tagChanges(current, desired)
+
+	if len(set) > 0 {
+		if _, err := svc.TagResource(&secretsmanager.TagResourceInput{
+			SecretId: aws.String(key),
+			Tags:     secretTags(set),
+		}); err != nil {
+			return err
+		}
+	}
+
+	if len(remove) > 0 {
+		if _, err := svc.UntagResource(&secretsmanager.UntagResourceInput{
+			SecretId: aws.String(key),
+			TagKeys:  aws.StringSlice(remove),
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//------------------------------------------
+//- S3 object tags
+//------------------------------------------
+func s3Tagging(tags map[string]string) string {
+	values := url.Values{}
+
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+
+	return values.Encode()
+}
@@ -0,0 +1,343 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+func TestEnsureTagChangesKeepTagsNotSetByCstore(t *testing.T) {
+	// arrange
+	current := map[string]string{
+		awsContextTag:     "app",
+		awsCatalogTagsTag: "dev",
+		awsManagedTagsTag: "cost-center team",
+		"team":            "payments",
+		"cost-center":     "42",
+		"owner":           "ops",
+	}
+
+	desired := map[string]string{
+		awsContextTag:     "app",
+		awsManagedTagsTag: "team",
+		"team":            "billing",
+	}
+
+	// act
+	set, remove := tagChanges(current, desired)
+
+	// assert
+	expectedSet := map[string]string{awsManagedTagsTag: "team", "team": "billing"}
+	if !reflect.DeepEqual(set, expectedSet) {
+		t.Errorf("\nEXPECTED: %v \nACTUAL: %v", expectedSet, set)
+	}
+
+	expectedRemove := []string{"cost-center", awsCatalogTagsTag}
+	if !reflect.DeepEqual(remove, expectedRemove) {
+		t.Errorf("\nEXPECTED: %v \nACTUAL: %v", expectedRemove, remove)
+	}
+}
+
+func TestEnsureReservedResourceTagsAreRejected(t *testing.T) {
+	// arrange
+	file := &catalog.File{Path: "dev/.env", Data: map[string]string{awsResourceTagsSetting: "cstore:context=other"}}
+
+	// act
+	_, err := resourceTags(catalog.Catalog{Context: "app"}, file, cfg.UserOptions{Silent: true}, models.IO{})
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "reserved tag error", err)
+	}
+}
+
+func TestEnsureParamTagsAreReconciledOnPush(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{
+		Path: "dev/.env",
+		Type: "env",
+		Tags: []string{"dev"},
+		Data: map[string]string{
+			awsStoreKMSKeyID:       defaultPSKMSKey,
+			awsResourceTagsSetting: "team=payments&cost-center=42",
+		},
+	}
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range fake.tags {
+		fake.tags[name]["owner"] = "ops"
+	}
+
+	file.Tags = []string{"prod"}
+	file.Data[awsResourceTagsSetting] = "team=billing"
+
+	// act
+	err := s.Push(file, envFile(3, "value"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tags := range fake.tags {
+		expected := map[string]string{
+			awsContextTag:       t.Name(),
+			awsPathTag:          file.Path,
+			awsCatalogTagsTag:   "prod",
+			awsCstoreVersionTag: cfg.Version,
+			awsManagedTagsTag:   "team",
+			"team":              "billing",
+			"owner":             "ops",
+		}
+
+		if !reflect.DeepEqual(tags, expected) {
+			t.Errorf("\nEXPECTED: %s %v \nACTUAL: %v", name, expected, tags)
+		}
+	}
+}
+
+func TestEnsureSecretIsCreatedWithTags(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	file := &catalog.File{
+		Path: "dev/config.json",
+		Type: "json",
+		Data: map[string]string{
+			awsStoreKMSKeyID:       defaultSMKMSKey,
+			awsResourceTagsSetting: "team=payments",
+		},
+	}
+
+	// act
+	err := s.Push(file, []byte(`{"a":"b"}`), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := fake.tags[fmt.Sprintf("%s/%s", t.Name(), file.Path)]
+
+	if tags["team"] != "payments" || tags[awsPathTag] != file.Path {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "team and path tags", tags)
+	}
+}
+
+func TestEnsureParamsUntaggedByEarlierPushAreTagged(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{
+		Path: "dev/.env",
+		Type: "env",
+		Data: map[string]string{
+			awsStoreKMSKeyID:       defaultPSKMSKey,
+			awsResourceTagsSetting: "team=payments",
+		},
+	}
+
+	fake.denied["AddTagsToResource"] = true
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	delete(fake.denied, "AddTagsToResource")
+
+	// act
+	err := s.Push(file, envFile(3, "value"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stale := buildRemoteKey(t.Name(), file.Path, "KEY_002", "")
+
+	if fake.tags[stale]["team"] != "payments" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "team tag set", fake.tags[stale])
+	}
+}
+
+func TestEnsureUnchangedParamTagsAreNotReconciled(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{
+		Path: "dev/.env",
+		Type: "env",
+		Data: map[string]string{
+			awsStoreKMSKeyID:       defaultPSKMSKey,
+			awsResourceTagsSetting: "team=payments",
+		},
+	}
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.listed = 0
+
+	// act
+	err := s.Push(file, envFile(4, "changed"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.listed != 0 {
+		t.Errorf("\nEXPECTED: %d tag lookups \nACTUAL: %d", 0, fake.listed)
+	}
+
+	added := buildRemoteKey(t.Name(), file.Path, "KEY_003", "")
+
+	if fake.tags[added]["team"] != "payments" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "team tag set", fake.tags[added])
+	}
+}
+
+func TestEnsureParamVersionTagIsOnlySetOnCreate(t *testing.T) {
+	// arrange
+	s, fake, _, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	file := &catalog.File{
+		Path: "dev/.env",
+		Type: "env",
+		Data: map[string]string{
+			awsStoreKMSKeyID:       defaultPSKMSKey,
+			awsResourceTagsSetting: "team=payments",
+		},
+	}
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for name := range fake.tags {
+		fake.tags[name][awsCstoreVersionTag] = "v0.0.1"
+	}
+
+	file.Data[awsResourceTagsSetting] = "team=billing"
+
+	// act
+	err := s.Push(file, envFile(3, "value"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tags := range fake.tags {
+		if tags[awsCstoreVersionTag] != "v0.0.1" || tags["team"] != "billing" {
+			t.Errorf("\nEXPECTED: %s %s \nACTUAL: %v", name, "original version and billing team", tags)
+		}
+	}
+}
+
+func TestEnsureParamsArePushedWithoutTagPermission(t *testing.T) {
+	// arrange
+	s, fake, output, cleanup := setupParamStore(t)
+	defer cleanup()
+
+	fake.denied["AddTagsToResource"] = true
+	fake.denied["RemoveTagsFromResource"] = true
+
+	file := &catalog.File{Path: "dev/.env", Type: "env", Data: map[string]string{awsStoreKMSKeyID: defaultPSKMSKey}}
+
+	if err := s.Push(file, envFile(3, "value"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := s.Push(file, envFile(4, "value"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.params) != 4 {
+		t.Errorf("\nEXPECTED: %d params \nACTUAL: %d", 4, len(fake.params))
+	}
+
+	if !strings.Contains(output.String(), "AccessDeniedException") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "tag warning", output.String())
+	}
+}
+
+func TestEnsureSecretIsCreatedWithoutTagPermission(t *testing.T) {
+	// arrange
+	s, fake, cleanup := setupSecretManagerStore(t)
+	defer cleanup()
+
+	fake.denied["TagResource"] = true
+
+	file := &catalog.File{Path: "dev/config.json", Type: "json", Data: map[string]string{awsStoreKMSKeyID: defaultSMKMSKey}}
+
+	// act
+	err := s.Push(file, []byte(`{"a":"b"}`), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _, err := s.Pull(file, ""); err != nil || string(b) != `{"a":"b"}` {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", `{"a":"b"}`, string(b), err)
+	}
+}
+
+func TestEnsureS3TagsOverTheLimitAreNotSet(t *testing.T) {
+	// arrange
+	s, file, fake, cleanup := setupS3Store(t)
+	defer cleanup()
+
+	output := &bytes.Buffer{}
+	s.io = models.IO{UserOutput: output}
+
+	user := []string{}
+	for i := 0; i < s3MaxTags; i++ {
+		user = append(user, fmt.Sprintf("tag%d=%d", i, i))
+	}
+	file.Data[awsResourceTagsSetting] = strings.Join(user, "&")
+
+	// act
+	err := s.Push(file, []byte("ENV=dev"), "")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	versions := fake.versions[s.key(file.Path, "")]
+	if len(versions) != 1 {
+		t.Fatalf("\nEXPECTED: %d versions \nACTUAL: %d", 1, len(versions))
+	}
+
+	tags := versions[0].tags
+	if len(tags) != s3MaxTags || len(tags.Get(awsPathTag)) == 0 || len(tags.Get("tag0")) == 0 {
+		t.Errorf("\nEXPECTED: %d tags with cstore tags \nACTUAL: %v", s3MaxTags, tags)
+	}
+
+	if !strings.Contains(output.String(), "tag9") {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "tag9 warning", output.String())
+	}
+}
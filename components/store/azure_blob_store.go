@@ -0,0 +1,411 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/turnerlabs/cstore/v4/components/azure"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	azureAccountSetting   = "AZURE_STORAGE_ACCOUNT"
+	azureContainerSetting = "AZURE_STORAGE_CONTAINER"
+	azureAccessKey        = "AZURE_STORAGE_ACCESS_KEY"
+	azureEndpoint         = "AZURE_STORAGE_ENDPOINT"
+
+	azureSnapshotPrefix = "AZURE_BLOB_SNAPSHOT_"
+
+	// Snapshots are labeled with the user version in metadata, so versions
+	// missing from the catalog can be found.
+	azureVersionMetadata = "cstoreversion"
+)
+
+// AzureBlobStore ...
+type AzureBlobStore struct {
+	Service azblob.ServiceURL
+
+	clog catalog.Catalog
+
+	uo cfg.UserOptions
+	io models.IO
+
+	container setting.Setting
+}
+
+// Name ...
+func (s AzureBlobStore) Name() string {
+	return "azure-blob"
+}
+
+// Capabilities ...
+func (s AzureBlobStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning:        true,
+		ConditionalWrites: true,
+		ClientEncryption:  true,
+		Binary:            true,
+	}
+}
+
+// Description ...
+func (s AzureBlobStore) Description() string {
+	return `
+	details: https://github.com/turnerlabs/cstore/v4/blob/master/docs/AZURE.md
+`
+}
+
+// Pre ...
+func (s *AzureBlobStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+
+	s.clog = clog
+	s.io = io
+	s.uo = uo
+
+	//------------------------------------------
+	//- Store Configuration
+	//------------------------------------------
+	s.container = setting.Setting{
+		Description:  "Azure Storage container that will store the file.",
+		Prop:         azureContainerSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		DefaultValue: clog.GetDataByStore(s.Name(), azureContainerSetting, strings.ToLower(fmt.Sprintf("%s-configs", clog.Context))),
+		Vault:        file,
+	}
+
+	account, err := setting.Setting{
+		Description:  "Azure Storage account that contains the container.",
+		Prop:         azureAccountSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		PromptOnce:   true,
+		DefaultValue: clog.GetDataByStore(s.Name(), azureAccountSetting, ""),
+		Vault:        file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	if len(account) == 0 {
+		return errors.New("Azure Storage account is required")
+	}
+
+	//------------------------------------------
+	//- Get Azure Credentials from Vault
+	//------------------------------------------
+	key, err := azure.Credential(azureAccessKey, clog, access, uo, io)
+	if err != nil {
+		return err
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(account, key)
+	if err != nil {
+		return err
+	}
+
+	//------------------------------------------
+	//- Use a custom endpoint like Azurite
+	//------------------------------------------
+	endpoint := os.Getenv(azureEndpoint)
+	if len(endpoint) == 0 {
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net", account)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", azureEndpoint, err)
+	}
+
+	s.Service = azblob.NewServiceURL(*u, azblob.NewPipeline(credential, azblob.PipelineOptions{}))
+
+	return nil
+}
+
+// Purge ...
+func (s AzureBlobStore) Purge(file *catalog.File, version string) error {
+
+	container, err := s.container.Get(s.clog.Context, s.io)
+	if err != nil {
+		return err
+	}
+
+	containerURL := s.Service.NewContainerURL(container)
+	blob := containerURL.NewBlobURL(s.key(file))
+
+	if len(version) == 0 {
+		if _, err := blob.Delete(context.Background(), azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{}); err != nil && !blobNotFound(err) {
+			return err
+		}
+
+		for key := range file.Data {
+			if strings.HasPrefix(key, azureSnapshotPrefix) {
+				delete(file.Data, key)
+			}
+		}
+
+		return nil
+	}
+
+	snapshot, err := s.snapshot(containerURL, file, version)
+	if err != nil {
+		if err == errSnapshotNotFound {
+			return fmt.Errorf("version %s %s", version, err)
+		}
+
+		return err
+	}
+
+	if _, err := blob.WithSnapshot(snapshot).Delete(context.Background(), azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{}); err != nil && !blobNotFound(err) {
+		return err
+	}
+
+	delete(file.Data, azureSnapshotPrefix+version)
+
+	return nil
+}
+
+// Push ...
+func (s AzureBlobStore) Push(file *catalog.File, fileData []byte, version string) error {
+	_, err := s.PushIf(file, fileData, version, "")
+	return err
+}
+
+// PushIf ...
+func (s AzureBlobStore) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	if len(fileData) == 0 {
+		return "", errors.New("empty file")
+	}
+
+	container, err := s.container.Get(s.clog.Context, s.io)
+	if err != nil {
+		return "", err
+	}
+
+	file.AddData(map[string]string{
+		azureContainerSetting: container,
+	})
+
+	blob := s.Service.NewContainerURL(container).NewBlockBlobURL(s.key(file))
+
+	//------------------------------------------
+	//- Only overwrite the ETag last seen.
+	//------------------------------------------
+	conditions := azblob.BlobAccessConditions{}
+	if len(revision) > 0 {
+		conditions.ModifiedAccessConditions.IfMatch = azblob.ETag(revision)
+	}
+
+	resp, err := blob.Upload(context.Background(), bytes.NewReader(fileData), azblob.BlobHTTPHeaders{}, azblob.Metadata{}, conditions)
+	if err != nil {
+		if serr, ok := err.(azblob.StorageError); ok && serr.Response().StatusCode == http.StatusPreconditionFailed {
+			return "", contract.ErrRevisionConflict
+		}
+
+		return "", err
+	}
+
+	//------------------------------------------
+	//- Snapshot the blob to keep the version.
+	//------------------------------------------
+	if len(version) > 0 {
+		snapshot, err := blob.CreateSnapshot(context.Background(), azblob.Metadata{azureVersionMetadata: version}, azblob.BlobAccessConditions{})
+		if err != nil {
+			return "", err
+		}
+
+		file.AddData(map[string]string{
+			azureSnapshotPrefix + version: snapshot.Snapshot(),
+		})
+	}
+
+	return string(resp.ETag()), nil
+}
+
+// Pull ...
+func (s AzureBlobStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+
+	blob, err := s.blob(file, version)
+	if err != nil {
+		if err == errSnapshotNotFound {
+			return []byte{}, contract.Attributes{}, fmt.Errorf("version %s %s", version, err)
+		}
+
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	resp, err := blob.Download(context.Background(), 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false)
+	if err != nil {
+		if blobNotFound(err) {
+			return []byte{}, contract.Attributes{}, fmt.Errorf("%s not found in %s", file.ActualPath(), blob.String())
+		}
+
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	body := resp.Body(azblob.RetryReaderOptions{})
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return b, contract.Attributes{}, err
+	}
+
+	attr := attributes(b)
+	attr.Modified = resp.LastModified()
+
+	//------------------------------------------
+	//- Snapshots are never written, so only the
+	//- working copy has a revision.
+	//------------------------------------------
+	if len(version) == 0 {
+		attr.Revision = string(resp.ETag())
+	}
+
+	return b, attr, nil
+}
+
+// Changed ...
+func (s AzureBlobStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+
+	blob, err := s.blob(file, version)
+	if err != nil {
+		if err == errSnapshotNotFound {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	props, err := blob.GetProperties(context.Background(), azblob.BlobAccessConditions{})
+	if err != nil {
+		if blobNotFound(err) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return props.LastModified(), nil
+}
+
+// Revision ...
+func (s AzureBlobStore) Revision(file *catalog.File, version string) (string, error) {
+
+	blob, err := s.blob(file, "")
+	if err != nil {
+		return "", err
+	}
+
+	props, err := blob.GetProperties(context.Background(), azblob.BlobAccessConditions{})
+	if err != nil {
+		if blobNotFound(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return string(props.ETag()), nil
+}
+
+func (s AzureBlobStore) blob(file *catalog.File, version string) (azblob.BlobURL, error) {
+	setting := s.container
+	setting.Prompt = false
+
+	container, err := setting.Get(s.clog.Context, s.io)
+	if err != nil {
+		return azblob.BlobURL{}, err
+	}
+
+	containerURL := s.Service.NewContainerURL(container)
+	blob := containerURL.NewBlobURL(s.key(file))
+
+	if len(version) == 0 {
+		return blob, nil
+	}
+
+	snapshot, err := s.snapshot(containerURL, file, version)
+	if err != nil {
+		return azblob.BlobURL{}, err
+	}
+
+	return blob.WithSnapshot(snapshot), nil
+}
+
+//------------------------------------------
+//- Lookup the snapshot for a user version.
+//- Versions pushed from other catalogs are
+//- found by the snapshot label.
+//------------------------------------------
+func (s AzureBlobStore) snapshot(container azblob.ContainerURL, file *catalog.File, version string) (string, error) {
+
+	if value, found := file.Data[azureSnapshotPrefix+version]; found {
+		return value, nil
+	}
+
+	snapshot := ""
+
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		list, err := container.ListBlobsFlatSegment(context.Background(), marker, azblob.ListBlobsSegmentOptions{
+			Prefix:  s.key(file),
+			Details: azblob.BlobListingDetails{Snapshots: true, Metadata: true},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		//------------------------------------------
+		//- Snapshots are listed oldest first, so
+		//- the last label pushed is used.
+		//------------------------------------------
+		for _, item := range list.Segment.BlobItems {
+			if item.Name == s.key(file) && len(item.Snapshot) > 0 && item.Metadata[azureVersionMetadata] == version {
+				snapshot = item.Snapshot
+			}
+		}
+
+		marker = list.NextMarker
+	}
+
+	if len(snapshot) == 0 {
+		return "", errSnapshotNotFound
+	}
+
+	return snapshot, nil
+}
+
+func (s AzureBlobStore) key(file *catalog.File) string {
+	return fmt.Sprintf("%s/%s", s.clog.Context, file.ActualPath())
+}
+
+var errSnapshotNotFound = errors.New("not found in the catalog or blob snapshots")
+
+func blobNotFound(err error) bool {
+	if serr, ok := err.(azblob.StorageError); ok {
+		return serr.ServiceCode() == azblob.ServiceCodeBlobNotFound || serr.Response().StatusCode == 404
+	}
+
+	return false
+}
+
+func init() {
+	s := new(AzureBlobStore)
+	stores[s.Name()] = s
+}
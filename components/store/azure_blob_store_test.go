@@ -0,0 +1,276 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+)
+
+// Well known Azurite development storage credentials.
+const (
+	azuriteAccount = "devstoreaccount1"
+	azuriteKey     = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+)
+
+// fakeBlob is a minimal stand-in for the Azure Blob service that is used
+// when AZURE_STORAGE_ENDPOINT does not point to Azurite.
+type fakeBlob struct {
+	sync.Mutex
+	blobs map[string]fakeBlobObject
+}
+
+type fakeBlobObject struct {
+	data     []byte
+	modified time.Time
+	metadata map[string]string
+}
+
+func (f *fakeBlob) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	name := r.URL.Path
+	snapshot := r.URL.Query().Get("snapshot")
+
+	switch {
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "snapshot":
+		blob, found := f.blobs[name]
+		if !found {
+			notFound(w)
+			return
+		}
+
+		snapshot = time.Now().UTC().Format("2006-01-02T15:04:05.0000000Z")
+
+		blob.metadata = map[string]string{}
+		for k := range r.Header {
+			if strings.HasPrefix(strings.ToLower(k), "x-ms-meta-") {
+				blob.metadata[strings.ToLower(k[len("x-ms-meta-"):])] = r.Header.Get(k)
+			}
+		}
+
+		f.blobs[name+"?"+snapshot] = blob
+
+		w.Header().Set("x-ms-snapshot", snapshot)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+		f.list(w, name+"/"+r.URL.Query().Get("prefix"))
+	case r.Method == http.MethodPut:
+		data, _ := ioutil.ReadAll(r.Body)
+		f.blobs[name] = fakeBlobObject{data: data, modified: time.Now().UTC()}
+
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodDelete:
+		key := name
+		if len(snapshot) > 0 {
+			key = name + "?" + snapshot
+		}
+
+		if _, found := f.blobs[key]; !found {
+			notFound(w)
+			return
+		}
+
+		delete(f.blobs, key)
+
+		if len(snapshot) == 0 {
+			for k := range f.blobs {
+				if len(k) > len(name) && k[:len(name)+1] == name+"?" {
+					delete(f.blobs, k)
+				}
+			}
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		key := name
+		if len(snapshot) > 0 {
+			key = name + "?" + snapshot
+		}
+
+		blob, found := f.blobs[key]
+		if !found {
+			notFound(w)
+			return
+		}
+
+		w.Header().Set("Last-Modified", blob.modified.Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob.data)))
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodGet {
+			w.Write(blob.data)
+		}
+	}
+}
+
+// list returns the snapshots of blobs starting with prefix, oldest first.
+func (f *fakeBlob) list(w http.ResponseWriter, prefix string) {
+	keys := []string{}
+	for k := range f.blobs {
+		if strings.HasPrefix(k, prefix) && strings.Contains(k, "?") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var body bytes.Buffer
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?><EnumerationResults><Blobs>`)
+
+	for _, k := range keys {
+		parts := strings.SplitN(k, "?", 2)
+		name := strings.SplitN(strings.TrimPrefix(parts[0], "/"), "/", 3)[2]
+
+		fmt.Fprintf(&body, "<Blob><Name>%s</Name><Snapshot>%s</Snapshot><Properties><Last-Modified>%s</Last-Modified><Content-Length>%d</Content-Length></Properties><Metadata>", name, parts[1], f.blobs[k].modified.Format(http.TimeFormat), len(f.blobs[k].data))
+
+		for mk, mv := range f.blobs[k].metadata {
+			fmt.Fprintf(&body, "<%s>%s</%s>", mk, mv, mk)
+		}
+
+		body.WriteString("</Metadata></Blob>")
+	}
+
+	body.WriteString("</Blobs><NextMarker /></EnumerationResults>")
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(body.Bytes())
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("x-ms-error-code", "BlobNotFound")
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func setupAzureBlobStore(t *testing.T) (*AzureBlobStore, *catalog.File, func()) {
+	cleanup := fakeEndpoint(azureEndpoint, &fakeBlob{blobs: map[string]fakeBlobObject{}}, "/"+azuriteAccount)
+	unset := setenv(map[string]string{azureAccessKey: azuriteKey})
+
+	s := new(AzureBlobStore)
+
+	file, err := preStore(t, s, map[string]string{
+		azureAccountSetting:   azuriteAccount,
+		azureContainerSetting: "cstore-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, file, func() {
+		unset()
+		cleanup()
+	}
+}
+
+func TestEnsureAzureBlobSnapshotsAreRetrievedIndependently(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupAzureBlobStore(t)
+	defer cleanup()
+
+	pushes := []struct {
+		version string
+		data    string
+	}{
+		{"v1.0.0", "ENV=v1"},
+		{"v2.0.0", "ENV=v2"},
+		{"", "ENV=working"},
+	}
+
+	// act
+	for _, p := range pushes {
+		if err := s.Push(file, []byte(p.data), p.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	for _, p := range pushes {
+		b, _, err := s.Pull(file, p.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != p.data {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", p.data, string(b))
+		}
+	}
+
+	s.Purge(file, "")
+}
+
+func TestEnsureAzureBlobPurgedSnapshotIsNotRetrieved(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupAzureBlobStore(t)
+	defer cleanup()
+
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := s.Push(file, []byte("VER="+v), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	if err := s.Purge(file, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if _, _, err := s.Pull(file, "v1.0.0"); err == nil {
+		t.Errorf("\nEXPECTED: v1.0.0 purged \nACTUAL: v1.0.0 retrieved")
+	}
+
+	if b, _, err := s.Pull(file, "v2.0.0"); err != nil {
+		t.Error(err)
+	} else if string(b) != "VER=v2.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "VER=v2.0.0", string(b))
+	}
+
+	if changed, err := s.Changed(file, []byte{}, "v2.0.0"); err != nil {
+		t.Error(err)
+	} else if changed.IsZero() {
+		t.Errorf("\nEXPECTED: v2.0.0 changed time \nACTUAL: zero time")
+	}
+
+	s.Purge(file, "")
+}
+
+func TestEnsureAzureBlobSnapshotMissingFromCatalogIsPulled(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupAzureBlobStore(t)
+	defer cleanup()
+
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := s.Push(file, []byte("VER="+v), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := testFile(file.Data)
+	delete(other.Data, azureSnapshotPrefix+"v1.0.0")
+
+	// act
+	b, _, err := s.Pull(other, "v1.0.0")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "VER=v1.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "VER=v1.0.0", string(b))
+	}
+
+	if _, _, err := s.Pull(other, "v3.0.0"); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "version not found error", err)
+	}
+
+	s.Purge(file, "")
+}
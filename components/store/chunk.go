@@ -0,0 +1,82 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// chunkManifest is stored in place of a value too large for the service.
+// The value is split across numbered chunks and verified on reassembly.
+type chunkManifest struct {
+	Chunks int    `json:"cstore_chunks"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// splitChunks splits a value into chunks no larger than size bytes. UTF-8
+// characters are never split, so each chunk is a valid string.
+func splitChunks(value string, size int) []string {
+	chunks := []string{}
+
+	for len(value) > size {
+		cut := size
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+
+		chunks = append(chunks, value[:cut])
+		value = value[cut:]
+	}
+
+	return append(chunks, value)
+}
+
+// newChunkManifest describes how a value was split into chunks.
+func newChunkManifest(value string, chunks int) string {
+	h := sha256.Sum256([]byte(value))
+
+	b, _ := json.Marshal(chunkManifest{
+		Chunks: chunks,
+		Size:   len(value),
+		SHA256: hex.EncodeToString(h[:]),
+	})
+
+	return string(b)
+}
+
+// parseChunkManifest returns the manifest when the stored value is one.
+func parseChunkManifest(value string) (chunkManifest, bool) {
+	m := chunkManifest{}
+
+	if !strings.HasPrefix(value, `{"cstore_chunks":`) {
+		return m, false
+	}
+
+	if err := json.Unmarshal([]byte(value), &m); err != nil || m.Chunks == 0 {
+		return m, false
+	}
+
+	return m, true
+}
+
+// join reassembles the chunks and verifies the checksum, so a value changed
+// between reading the manifest and the chunks is never returned.
+func (m chunkManifest) join(chunks []string) (string, error) {
+	if len(chunks) != m.Chunks {
+		return "", fmt.Errorf("expected %d chunks, found %d", m.Chunks, len(chunks))
+	}
+
+	value := strings.Join(chunks, "")
+
+	h := sha256.Sum256([]byte(value))
+
+	if len(value) != m.Size || hex.EncodeToString(h[:]) != m.SHA256 {
+		return "", fmt.Errorf("chunk checksum mismatch, push again or retry the pull")
+	}
+
+	return value, nil
+}
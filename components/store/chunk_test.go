@@ -0,0 +1,99 @@
+package store
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/subosito/gotenv"
+)
+
+func TestEnsureChunksDoNotSplitCharacters(t *testing.T) {
+	// arrange
+	value := strings.Repeat("aé€", 1000)
+
+	// act
+	chunks := splitChunks(value, 100)
+
+	// assert
+	for _, chunk := range chunks {
+		if len(chunk) > 100 || !utf8.ValidString(chunk) {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %q", "valid chunk of 100 bytes or less", chunk)
+		}
+	}
+
+	if strings.Join(chunks, "") != value {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "chunks join to value", "value changed")
+	}
+}
+
+func TestEnsureLargeParamIsChunkedAndReassembled(t *testing.T) {
+	// arrange
+	env := gotenv.Env{
+		"SMALL":  "dev",
+		"MEDIUM": strings.Repeat("m", paramStandardMaxSize+1),
+		"LARGE":  strings.Repeat("l", paramAdvancedMaxSize*2),
+	}
+
+	// act
+	params := buildParams("app", "dev/.env", "", env, []param{})
+
+	joined, err := joinParamChunks(params)
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := toMap(joined)
+
+	if len(values) != len(env) {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d", len(env), len(values))
+	}
+
+	for name, value := range env {
+		if values[buildRemoteKey("app", "dev/.env", name, "")] != value {
+			t.Errorf("\nEXPECTED: %s reassembled \nACTUAL: %s changed", name, name)
+		}
+	}
+
+	for _, p := range params {
+		expected := ssm.ParameterTierStandard
+		if p.name == buildRemoteKey("app", "dev/.env", "MEDIUM", "") {
+			expected = ssm.ParameterTierAdvanced
+		}
+
+		if p.tier != expected || len(p.value) > paramAdvancedMaxSize {
+			t.Errorf("\nEXPECTED: %s %s \nACTUAL: %s %s (%d bytes)", p.name, expected, p.name, p.tier, len(p.value))
+		}
+	}
+}
+
+func TestEnsureAdvancedParamIsNotDowngraded(t *testing.T) {
+	// arrange
+	name := buildRemoteKey("app", "dev/.env", "ENV", "")
+
+	stored := []param{{name: name, value: strings.Repeat("x", paramStandardMaxSize+1), tier: ssm.ParameterTierAdvanced}}
+
+	// act
+	params := buildParams("app", "dev/.env", "", gotenv.Env{"ENV": "dev"}, stored)
+
+	// assert
+	if params[0].tier != ssm.ParameterTierAdvanced {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", ssm.ParameterTierAdvanced, params[0].tier)
+	}
+}
+
+func TestEnsureMissingParamChunkFailsReassembly(t *testing.T) {
+	// arrange
+	params := buildParams("app", "dev/.env", "", gotenv.Env{"LARGE": strings.Repeat("l", paramAdvancedMaxSize*2)}, []param{})
+
+	// act
+	_, err := joinParamChunks(params[1:])
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "missing chunk error", err)
+	}
+}
@@ -0,0 +1,240 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/cipher"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	localFile "github.com/turnerlabs/cstore/v4/components/file"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+)
+
+const (
+	ceAlgorithmSetting = "CSTORE_ENCRYPTION_ALGORITHM"
+	ceKeyIDSetting     = "CSTORE_ENCRYPTION_KEY_ID"
+)
+
+// EncryptedStore wraps a store to encrypt file data before it is pushed
+// and decrypt it after it is pulled. Each push encrypts the data with a
+// new data key that is wrapped by the CSTORE_ENCRYPTION_KEY retrieved
+// from the access vault.
+type EncryptedStore struct {
+	contract.IStore
+
+	clog catalog.Catalog
+
+	key []byte
+}
+
+// Encrypted checks if a file is configured for client side encryption.
+func Encrypted(file catalog.File) bool {
+	_, found := file.Data[ceAlgorithmSetting]
+	return found
+}
+
+// SetEncryption turns client side encryption on or off for a file.
+func SetEncryption(file *catalog.File, enabled bool) {
+	if enabled {
+		file.AddData(map[string]string{
+			ceAlgorithmSetting: cipher.EnvelopeAlgorithm,
+		})
+		return
+	}
+
+	delete(file.Data, ceAlgorithmSetting)
+	delete(file.Data, ceKeyIDSetting)
+}
+
+// Moved returns a copy of the file for another store. Data saved by
+// the previous store is removed, but encryption settings are kept.
+func Moved(file catalog.File, storeName string) catalog.File {
+	moved := file
+	moved.Store = storeName
+	moved.Data = map[string]string{}
+
+	for _, key := range []string{ceAlgorithmSetting, ceKeyIDSetting} {
+		if value, found := file.Data[key]; found {
+			moved.Data[key] = value
+		}
+	}
+
+	return moved
+}
+
+// Pre ...
+func (s *EncryptedStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+
+	if !s.IStore.Capabilities().ClientEncryption {
+		return contract.UnsupportedError{Store: s.IStore.Name(), Capability: "client side encryption"}
+	}
+
+	if err := s.IStore.Pre(clog, file, access, uo, io); err != nil {
+		return err
+	}
+
+	value, err := access.Get(clog.Context, clog.Context, ceKeyName)
+	if err != nil && err != contract.ErrSecretNotFound {
+		return err
+	}
+
+	//------------------------------------------
+	//- Generate a key for the first encrypted
+	//- file when the access vault can keep it.
+	//------------------------------------------
+	if len(value) == 0 && len(file.Data[ceKeyIDSetting]) == 0 {
+		if _, env := access.(vault.EnvVault); !env {
+			if value, err = cipher.GenerateKey(); err != nil {
+				return err
+			}
+
+			if err := access.Set(clog.Context, clog.Context, ceKeyName, value); err != nil {
+				return err
+			}
+
+			color.New(color.FgYellow).Fprintf(io.UserOutput, "Generated %s and saved it in %s. Share it with anyone who needs to pull encrypted files.\n", ceKeyName, access.Name())
+		}
+	}
+
+	if len(value) == 0 {
+		value, err = setting.Setting{
+			Description: fmt.Sprintf("Key used to encrypt and decrypt files client side. Save key in %s.", access.Name()),
+			Group:       clog.Context,
+			Prop:        ceKeyName,
+			Prompt:      true,
+			Silent:      uo.Silent,
+			HideInput:   true,
+			AutoSave:    true,
+			PromptOnce:  true,
+			Vault:       access,
+		}.Get(clog.Context, io)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(value) == 0 {
+		return fmt.Errorf("%s not found in %s", ceKeyName, access.Name())
+	}
+
+	s.key, err = cipher.ParseKey(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %s", ceKeyName, err)
+	}
+
+	return nil
+}
+
+// Push ...
+func (s EncryptedStore) Push(file *catalog.File, fileData []byte, version string) error {
+
+	sealed, err := s.seal(file, fileData)
+	if err != nil {
+		return err
+	}
+
+	return s.IStore.Push(file, sealed, version)
+}
+
+// PushIf ...
+func (s EncryptedStore) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	sealed, err := s.seal(file, fileData)
+	if err != nil {
+		return "", err
+	}
+
+	return PushIf(s.IStore, file, sealed, version, revision)
+}
+
+// Pull ...
+func (s EncryptedStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+
+	b, attr, err := s.IStore.Pull(file, version)
+	if err != nil {
+		return b, attr, err
+	}
+
+	if !cipher.IsEnvelope(b) {
+		return []byte{}, attr, fmt.Errorf("%s is not encrypted, push the file to encrypt it", file.ActualPath())
+	}
+
+	b, err = cipher.Open(s.key, b)
+	if err == cipher.ErrKeyMismatch {
+		return []byte{}, attr, fmt.Errorf("%s was encrypted with a different %s", file.ActualPath(), ceKeyName)
+	}
+
+	if err != nil {
+		return []byte{}, attr, err
+	}
+
+	//------------------------------------------
+	//- Describe the decrypted data.
+	//------------------------------------------
+	decrypted := attributes(b)
+	attr.Size, attr.Checksum = decrypted.Size, decrypted.Checksum
+
+	return b, attr, nil
+}
+
+// Stat ...
+func (s EncryptedStore) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+	attr, err := Stat(s.IStore, file, version)
+
+	//------------------------------------------
+	//- The store only knows the size and
+	//- checksum of the encrypted file.
+	//------------------------------------------
+	attr.Size = 0
+	attr.Checksum = ""
+
+	return attr, err
+}
+
+// History ...
+func (s EncryptedStore) History(file *catalog.File) ([]contract.Revision, error) {
+	if h, ok := s.IStore.(contract.IHistory); ok {
+		return h.History(file)
+	}
+
+	return []contract.Revision{}, contract.ErrHistoryNotSupported
+}
+
+// Rollback ...
+func (s EncryptedStore) Rollback(file *catalog.File, revision string) error {
+	if h, ok := s.IStore.(contract.IHistory); ok {
+		return h.Rollback(file, revision)
+	}
+
+	return contract.ErrHistoryNotSupported
+}
+
+func (s EncryptedStore) seal(file *catalog.File, fileData []byte) ([]byte, error) {
+
+	sealed, err := cipher.Seal(s.key, fileData)
+	if err != nil {
+		return nil, err
+	}
+
+	file.AddData(map[string]string{
+		ceAlgorithmSetting: cipher.EnvelopeAlgorithm,
+		ceKeyIDSetting:     cipher.KeyID(s.key),
+	})
+
+	//------------------------------------------
+	//- Replace the local file, so only the
+	//- encrypted file is committed.
+	//------------------------------------------
+	if s.IStore.Capabilities().SourceControl {
+		if err := localFile.Save(s.clog.GetFullPath(file.ActualPath()), sealed); err != nil {
+			return nil, err
+		}
+	}
+
+	return sealed, nil
+}
@@ -0,0 +1,107 @@
+package store
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+)
+
+//---------------------------------------------------
+//- Store tests run against a fake service unless an
+//- environment variable points the store at a real
+//- one, like an emulator in CI.
+//---------------------------------------------------
+
+// fakeEndpoint serves handler and sets the env endpoint to its url with
+// suffix appended, unless env is already set.
+func fakeEndpoint(env string, handler http.Handler, suffix string) func() {
+	if len(os.Getenv(env)) > 0 {
+		return func() {}
+	}
+
+	server := httptest.NewServer(handler)
+	os.Setenv(env, server.URL+suffix)
+
+	return func() {
+		server.Close()
+		os.Unsetenv(env)
+	}
+}
+
+// setenv sets the environment variables that are not already set and
+// returns a func unsetting them.
+func setenv(vars map[string]string) func() {
+	set := []string{}
+
+	for k, v := range vars {
+		if len(os.Getenv(k)) == 0 {
+			os.Setenv(k, v)
+			set = append(set, k)
+		}
+	}
+
+	return func() {
+		for _, k := range set {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+// testCatalog uses the test name as the context, so tests sharing a
+// service do not read each others files.
+func testCatalog(t *testing.T) catalog.Catalog {
+	return catalog.Catalog{
+		Context: t.Name(),
+	}
+}
+
+// testFile returns the file pushed by store tests with the store
+// settings in data.
+func testFile(data map[string]string) *catalog.File {
+	file := &catalog.File{
+		Path: "dev/.env",
+		Type: "env",
+		Data: map[string]string{},
+	}
+	file.AddData(data)
+
+	return file
+}
+
+// preStore prepares the store without prompting using the store settings
+// in data and access credentials from the environment.
+func preStore(t *testing.T, s contract.IStore, data map[string]string) (*catalog.File, error) {
+	file := testFile(data)
+
+	return file, s.Pre(testCatalog(t), file, vault.EnvVault{}, cfg.UserOptions{Silent: true}, models.IO{})
+}
+
+// fakeAWSSession returns a session sending requests for every AWS
+// service to handler.
+func fakeAWSSession(t *testing.T, handler http.Handler) (*session.Session, func()) {
+	server := httptest.NewServer(handler)
+
+	sess, err := session.NewSession(&aws.Config{
+		Region:           aws.String(awsDefaultRegion),
+		Endpoint:         aws.String(server.URL),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		server.Close()
+		t.Fatal(err)
+	}
+
+	return sess, server.Close
+}
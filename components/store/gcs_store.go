@@ -0,0 +1,348 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+	"github.com/turnerlabs/cstore/v4/components/vault"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+const (
+	gcsBucketSetting     = "GCS_BUCKET"
+	gcsKMSKeyNameSetting = "GCS_KMS_KEY_NAME"
+	gcsEndpoint          = "GCS_ENDPOINT"
+	gcsCredentials       = "GCS_CREDENTIALS"
+)
+
+// GCSStore ...
+type GCSStore struct {
+	Client *storage.Client
+
+	clog catalog.Catalog
+
+	uo cfg.UserOptions
+	io models.IO
+
+	bucket setting.Setting
+}
+
+// Name ...
+func (s GCSStore) Name() string {
+	return "gcs"
+}
+
+// Capabilities ...
+func (s GCSStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning:        true,
+		ConditionalWrites: true,
+		ClientEncryption:  true,
+		Binary:            true,
+	}
+}
+
+// Description ...
+func (s GCSStore) Description() string {
+	return `
+	details: https://github.com/turnerlabs/cstore/v4/blob/master/docs/GCS.md
+`
+}
+
+// Pre ...
+func (s *GCSStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+
+	s.clog = clog
+	s.io = io
+	s.uo = uo
+
+	//------------------------------------------
+	//- Store Configuration
+	//------------------------------------------
+	s.bucket = setting.Setting{
+		Description:  "GCS Bucket that will store the file.",
+		Prop:         gcsBucketSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		DefaultValue: clog.GetDataByStore(s.Name(), gcsBucketSetting, fmt.Sprintf("%s-configs", clog.Context)),
+		Vault:        file,
+	}
+
+	opts := []option.ClientOption{}
+
+	//------------------------------------------
+	//- Use a custom endpoint like fake-gcs-server
+	//------------------------------------------
+	if endpoint := os.Getenv(gcsEndpoint); len(endpoint) > 0 {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %s", gcsEndpoint, err)
+		}
+
+		opts = append(opts,
+			option.WithEndpoint(fmt.Sprintf("%s/storage/v1/", strings.TrimSuffix(endpoint, "/"))),
+			option.WithHTTPClient(&http.Client{Transport: gcsEndpointTransport{scheme: u.Scheme, host: u.Host}}))
+
+		return s.connect(opts...)
+	}
+
+	//------------------------------------------
+	//- Get GCP Credentials from Environment
+	//------------------------------------------
+	if _, ok := access.(vault.EnvVault); ok {
+		return s.connect(opts...)
+	}
+
+	//------------------------------------------
+	//- Get GCP Credentials from Vault
+	//------------------------------------------
+	key, err := setting.Setting{
+		Description: fmt.Sprintf("Service account key JSON or the path to a key file. Save credential in %s.", access.Name()),
+		Group:       clog.Context,
+		Prop:        gcsCredentials,
+		Prompt:      uo.Prompt,
+		Silent:      uo.Silent,
+		AutoSave:    true,
+		PromptOnce:  true,
+		Vault:       access,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasPrefix(strings.TrimSpace(key), "{") {
+		b, err := ioutil.ReadFile(key)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %s", gcsCredentials, err)
+		}
+
+		key = string(b)
+	}
+
+	return s.connect(append(opts, option.WithCredentialsJSON([]byte(key)))...)
+}
+
+// Purge ...
+func (s GCSStore) Purge(file *catalog.File, version string) error {
+
+	bucket, err := s.bucket.Get(s.clog.Context, s.io)
+	if err != nil {
+		return err
+	}
+
+	err = s.Client.Bucket(bucket).Object(s.key(file.ActualPath(), version)).Delete(context.Background())
+	if err != nil && err != storage.ErrObjectNotExist {
+		return err
+	}
+
+	return nil
+}
+
+// Push ...
+func (s GCSStore) Push(file *catalog.File, fileData []byte, version string) error {
+	_, err := s.PushIf(file, fileData, version, "")
+	return err
+}
+
+// PushIf ...
+func (s GCSStore) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	if len(fileData) == 0 {
+		return "", errors.New("empty file")
+	}
+
+	bucket, err := s.bucket.Get(s.clog.Context, s.io)
+	if err != nil {
+		return "", err
+	}
+
+	file.AddData(map[string]string{
+		gcsBucketSetting: bucket,
+	})
+
+	obj := s.Client.Bucket(bucket).Object(s.key(file.ActualPath(), version))
+
+	//------------------------------------------
+	//- Only overwrite the generation last seen.
+	//------------------------------------------
+	if len(revision) > 0 {
+		generation, err := strconv.ParseInt(revision, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid revision %s", revision)
+		}
+
+		obj = obj.If(storage.Conditions{GenerationMatch: generation})
+	}
+
+	w := obj.NewWriter(context.Background())
+
+	//------------------------------------------
+	//- Set customer managed encryption key
+	//------------------------------------------
+	value, err := setting.Setting{
+		Description:  "Cloud KMS key name (projects/{p}/locations/{l}/keyRings/{r}/cryptoKeys/{k}) is used by GCS to encrypt and decrypt the file. Any account accessing the file must also have access to the key. Leave blank to use the default bucket encryption settings.",
+		Prop:         gcsKMSKeyNameSetting,
+		Prompt:       s.uo.Prompt,
+		Silent:       s.uo.Silent,
+		DefaultValue: s.clog.GetDataByStore(s.Name(), gcsKMSKeyNameSetting, ""),
+		AutoSave:     false,
+		Vault:        file,
+	}.Get(s.clog.Context, s.io)
+
+	if len(value) > 0 {
+		w.KMSKeyName = value
+	}
+
+	if _, err := w.Write(fileData); err != nil {
+		w.Close()
+		return "", gcsConflict(err)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", gcsConflict(err)
+	}
+
+	return strconv.FormatInt(w.Attrs().Generation, 10), nil
+}
+
+// Pull ...
+func (s GCSStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+
+	bucket, err := s.storedBucket()
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	r, err := s.Client.Bucket(bucket).Object(s.key(file.ActualPath(), version)).NewReader(context.Background())
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return []byte{}, contract.Attributes{}, fmt.Errorf("%s not found in %s", file.ActualPath(), bucket)
+		}
+
+		return []byte{}, contract.Attributes{}, err
+	}
+	defer r.Close()
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return b, contract.Attributes{}, err
+	}
+
+	attr := attributes(b)
+	attr.Modified = r.Attrs.LastModified
+	attr.Revision = strconv.FormatInt(r.Attrs.Generation, 10)
+
+	return b, attr, nil
+}
+
+// Changed ...
+func (s GCSStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+
+	bucket, err := s.storedBucket()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	attrs, err := s.Client.Bucket(bucket).Object(s.key(file.ActualPath(), version)).Attrs(context.Background())
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return attrs.Updated, nil
+}
+
+// Revision ...
+func (s GCSStore) Revision(file *catalog.File, version string) (string, error) {
+
+	bucket, err := s.storedBucket()
+	if err != nil {
+		return "", err
+	}
+
+	attrs, err := s.Client.Bucket(bucket).Object(s.key(file.ActualPath(), version)).Attrs(context.Background())
+	if err != nil {
+		if err == storage.ErrObjectNotExist {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strconv.FormatInt(attrs.Generation, 10), nil
+}
+
+func gcsConflict(err error) error {
+	if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusPreconditionFailed {
+		return contract.ErrRevisionConflict
+	}
+
+	return err
+}
+
+func (s *GCSStore) connect(opts ...option.ClientOption) (err error) {
+	s.Client, err = storage.NewClient(context.Background(), opts...)
+	return err
+}
+
+//------------------------------------------
+//- Downloads are always requested over https
+//- by the client library, so requests to a
+//- custom endpoint are sent with its scheme.
+//------------------------------------------
+type gcsEndpointTransport struct {
+	scheme string
+	host   string
+}
+
+func (t gcsEndpointTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == t.host && req.URL.Scheme != t.scheme {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = t.scheme
+	}
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func (s GCSStore) storedBucket() (string, error) {
+	setting := s.bucket
+	setting.Prompt = false
+
+	return setting.Get(s.clog.Context, s.io)
+}
+
+func init() {
+	s := new(GCSStore)
+	stores[s.Name()] = s
+}
+
+//------------------------------------------
+//- Create GCS object key.
+//------------------------------------------
+func (s GCSStore) key(path, version string) string {
+
+	if len(version) > 0 {
+		return fmt.Sprintf("%s/%s/%s", s.clog.Context, version, path)
+	}
+
+	return fmt.Sprintf("%s/%s", s.clog.Context, path)
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+)
+
+// fakeGCS is a minimal stand-in for the GCS JSON and download APIs
+// that is used when GCS_ENDPOINT does not point to a fake-gcs-server.
+type fakeGCS struct {
+	sync.Mutex
+	objects map[string]fakeGCSObject
+}
+
+type fakeGCSObject struct {
+	data    []byte
+	updated time.Time
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/upload/storage/v1/b/") && r.Method == http.MethodPost:
+		bucket := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/upload/storage/v1/b/"), "/o")
+
+		_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		mr := multipart.NewReader(r.Body, params["boundary"])
+
+		meta := map[string]interface{}{}
+		part, err := mr.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(part).Decode(&meta)
+
+		part, err = mr.NextPart()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := ioutil.ReadAll(part)
+
+		name, _ := meta["name"].(string)
+		f.objects[bucket+"/"+name] = fakeGCSObject{data: data, updated: time.Now().UTC()}
+
+		f.attrs(w, bucket, name)
+	case strings.HasPrefix(r.URL.Path, "/storage/v1/b/"):
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/storage/v1/b/"), "/o/", 2)
+		if len(parts) < 2 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Method == http.MethodDelete {
+			if _, found := f.objects[parts[0]+"/"+parts[1]]; !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			delete(f.objects, parts[0]+"/"+parts[1])
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		f.attrs(w, parts[0], parts[1])
+	case r.Method == http.MethodGet:
+		o, found := f.objects[strings.TrimPrefix(r.URL.Path, "/")]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write(o.data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeGCS) attrs(w http.ResponseWriter, bucket, name string) {
+	o, found := f.objects[bucket+"/"+name]
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"bucket":  bucket,
+		"name":    name,
+		"size":    strconv.Itoa(len(o.data)),
+		"updated": o.updated.Format(time.RFC3339Nano),
+	})
+}
+
+func setupGCSStore(t *testing.T) (*GCSStore, *catalog.File, func()) {
+	cleanup := fakeEndpoint(gcsEndpoint, &fakeGCS{objects: map[string]fakeGCSObject{}}, "")
+
+	s := new(GCSStore)
+
+	file, err := preStore(t, s, map[string]string{
+		gcsBucketSetting: "cstore-test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, file, cleanup
+}
+
+func TestEnsureGCSVersionsAreRetrievedIndependently(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupGCSStore(t)
+	defer cleanup()
+
+	pushes := []struct {
+		version string
+		data    string
+	}{
+		{"", "ENV=working"},
+		{"v1.0.0", "ENV=v1"},
+		{"v2.0.0", "ENV=v2"},
+	}
+
+	// act
+	for _, p := range pushes {
+		if err := s.Push(file, []byte(p.data), p.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	for _, p := range pushes {
+		b, _, err := s.Pull(file, p.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != p.data {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", p.data, string(b))
+		}
+
+		s.Purge(file, p.version)
+	}
+}
+
+func TestEnsureGCSChangedIsDrivenByObjectMetadata(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupGCSStore(t)
+	defer cleanup()
+
+	if changed, err := s.Changed(file, []byte{}, ""); err != nil {
+		t.Fatal(err)
+	} else if !changed.IsZero() {
+		t.Errorf("\nEXPECTED: zero time \nACTUAL: %s", changed)
+	}
+
+	// act
+	if err := s.Push(file, []byte("ENV=dev"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if changed, err := s.Changed(file, []byte{}, ""); err != nil {
+		t.Error(err)
+	} else if changed.IsZero() {
+		t.Errorf("\nEXPECTED: updated time \nACTUAL: zero time")
+	}
+
+	if err := s.Purge(file, ""); err != nil {
+		t.Error(err)
+	}
+
+	if _, _, err := s.Pull(file, ""); err == nil {
+		t.Errorf("\nEXPECTED: file purged \nACTUAL: file retrieved")
+	}
+}
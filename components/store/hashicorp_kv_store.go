@@ -0,0 +1,357 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/hashicorp"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	hcKVVersionPrefix = "VAULT_KV_VERSION_"
+
+	hcKVContentKey  = "content"
+	hcKVEncodingKey = "encoding"
+	hcKVBase64      = "base64"
+)
+
+// HashiCorpKVStore ...
+type HashiCorpKVStore struct {
+	Client *api.Client
+
+	clog catalog.Catalog
+
+	uo cfg.UserOptions
+	io models.IO
+
+	mount string
+}
+
+// Name ...
+func (s HashiCorpKVStore) Name() string {
+	return "hashicorp-kv"
+}
+
+// Capabilities ...
+func (s HashiCorpKVStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning:        true,
+		ConditionalWrites: true,
+		ClientEncryption:  true,
+		Binary:            true,
+	}
+}
+
+// Description ...
+func (s HashiCorpKVStore) Description() string {
+	return `
+	detail: https://github.com/turnerlabs/cstore/v4/blob/master/docs/HASHICORP_VAULT.md
+`
+}
+
+// Pre ...
+func (s *HashiCorpKVStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+	s.uo = uo
+	s.io = io
+
+	//------------------------------------------
+	//- Store Configuration
+	//------------------------------------------
+	mount, err := setting.Setting{
+		Description:  "Mount of the KV version 2 secrets engine that will store the file.",
+		Prop:         hashicorp.MountSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		DefaultValue: clog.GetDataByStore(s.Name(), hashicorp.MountSetting, hashicorp.DefaultMount),
+		Vault:        file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	if len(mount) == 0 {
+		mount = hashicorp.DefaultMount
+	}
+
+	s.mount = mount
+
+	//------------------------------------------
+	//- Authenticate
+	//------------------------------------------
+	s.Client, err = hashicorp.Client(clog, file, access, uo, io)
+
+	return err
+}
+
+// Push ...
+func (s HashiCorpKVStore) Push(file *catalog.File, fileData []byte, version string) error {
+	_, err := s.PushIf(file, fileData, version, "")
+	return err
+}
+
+// PushIf ...
+func (s HashiCorpKVStore) PushIf(file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	if len(fileData) == 0 {
+		return "", errors.New("empty file")
+	}
+
+	//------------------------------------------
+	//- Check-and-set the KV version last seen.
+	//------------------------------------------
+	cas := -1
+	if len(revision) > 0 {
+		v, err := strconv.Atoi(revision)
+		if err != nil {
+			return "", fmt.Errorf("invalid revision %s", revision)
+		}
+
+		cas = v
+	}
+
+	data := map[string]interface{}{
+		hcKVContentKey: string(fileData),
+	}
+
+	if !utf8.Valid(fileData) {
+		data[hcKVContentKey] = base64.StdEncoding.EncodeToString(fileData)
+		data[hcKVEncodingKey] = hcKVBase64
+	}
+
+	stored, err := hashicorp.Write(s.Client, s.mount, s.key(file), data, cas)
+	if err != nil {
+		if cas > -1 && strings.Contains(err.Error(), "check-and-set") {
+			return "", contract.ErrRevisionConflict
+		}
+
+		return "", err
+	}
+
+	file.AddData(map[string]string{
+		hashicorp.MountSetting: s.mount,
+	})
+
+	//------------------------------------------
+	//- Map the user version to the KV version
+	//- in the catalog and the secret metadata,
+	//- so other catalogs can pull the version.
+	//------------------------------------------
+	if len(version) > 0 {
+		file.AddData(map[string]string{
+			hcKVVersionPrefix + version: strconv.Itoa(stored.Number),
+		})
+
+		if err := s.label(file, version, strconv.Itoa(stored.Number)); err != nil {
+			display.Warn(fmt.Errorf("%s version was not saved in %s metadata, so only this catalog can pull it (%s)", version, s.key(file), err), s.io.UserOutput)
+		}
+	}
+
+	return strconv.Itoa(stored.Number), nil
+}
+
+// Pull ...
+func (s HashiCorpKVStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+
+	kvVersion, err := s.kvVersion(file, version)
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	data, meta, err := hashicorp.Read(s.Client, s.mount, s.key(file), kvVersion)
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	//------------------------------------------
+	//- Every push creates a new version of the
+	//- same secret, so only the current version
+	//- has a revision.
+	//------------------------------------------
+	attr := contract.Attributes{Modified: meta.Created}
+	if len(version) == 0 {
+		attr.Revision = strconv.Itoa(meta.Number)
+	}
+
+	content, ok := data[hcKVContentKey].(string)
+	if !ok {
+		return []byte{}, contract.Attributes{}, fmt.Errorf("%s missing file content", s.key(file))
+	}
+
+	b := []byte(content)
+
+	if encoding, _ := data[hcKVEncodingKey].(string); encoding == hcKVBase64 {
+		if b, err = base64.StdEncoding.DecodeString(content); err != nil {
+			return []byte{}, contract.Attributes{}, err
+		}
+	}
+
+	sized := attributes(b)
+	attr.Size, attr.Checksum = sized.Size, sized.Checksum
+
+	return b, attr, nil
+}
+
+// Purge ...
+func (s HashiCorpKVStore) Purge(file *catalog.File, version string) error {
+
+	if len(version) == 0 {
+		return hashicorp.DeleteAll(s.Client, s.mount, s.key(file))
+	}
+
+	meta, err := hashicorp.ReadMetadata(s.Client, s.mount, s.key(file))
+	if err != nil {
+		return err
+	}
+
+	kvVersion, found := s.versionOf(file, version, meta)
+	if !found {
+		return fmt.Errorf("version %s not found in the catalog or %s metadata", version, s.key(file))
+	}
+
+	//------------------------------------------
+	//- The current KV version is also the file
+	//- pulled without a version, so destroying
+	//- it would break pulls.
+	//------------------------------------------
+	if kvVersion == meta.CurrentVersion {
+		return fmt.Errorf("%s is the current version of %s and cannot be purged until a newer version is pushed", version, file.ActualPath())
+	}
+
+	if err := hashicorp.Destroy(s.Client, s.mount, s.key(file), kvVersion); err != nil {
+		return err
+	}
+
+	delete(file.Data, hcKVVersionPrefix+version)
+
+	if _, found := meta.Custom[hcKVVersionPrefix+version]; found {
+		delete(meta.Custom, hcKVVersionPrefix+version)
+
+		return hashicorp.WriteCustomMetadata(s.Client, s.mount, s.key(file), meta.Custom)
+	}
+
+	return nil
+}
+
+// Changed ...
+func (s HashiCorpKVStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+
+	meta, err := hashicorp.ReadMetadata(s.Client, s.mount, s.key(file))
+	if err != nil {
+		if err == contract.ErrSecretNotFound {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	if len(version) == 0 {
+		return meta.Updated, nil
+	}
+
+	kvVersion, found := s.versionOf(file, version, meta)
+	if !found {
+		return time.Time{}, nil
+	}
+
+	if v, found := meta.Versions[kvVersion]; found && !v.Destroyed {
+		return v.Created, nil
+	}
+
+	return time.Time{}, nil
+}
+
+// Revision ...
+func (s HashiCorpKVStore) Revision(file *catalog.File, version string) (string, error) {
+
+	meta, err := hashicorp.ReadMetadata(s.Client, s.mount, s.key(file))
+	if err != nil {
+		if err == contract.ErrSecretNotFound {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strconv.Itoa(meta.CurrentVersion), nil
+}
+
+//------------------------------------------
+//- Lookup the KV version for a user version.
+//------------------------------------------
+func (s HashiCorpKVStore) kvVersion(file *catalog.File, version string) (int, error) {
+
+	if len(version) == 0 {
+		return 0, nil
+	}
+
+	if value, found := file.Data[hcKVVersionPrefix+version]; found {
+		return strconv.Atoi(value)
+	}
+
+	meta, err := hashicorp.ReadMetadata(s.Client, s.mount, s.key(file))
+	if err != nil && err != contract.ErrSecretNotFound {
+		return 0, err
+	}
+
+	kvVersion, found := s.versionOf(file, version, meta)
+	if !found {
+		return 0, fmt.Errorf("version %s not found in the catalog or %s metadata", version, s.key(file))
+	}
+
+	return kvVersion, nil
+}
+
+//------------------------------------------
+//- Versions pushed from other catalogs are
+//- only recorded in the secret metadata.
+//------------------------------------------
+func (s HashiCorpKVStore) versionOf(file *catalog.File, version string, meta hashicorp.Metadata) (int, bool) {
+
+	value, found := file.Data[hcKVVersionPrefix+version]
+	if !found {
+		value, found = meta.Custom[hcKVVersionPrefix+version]
+	}
+
+	if !found {
+		return 0, false
+	}
+
+	kvVersion, err := strconv.Atoi(value)
+
+	return kvVersion, err == nil
+}
+
+func (s HashiCorpKVStore) label(file *catalog.File, version, kvVersion string) error {
+
+	meta, err := hashicorp.ReadMetadata(s.Client, s.mount, s.key(file))
+	if err != nil {
+		return err
+	}
+
+	meta.Custom[hcKVVersionPrefix+version] = kvVersion
+
+	return hashicorp.WriteCustomMetadata(s.Client, s.mount, s.key(file), meta.Custom)
+}
+
+func (s HashiCorpKVStore) key(file *catalog.File) string {
+	return fmt.Sprintf("%s/%s", s.clog.Context, file.ActualPath())
+}
+
+func init() {
+	s := new(HashiCorpKVStore)
+	stores[s.Name()] = s
+}
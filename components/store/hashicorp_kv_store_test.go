@@ -0,0 +1,171 @@
+package store
+
+import (
+	"os"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/hashicorp/hashicorptest"
+)
+
+func setupHashiCorpKVStore(t *testing.T) (*HashiCorpKVStore, *catalog.File, func()) {
+	cleanup := fakeEndpoint("VAULT_ADDR", hashicorptest.NewKV(), "")
+	unset := setenv(map[string]string{"VAULT_TOKEN": "root"})
+
+	s := new(HashiCorpKVStore)
+
+	file, err := preStore(t, s, map[string]string{
+		"VAULT_ADDR":        os.Getenv("VAULT_ADDR"),
+		"VAULT_AUTH_METHOD": "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s, file, func() {
+		unset()
+		cleanup()
+	}
+}
+
+func TestEnsureHashiCorpKVVersionsAreRetrievedIndependently(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupHashiCorpKVStore(t)
+	defer cleanup()
+
+	pushes := []struct {
+		version string
+		data    string
+	}{
+		{"", "ENV=working"},
+		{"v1.0.0", "ENV=v1"},
+		{"v2.0.0", "ENV=v2"},
+	}
+
+	// act
+	for _, p := range pushes {
+		if err := s.Push(file, []byte(p.data), p.version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	for _, p := range pushes[1:] {
+		b, _, err := s.Pull(file, p.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(b) != p.data {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", p.data, string(b))
+		}
+	}
+
+	b, _, err := s.Pull(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != pushes[2].data {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", pushes[2].data, string(b))
+	}
+
+	s.Purge(file, "")
+}
+
+func TestEnsureHashiCorpKVPurgedVersionIsNotRetrieved(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupHashiCorpKVStore(t)
+	defer cleanup()
+
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := s.Push(file, []byte("VER="+v), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	if err := s.Purge(file, "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if _, _, err := s.Pull(file, "v1.0.0"); err == nil {
+		t.Errorf("\nEXPECTED: v1.0.0 purged \nACTUAL: v1.0.0 retrieved")
+	}
+
+	if b, _, err := s.Pull(file, "v2.0.0"); err != nil {
+		t.Error(err)
+	} else if string(b) != "VER=v2.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "VER=v2.0.0", string(b))
+	}
+
+	if changed, err := s.Changed(file, []byte{}, "v2.0.0"); err != nil {
+		t.Error(err)
+	} else if changed.IsZero() {
+		t.Errorf("\nEXPECTED: v2.0.0 changed time \nACTUAL: zero time")
+	}
+
+	s.Purge(file, "")
+}
+
+func TestEnsureHashiCorpKVCurrentVersionIsNotPurged(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupHashiCorpKVStore(t)
+	defer cleanup()
+
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := s.Push(file, []byte("VER="+v), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	err := s.Purge(file, "v2.0.0")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "current version error", err)
+	}
+
+	if b, _, err := s.Pull(file, ""); err != nil {
+		t.Error(err)
+	} else if string(b) != "VER=v2.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "VER=v2.0.0", string(b))
+	}
+
+	s.Purge(file, "")
+}
+
+func TestEnsureHashiCorpKVVersionMissingFromCatalogIsPulled(t *testing.T) {
+	// arrange
+	s, file, cleanup := setupHashiCorpKVStore(t)
+	defer cleanup()
+
+	for _, v := range []string{"v1.0.0", "v2.0.0"} {
+		if err := s.Push(file, []byte("VER="+v), v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	other := testFile(file.Data)
+	delete(other.Data, hcKVVersionPrefix+"v1.0.0")
+
+	// act
+	b, _, err := s.Pull(other, "v1.0.0")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(b) != "VER=v1.0.0" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "VER=v1.0.0", string(b))
+	}
+
+	if _, _, err := s.Pull(other, "v3.0.0"); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "version not found error", err)
+	}
+
+	s.Purge(file, "")
+}
@@ -0,0 +1,255 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/local"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	localFSDirSetting = "LOCAL_FS_STORE_DIR"
+
+	localFSDefaultDir = "local-fs"
+)
+
+// LocalFSStore ...
+type LocalFSStore struct {
+	clog catalog.Catalog
+
+	uo cfg.UserOptions
+	io models.IO
+
+	dir setting.Setting
+}
+
+// Name ...
+func (s LocalFSStore) Name() string {
+	return "local-fs"
+}
+
+// Capabilities ...
+func (s LocalFSStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		Versioning:       true,
+		ClientEncryption: true,
+		Binary:           true,
+	}
+}
+
+// Description ...
+func (s LocalFSStore) Description() string {
+	return `
+	detail: https://github.com/turnerlabs/cstore/v4/blob/master/docs/LOCAL_FS.md
+`
+}
+
+// Pre ...
+func (s *LocalFSStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.clog = clog
+	s.uo = uo
+	s.io = io
+
+	//------------------------------------------
+	//- Store Configuration
+	//------------------------------------------
+	s.dir = setting.Setting{
+		Description:  "Directory that will store the file. Share or back up this directory to make the files available on other machines.",
+		Prop:         localFSDirSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		DefaultValue: clog.GetDataByStore(s.Name(), localFSDirSetting, local.BuildPath(localFSDefaultDir)),
+		Vault:        file,
+	}
+
+	return nil
+}
+
+// Push ...
+func (s LocalFSStore) Push(file *catalog.File, fileData []byte, version string) error {
+
+	if len(fileData) == 0 {
+		return errors.New("empty file")
+	}
+
+	dir, err := s.dir.Get(s.clog.Context, s.io)
+	if err != nil {
+		return err
+	}
+
+	if len(dir) == 0 {
+		dir = s.dir.DefaultValue
+	}
+
+	file.AddData(map[string]string{
+		localFSDirSetting: dir,
+	})
+
+	fullPath, err := s.key(dir, file.ActualPath(), version)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0700); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(fullPath, fileData, 0600)
+}
+
+// Pull ...
+func (s LocalFSStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+
+	dir, err := s.storedDir()
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	fullPath, err := s.key(dir, file.ActualPath(), version)
+	if err != nil {
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	b, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []byte{}, contract.Attributes{}, fmt.Errorf("%s not found in %s", file.ActualPath(), dir)
+		}
+
+		return []byte{}, contract.Attributes{}, err
+	}
+
+	attr := attributes(b)
+	attr.Revision = revisionOf(b)
+
+	if info, err := os.Stat(fullPath); err == nil {
+		attr.Modified = info.ModTime()
+	}
+
+	return b, attr, nil
+}
+
+// Purge ...
+func (s LocalFSStore) Purge(file *catalog.File, version string) error {
+
+	dir, err := s.storedDir()
+	if err != nil {
+		return err
+	}
+
+	fullPath, err := s.key(dir, file.ActualPath(), version)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// Changed ...
+func (s LocalFSStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+
+	dir, err := s.storedDir()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	fullPath, err := s.key(dir, file.ActualPath(), version)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return time.Time{}, nil
+		}
+
+		return time.Time{}, err
+	}
+
+	return info.ModTime(), nil
+}
+
+// Revision ...
+func (s LocalFSStore) Revision(file *catalog.File, version string) (string, error) {
+
+	dir, err := s.storedDir()
+	if err != nil {
+		return "", err
+	}
+
+	fullPath, err := s.key(dir, file.ActualPath(), version)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return revisionOf(b), nil
+}
+
+func (s LocalFSStore) storedDir() (string, error) {
+	setting := s.dir
+	setting.Prompt = false
+
+	dir, err := setting.Get(s.clog.Context, s.io)
+	if err != nil {
+		return dir, err
+	}
+
+	if len(dir) == 0 {
+		return setting.DefaultValue, nil
+	}
+
+	return dir, nil
+}
+
+//------------------------------------------
+//- Create the file location in the store.
+//- Versions and paths cannot leave the
+//- store directory.
+//------------------------------------------
+func (s LocalFSStore) key(dir, path, version string) (string, error) {
+
+	if version == "." || version == ".." || strings.ContainsAny(version, `/\`) {
+		return "", fmt.Errorf("invalid version %s", version)
+	}
+
+	root := filepath.Join(dir, s.clog.Context)
+
+	fullPath := filepath.Join(root, version, path)
+
+	rel, err := filepath.Rel(root, fullPath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside of %s", path, dir)
+	}
+
+	return fullPath, nil
+}
+
+func init() {
+	s := new(LocalFSStore)
+	stores[s.Name()] = s
+}
@@ -0,0 +1,370 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	mirrorPrimarySetting     = "MIRROR_PRIMARY_STORE"
+	mirrorSecondariesSetting = "MIRROR_SECONDARY_STORES"
+
+	mirrorDataPrefix = "MIRROR_%d_"
+)
+
+// MirrorStore pushes and purges files in a primary store and one or more
+// secondary stores. Files are pulled from the primary store and from the
+// secondary stores when the primary store fails.
+type MirrorStore struct {
+	io models.IO
+
+	replicas []replica
+}
+
+// replica is a store holding a copy of the file. Each replica keeps its
+// own catalog file data, so the same store can be used more than once.
+type replica struct {
+	contract.IStore
+
+	prefix string
+}
+
+// ReplicaStatus describes the copy of a file in a replica compared to
+// the copy in the primary store.
+type ReplicaStatus struct {
+	Store    string
+	Primary  bool
+	Checksum string
+	InSync   bool
+	Err      error
+}
+
+// Name ...
+func (s MirrorStore) Name() string {
+	return "mirror"
+}
+
+// Capabilities are shared by all replicas once the file is configured.
+func (s MirrorStore) Capabilities() contract.Capabilities {
+	c := contract.Capabilities{
+		Versioning:       true,
+		ClientEncryption: true,
+		Binary:           true,
+	}
+
+	for _, r := range s.replicas {
+		rc := r.Capabilities()
+
+		c.Versioning = c.Versioning && rc.Versioning
+		c.ClientEncryption = c.ClientEncryption && rc.ClientEncryption
+		c.Binary = c.Binary && rc.Binary
+
+		if rc.MaxSize > 0 && (c.MaxSize == 0 || rc.MaxSize < c.MaxSize) {
+			c.MaxSize = rc.MaxSize
+		}
+
+		types, shared := sharedFileTypes(c.FileTypes, rc.FileTypes)
+
+		c.FileTypes = types
+		c.NoFileTypes = c.NoFileTypes || rc.NoFileTypes || !shared
+	}
+
+	return c
+}
+
+// Description ...
+func (s MirrorStore) Description() string {
+	return `
+	detail: https://github.com/turnerlabs/cstore/v4/blob/master/docs/MIRROR.md
+`
+}
+
+// Pre ...
+func (s *MirrorStore) Pre(clog catalog.Catalog, file *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	s.io = io
+	s.replicas = []replica{}
+
+	//------------------------------------------
+	//- Store Configuration
+	//------------------------------------------
+	primary, err := setting.Setting{
+		Description: "Store files are pushed to and pulled from.",
+		Prop:        mirrorPrimarySetting,
+		Prompt:      uo.Prompt,
+		Silent:      uo.Silent,
+		AutoSave:    true,
+		Vault:       file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	secondaries, err := setting.Setting{
+		Description: "Comma delimited list of stores files are copied to and pulled from when the primary store fails.",
+		Prop:        mirrorSecondariesSetting,
+		Prompt:      uo.Prompt,
+		Silent:      uo.Silent,
+		AutoSave:    true,
+		Vault:       file,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	names := []string{primary}
+	for _, name := range strings.Split(secondaries, ",") {
+		if name = strings.TrimSpace(name); len(name) > 0 {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) < 2 {
+		return fmt.Errorf("%s store requires a primary and at least one secondary store", s.Name())
+	}
+
+	//------------------------------------------
+	//- Prepare each replica with its own copy
+	//- of the file data.
+	//------------------------------------------
+	for i, name := range names {
+		st, found := Get()[name]
+		if !found || name == s.Name() {
+			return fmt.Errorf("%s (%s)", contract.ErrStoreNotFound, name)
+		}
+
+		r := replica{
+			IStore: clone(st),
+			prefix: fmt.Sprintf(mirrorDataPrefix, i),
+		}
+
+		if !r.Capabilities().SupportsFileType(file.Type) {
+			return contract.UnsupportedError{Store: name, Capability: fmt.Sprintf("%s files", file.Type)}
+		}
+
+		f := r.file(file)
+		if err := r.Pre(clog, f, access, uo, io); err != nil {
+			return fmt.Errorf("%s replica failed (%s)", name, err)
+		}
+		r.save(file, f)
+
+		s.replicas = append(s.replicas, r)
+	}
+
+	return nil
+}
+
+// Push stores the file in the primary store and then each secondary
+// store. Failures in secondary stores are reported, but do not fail
+// the push.
+func (s MirrorStore) Push(file *catalog.File, fileData []byte, version string) error {
+	for i, r := range s.replicas {
+		f := r.file(file)
+		err := r.Push(f, fileData, version)
+		r.save(file, f)
+
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+
+			display.Warn(fmt.Errorf("%s replica was not updated (%s); run 'cstore verify-replicas' to check for drift", r.Name(), err), s.io.UserOutput)
+		}
+	}
+
+	return nil
+}
+
+// Pull retrieves the file from the primary store or the first secondary
+// store holding the file when the primary store fails.
+func (s MirrorStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
+	var primaryErr error
+
+	for i, r := range s.replicas {
+		f := r.file(file)
+		b, attr, err := r.Pull(f, version)
+		r.save(file, f)
+
+		if err == nil {
+			if i > 0 {
+				display.Warn(fmt.Errorf("%s pulled from %s replica, because the primary store failed (%s)", file.ActualPath(), r.Name(), primaryErr), s.io.UserOutput)
+			}
+
+			return b, attr, nil
+		}
+
+		if i == 0 {
+			primaryErr = err
+		}
+	}
+
+	return []byte{}, contract.Attributes{}, primaryErr
+}
+
+// Purge deletes the file from every replica.
+func (s MirrorStore) Purge(file *catalog.File, version string) error {
+	for i, r := range s.replicas {
+		f := r.file(file)
+		err := r.Purge(f, version)
+		r.save(file, f)
+
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+
+			display.Warn(fmt.Errorf("%s was not purged from %s replica (%s)", file.ActualPath(), r.Name(), err), s.io.UserOutput)
+		}
+	}
+
+	return nil
+}
+
+// Changed ...
+func (s MirrorStore) Changed(file *catalog.File, fileData []byte, version string) (time.Time, error) {
+	if len(s.replicas) == 0 {
+		return time.Time{}, nil
+	}
+
+	r := s.replicas[0]
+	return r.Changed(r.file(file), fileData, version)
+}
+
+// Revision ...
+func (s MirrorStore) Revision(file *catalog.File, version string) (string, error) {
+	if len(s.replicas) == 0 {
+		return "", nil
+	}
+
+	r := s.replicas[0]
+	return r.Revision(r.file(file), version)
+}
+
+// Stat ...
+func (s MirrorStore) Stat(file *catalog.File, version string) (contract.Attributes, error) {
+	if len(s.replicas) == 0 {
+		return contract.Attributes{}, nil
+	}
+
+	r := s.replicas[0]
+	return Stat(r.IStore, r.file(file), version)
+}
+
+// Verify compares the copy of the file in each secondary store with
+// the copy in the primary store.
+func (s MirrorStore) Verify(file *catalog.File, version string) []ReplicaStatus {
+	statuses := []ReplicaStatus{}
+
+	var primary []byte
+
+	for i, r := range s.replicas {
+		status := ReplicaStatus{
+			Store:   r.Name(),
+			Primary: i == 0,
+		}
+
+		b, _, err := r.Pull(r.file(file), version)
+		if err != nil {
+			status.Err = err
+		} else {
+			status.Checksum = attributes(b).Checksum
+		}
+
+		if i == 0 {
+			primary = b
+			status.InSync = err == nil
+		} else {
+			status.InSync = err == nil && statuses[0].Err == nil && bytes.Equal(primary, b)
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
+
+//------------------------------------------
+//- Replica file data is saved in the file
+//- using the replica prefix.
+//------------------------------------------
+func (r replica) file(parent *catalog.File) *catalog.File {
+	f := *parent
+	f.Store = r.Name()
+	f.Data = map[string]string{}
+
+	for key, value := range parent.Data {
+		if strings.HasPrefix(key, r.prefix) {
+			f.Data[strings.TrimPrefix(key, r.prefix)] = value
+		}
+	}
+
+	return &f
+}
+
+func (r replica) save(parent, f *catalog.File) {
+	for key := range parent.Data {
+		if strings.HasPrefix(key, r.prefix) {
+			delete(parent.Data, key)
+		}
+	}
+
+	for key, value := range f.Data {
+		parent.AddData(map[string]string{r.prefix + key: value})
+	}
+}
+
+//------------------------------------------
+//- Stores are registered once, so a copy is
+//- made for each replica.
+//------------------------------------------
+func clone(store contract.IStore) contract.IStore {
+	v := reflect.ValueOf(store)
+	if v.Kind() != reflect.Ptr {
+		return store
+	}
+
+	c := reflect.New(v.Elem().Type())
+	c.Elem().Set(v.Elem())
+
+	return c.Interface().(contract.IStore)
+}
+
+//------------------------------------------
+//- An empty list accepts any file type, so
+//- lists without a file type in common are
+//- reported as not shared instead of empty.
+//------------------------------------------
+func sharedFileTypes(a, b []string) ([]string, bool) {
+	if len(a) == 0 {
+		return b, true
+	}
+
+	if len(b) == 0 {
+		return a, true
+	}
+
+	shared := []string{}
+	for _, t := range a {
+		for _, u := range b {
+			if t == u {
+				shared = append(shared, t)
+			}
+		}
+	}
+
+	return shared, len(shared) > 0
+}
+
+func init() {
+	s := new(MirrorStore)
+	stores[s.Name()] = s
+}
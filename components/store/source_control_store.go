@@ -3,6 +3,9 @@ package store
 import (
 	"errors"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/turnerlabs/cstore/v4/components/catalog"
@@ -22,23 +25,15 @@ func (s SourceControlStore) Name() string {
 	return "source-control"
 }
 
-// SupportsFeature ...
-func (s SourceControlStore) SupportsFeature(feature string) bool {
-	switch feature {
-	case VersionFeature:
-		return false
-	case SourceControlFeature:
-		return true
-	default:
-		return false
+// Capabilities ...
+func (s SourceControlStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{
+		SourceControl:    true,
+		ClientEncryption: true,
+		Binary:           true,
 	}
 }
 
-// SupportsFileType ...
-func (s SourceControlStore) SupportsFileType(fileType string) bool {
-	return true
-}
-
 // Description ...
 func (s SourceControlStore) Description() string {
 	return `
@@ -66,12 +61,31 @@ func (s SourceControlStore) Push(file *catalog.File, fileData []byte, version st
 // Pull ...
 func (s SourceControlStore) Pull(file *catalog.File, version string) ([]byte, contract.Attributes, error) {
 
-	b, err := localFile.GetBy(s.clog.GetFullPath(file.ActualPath()))
+	fullPath := s.clog.GetFullPath(file.ActualPath())
+
+	b, err := localFile.GetBy(fullPath)
 	if err != nil {
 		return b, contract.Attributes{}, err
 	}
 
-	return b, contract.Attributes{}, nil
+	attr := attributes(b)
+
+	if info, err := os.Stat(fullPath); err == nil {
+		attr.Modified = info.ModTime()
+	}
+
+	//------------------------------------------
+	//- The last commit author is the last writer
+	//- when the file is in a git repository.
+	//------------------------------------------
+	git := exec.Command("git", "log", "-1", "--format=%an <%ae>", "--", filepath.Base(fullPath))
+	git.Dir = filepath.Dir(fullPath)
+
+	if out, err := git.Output(); err == nil {
+		attr.LastWriter = strings.TrimSpace(string(out))
+	}
+
+	return b, attr, nil
 }
 
 // Purge ...
@@ -84,6 +98,11 @@ func (s SourceControlStore) Changed(file *catalog.File, fileData []byte, version
 	return time.Time{}, nil
 }
 
+// Revision ...
+func (s SourceControlStore) Revision(file *catalog.File, version string) (string, error) {
+	return "", nil
+}
+
 func init() {
 	s := new(SourceControlStore)
 	stores[s.Name()] = s
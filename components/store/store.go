@@ -1,24 +1,26 @@
 package store
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"unicode/utf8"
 
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/cipher"
 	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/plugin"
 	"github.com/turnerlabs/cstore/v4/components/prompt"
 )
 
 const (
 	ceKeyName = "CSTORE_ENCRYPTION_KEY"
 
-	// VersionFeature ...
-	VersionFeature = "VERSIONING"
-
-	// SourceControlFeature ...
-	SourceControlFeature = "SOURCE_CONTROL"
-
 	// EnvFeature ...
 	EnvFeature = "env"
 
@@ -28,8 +30,19 @@ const (
 
 var stores = map[string]contract.IStore{}
 
-// Get ...
+var plugins sync.Once
+
+// Get returns the built in stores and any store plugins. Built in
+// stores take precedence over plugins with the same name.
 func Get() map[string]contract.IStore {
+	plugins.Do(func() {
+		for name, p := range plugin.Stores() {
+			if _, found := stores[name]; !found {
+				stores[name] = p
+			}
+		}
+	})
+
 	return stores
 }
 
@@ -37,7 +50,8 @@ func Get() map[string]contract.IStore {
 func Select(file *catalog.File, clog catalog.Catalog, v contract.IVault, uo cfg.UserOptions, io models.IO) (contract.IStore, error) {
 
 	if len(file.Store) > 0 {
-		if store, found := stores[file.Store]; found {
+		if store, found := Get()[file.Store]; found {
+			store = wrap(store, file)
 			return store, store.Pre(clog, file, v, uo, io)
 		}
 
@@ -46,7 +60,7 @@ func Select(file *catalog.File, clog catalog.Catalog, v contract.IVault, uo cfg.
 
 	supportedStores := ""
 	for _, s := range Get() {
-		if s.SupportsFileType(file.Type) {
+		if s.Capabilities().SupportsFileType(file.Type) {
 			if len(supportedStores) == 0 {
 				supportedStores = s.Name()
 			} else {
@@ -60,13 +74,35 @@ func Select(file *catalog.File, clog catalog.Catalog, v contract.IVault, uo cfg.
 		DefaultValue: GetDefaultStoreFor(file.Type),
 	}, io)
 
-	if store, found := stores[val]; found {
+	if store, found := Get()[val]; found {
+		store = wrap(store, file)
 		return store, store.Pre(clog, file, v, uo, io)
 	}
 
 	return nil, contract.ErrStoreNotFound
 }
 
+//------------------------------------------
+//- Add client side encryption when the
+//- file is configured for it.
+//------------------------------------------
+func wrap(store contract.IStore, file *catalog.File) contract.IStore {
+	if Encrypted(*file) {
+		return &EncryptedStore{IStore: store}
+	}
+
+	return store
+}
+
+// Unwrap returns the store without client side encryption.
+func Unwrap(store contract.IStore) contract.IStore {
+	if e, ok := store.(*EncryptedStore); ok {
+		return e.IStore
+	}
+
+	return store
+}
+
 // GetDefaultStoreFor ...
 func GetDefaultStoreFor(fileType string) string {
 	switch fileType {
@@ -76,3 +112,150 @@ func GetDefaultStoreFor(fileType string) string {
 		return S3Store{}.Name()
 	}
 }
+
+// PushIf stores a file only when the remote copy of the file is still at
+// the revision last pulled and returns the revision of the stored file.
+// Stores unable to write conditionally are checked before the push.
+func PushIf(store contract.IStore, file *catalog.File, fileData []byte, version, revision string) (string, error) {
+
+	if s, ok := store.(contract.IConditionalStore); ok {
+		return s.PushIf(file, fileData, version, revision)
+	}
+
+	if len(revision) > 0 {
+		current, err := store.Revision(file, version)
+		if err != nil {
+			return "", err
+		}
+
+		if current != revision {
+			return "", contract.ErrRevisionConflict
+		}
+	}
+
+	if err := store.Push(file, fileData, version); err != nil {
+		return "", err
+	}
+
+	return store.Revision(file, version)
+}
+
+// Stat gets the attributes of the remote copy of a file without pulling
+// it. Stores unable to describe a file only provide the time it changed
+// and its revision.
+func Stat(store contract.IStore, file *catalog.File, version string) (contract.Attributes, error) {
+
+	if s, ok := store.(contract.IStatStore); ok {
+		return s.Stat(file, version)
+	}
+
+	modified, err := store.Changed(file, []byte{}, version)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	revision, err := store.Revision(file, version)
+	if err != nil {
+		return contract.Attributes{}, err
+	}
+
+	return contract.Attributes{Modified: modified, Revision: revision}, nil
+}
+
+// ValidatePush ensures a store is capable of pushing a file before
+// any changes are made locally or remotely.
+func ValidatePush(store contract.IStore, file catalog.File, fileData []byte, version string) error {
+	c := store.Capabilities()
+
+	if err := validate(store, file, version); err != nil {
+		return err
+	}
+
+	if Encrypted(file) && !c.ClientEncryption {
+		return contract.UnsupportedError{Store: store.Name(), Capability: "client side encryption"}
+	}
+
+	if !c.Binary && !utf8.Valid(fileData) {
+		return contract.UnsupportedError{Store: store.Name(), Capability: "binary files"}
+	}
+
+	//------------------------------------------
+	//- Encrypted files are stored as envelopes,
+	//- which are larger than the file.
+	//------------------------------------------
+	if c.MaxSize > 0 && Encrypted(file) && cipher.SealedSize(len(fileData)) > c.MaxSize {
+		return contract.UnsupportedError{Store: store.Name(), Capability: fmt.Sprintf("files larger than %d bytes once encrypted", c.MaxSize)}
+	}
+
+	if c.MaxSize > 0 && len(fileData) > c.MaxSize {
+		return contract.UnsupportedError{Store: store.Name(), Capability: fmt.Sprintf("files larger than %d bytes", c.MaxSize)}
+	}
+
+	return nil
+}
+
+// ValidatePull ensures a store is capable of pulling a file.
+func ValidatePull(store contract.IStore, file catalog.File, version string) error {
+	return validate(store, file, version)
+}
+
+// ValidatePurge ensures a store is capable of purging a file.
+func ValidatePurge(store contract.IStore, file catalog.File, version string) error {
+	return validate(store, file, version)
+}
+
+func validate(store contract.IStore, file catalog.File, version string) error {
+	c := store.Capabilities()
+
+	if !c.SupportsFileType(file.Type) {
+		return contract.UnsupportedError{Store: store.Name(), Capability: fmt.Sprintf("%s files", file.Type)}
+	}
+
+	if len(version) > 0 && !c.Versioning {
+		return contract.UnsupportedError{Store: store.Name(), Capability: "versioning"}
+	}
+
+	return nil
+}
+
+//------------------------------------------
+//- Describe the data retrieved from a store.
+//------------------------------------------
+func attributes(data []byte) contract.Attributes {
+	sum := sha256.Sum256(data)
+
+	return contract.Attributes{
+		Size:     len(data),
+		Checksum: hex.EncodeToString(sum[:]),
+	}
+}
+
+//------------------------------------------
+//- Identify who is pushing a file for stores
+//- without a native last writer.
+//------------------------------------------
+func writer() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	if host, err := os.Hostname(); err == nil {
+		return fmt.Sprintf("%s@%s", name, host)
+	}
+
+	return name
+}
+
+//------------------------------------------
+//- Identify file contents for stores without
+//- native revisions.
+//------------------------------------------
+func revisionOf(data ...[]byte) string {
+	h := sha256.New()
+	for _, d := range data {
+		h.Write(d)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
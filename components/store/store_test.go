@@ -0,0 +1,122 @@
+package store
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cipher"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+)
+
+// fileTypeStore is a local store that only accepts the listed types.
+type fileTypeStore struct {
+	LocalFSStore
+
+	types []string
+}
+
+func (s fileTypeStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{FileTypes: s.types}
+}
+
+// sizeStore is a local store that encrypts files no larger than maxSize.
+type sizeStore struct {
+	LocalFSStore
+
+	maxSize int
+}
+
+func (s sizeStore) Capabilities() contract.Capabilities {
+	return contract.Capabilities{ClientEncryption: true, MaxSize: s.maxSize}
+}
+
+//---------------------------------------------------
+//- When a store is missing a capability a push
+//- requires, the push should be rejected before
+//- anything is stored.
+//---------------------------------------------------
+func TestEnsurePushIsValidatedAgainstStoreCapabilities(t *testing.T) {
+	tests := []struct {
+		name       string
+		store      contract.IStore
+		file       catalog.File
+		data       []byte
+		version    string
+		capability string
+	}{
+		{
+			name:       "versioning",
+			store:      &AWSSecretManagerStore{},
+			file:       catalog.File{Type: "env"},
+			data:       []byte("ENV=dev"),
+			version:    "v1.0.0",
+			capability: "versioning",
+		},
+		{
+			name:       "file type",
+			store:      &AWSParameterStore{},
+			file:       catalog.File{Type: "yml"},
+			data:       []byte("ENV: dev"),
+			capability: "yml files",
+		},
+		{
+			name: "no shared file type",
+			store: &MirrorStore{replicas: []replica{
+				{IStore: &AWSParameterStore{}},
+				{IStore: &fileTypeStore{types: []string{"yml"}}},
+			}},
+			file:       catalog.File{Type: "env"},
+			data:       []byte("ENV=dev"),
+			capability: "env files",
+		},
+		{
+			name:       "binary",
+			store:      &AWSParameterStore{},
+			file:       catalog.File{Type: "env"},
+			data:       []byte{0xff, 0xfe, 0xfd},
+			capability: "binary files",
+		},
+		{
+			name:       "encrypted size",
+			store:      &sizeStore{maxSize: 1024},
+			file:       catalog.File{Type: "env", Data: map[string]string{ceAlgorithmSetting: cipher.EnvelopeAlgorithm}},
+			data:       bytes.Repeat([]byte("A"), 900),
+			capability: "files larger than 1024 bytes once encrypted",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// act
+			err := ValidatePush(test.store, test.file, test.data, test.version)
+
+			// assert
+			unsupported, ok := err.(contract.UnsupportedError)
+			if !ok {
+				t.Fatalf("\nEXPECTED: %s \nACTUAL: %v", "UnsupportedError", err)
+			}
+
+			if unsupported.Capability != test.capability {
+				t.Errorf("\nEXPECTED: %s \nACTUAL: %s", test.capability, unsupported.Capability)
+			}
+		})
+	}
+}
+
+//---------------------------------------------------
+//- When a store has the capabilities a push
+//- requires, the push should be allowed.
+//---------------------------------------------------
+func TestEnsureSupportedPushIsValid(t *testing.T) {
+	// arrange
+	file := catalog.File{Type: "json"}
+
+	// act
+	err := ValidatePush(&S3Store{}, file, []byte{0xff, 0xfe}, "v1.0.0")
+
+	// assert
+	if err != nil {
+		t.Errorf("\nEXPECTED: %v \nACTUAL: %s", nil, err)
+	}
+}
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// throttledRequest is a named call made by a throttle.
+type throttledRequest struct {
+	name string
+	send func() error
+}
+
+// throttle sends requests with a bounded number of workers at a limited
+// rate. Requests failing with a retryable error are retried with jittered
+// exponential backoff.
+type throttle struct {
+	workers   int
+	limiter   *limiter
+	retries   int
+	backoff   time.Duration
+	retryable func(error) bool
+}
+
+func newThrottle(workers int, rate float64, retryable func(error) bool) throttle {
+	return throttle{
+		workers:   workers,
+		limiter:   newLimiter(rate, workers),
+		retries:   5,
+		backoff:   200 * time.Millisecond,
+		retryable: retryable,
+	}
+}
+
+// run sends the requests and reports progress as each completes. After a
+// request fails, requests not yet started are skipped and the name of the
+// failed request is returned with the error.
+func (t throttle) run(requests []throttledRequest, progress func(done, total int)) (string, error) {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		done   int
+		failed string
+		first  error
+	)
+
+	jobs := make(chan throttledRequest)
+
+	workers := t.workers
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for r := range jobs {
+				err := t.send(r)
+
+				mu.Lock()
+				done++
+				if err != nil && first == nil {
+					failed, first = r.name, err
+				}
+				if progress != nil {
+					progress(done, len(requests))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, r := range requests {
+		mu.Lock()
+		stop := first != nil
+		mu.Unlock()
+
+		if stop {
+			break
+		}
+
+		jobs <- r
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return failed, first
+}
+
+func (t throttle) send(r throttledRequest) error {
+	for attempt := 0; ; attempt++ {
+		t.limiter.wait()
+
+		err := r.send()
+		if err == nil || attempt >= t.retries || !t.retryable(err) {
+			return err
+		}
+
+		//------------------------------------------
+		//- Full jitter keeps workers throttled at
+		//- the same time from retrying together.
+		//------------------------------------------
+		time.Sleep(time.Duration(rand.Int63n(int64(t.backoff << uint(attempt)))))
+	}
+}
+
+// limiter is a token bucket allowing rate requests per second with bursts
+// of up to burst requests.
+type limiter struct {
+	sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait blocks until a token is available.
+func (l *limiter) wait() {
+	l.Lock()
+
+	now := time.Now()
+
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+	l.tokens--
+
+	delay := time.Duration(0)
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+
+	l.Unlock()
+
+	time.Sleep(delay)
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errThrottled = errors.New("throttled")
+
+func TestEnsureThrottleBoundsWorkersAndRetries(t *testing.T) {
+	// arrange
+	th := newThrottle(3, 1000, func(err error) bool { return err == errThrottled })
+	th.backoff = time.Millisecond
+
+	var (
+		mu       sync.Mutex
+		running  int
+		max      int
+		attempts = map[string]int{}
+	)
+
+	requests := []throttledRequest{}
+
+	for i := 0; i < 30; i++ {
+		name := fmt.Sprintf("p%d", i)
+
+		requests = append(requests, throttledRequest{
+			name: name,
+			send: func() error {
+				mu.Lock()
+				running++
+				if running > max {
+					max = running
+				}
+				attempts[name]++
+				first := attempts[name] == 1
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				running--
+				mu.Unlock()
+
+				if first {
+					return errThrottled
+				}
+
+				return nil
+			},
+		})
+	}
+
+	done := 0
+
+	// act
+	_, err := th.run(requests, func(d, total int) { done = d })
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if max > 3 {
+		t.Errorf("\nEXPECTED: %d workers \nACTUAL: %d workers", 3, max)
+	}
+
+	if done != len(requests) {
+		t.Errorf("\nEXPECTED: %d \nACTUAL: %d", len(requests), done)
+	}
+
+	for name, count := range attempts {
+		if count != 2 {
+			t.Errorf("\nEXPECTED: %s sent %d times \nACTUAL: %d", name, 2, count)
+		}
+	}
+}
+
+func TestEnsureThrottleStopsAfterError(t *testing.T) {
+	// arrange
+	th := newThrottle(1, 1000, func(err error) bool { return false })
+
+	sent := 0
+	fail := errors.New("access denied")
+
+	requests := []throttledRequest{
+		{name: "first", send: func() error { sent++; return nil }},
+		{name: "second", send: func() error { sent++; return fail }},
+		{name: "third", send: func() error { sent++; return nil }},
+		{name: "fourth", send: func() error { sent++; return nil }},
+	}
+
+	// act
+	name, err := th.run(requests, nil)
+
+	// assert
+	if err != fail || name != "second" {
+		t.Errorf("\nEXPECTED: %s %s \nACTUAL: %s %v", "second", fail, name, err)
+	}
+
+	if sent > 3 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %d sent", "requests skipped after error", sent)
+	}
+}
+
+func TestEnsureLimiterKeepsRate(t *testing.T) {
+	// arrange
+	l := newLimiter(100, 1)
+
+	start := time.Now()
+
+	// act
+	for i := 0; i < 21; i++ {
+		l.wait()
+	}
+
+	// assert
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "at least 200ms", elapsed)
+	}
+}
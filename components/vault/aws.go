@@ -1,15 +1,10 @@
 package vault
 
 const (
-	awsRegion          = "AWS_REGION"
-	awsProfile         = "AWS_PROFILE"
-	awsSecretAccessKey = "AWS_SECRET_ACCESS_KEY"
-	awsAccessKeyID     = "AWS_ACCESS_KEY_ID"
-	awsSessionToken    = "AWS_SESSION_TOKEN"
+	awsProfile = "AWS_PROFILE"
 
 	awsVaultKMSKeyID = "AWS_VAULT_KMS_KEY_ID"
 
-	awsDefaultRegion  = "us-east-1"
 	awsDefaultProfile = "default"
 
 	defaultKMSKey = "aws/secretsmanager"
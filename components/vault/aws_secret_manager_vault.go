@@ -6,11 +6,10 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/aws/aws-sdk-go/aws/credentials"
-
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/turnerlabs/cstore/v4/components/awssession"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 	"github.com/turnerlabs/cstore/v4/components/contract"
@@ -62,80 +61,10 @@ func (v *AWSSecretManagerVault) Pre(clog catalog.Catalog, fileEntry *catalog.Fil
 	v.fileEntry = fileEntry
 
 	//------------------------------------------
-	//- Get AWS Region
-	//------------------------------------------
-	region, err := setting.Setting{
-		Description:  "Export as an environment variable to silence this prompt.",
-		Group:        clog.Context,
-		Prop:         awsRegion,
-		Prompt:       uo.Prompt,
-		Silent:       uo.Silent,
-		AutoSave:     true,
-		PromptOnce:   true,
-		DefaultValue: awsDefaultRegion,
-		Vault:        EnvVault{},
-	}.Get(clog.Context, io)
-
-	//------------------------------------------
-	//- Get AWS Credentials from Environment
+	//- Get AWS Session
 	//------------------------------------------
-	if _, ok := access.(EnvVault); ok {
-		v.Session, err = session.NewSession(&aws.Config{
-			Region: aws.String(region),
-		})
-
-		return err
-	}
-
-	//------------------------------------------
-	//- Get AWS Credentials from Vault
-	//------------------------------------------
-	id, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsAccessKeyID,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	secret, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSecretAccessKey,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	token, err := setting.Setting{
-		Description: fmt.Sprintf("Save credential in %s.", access.Name()),
-		Group:       clog.Context,
-		Prop:        awsSessionToken,
-		Prompt:      uo.Prompt,
-		Silent:      uo.Silent,
-		AutoSave:    true,
-		PromptOnce:  true,
-		Vault:       access,
-	}.Get(clog.Context, io)
-	if err != nil {
-		return err
-	}
-
-	v.Session, err = session.NewSession(&aws.Config{
-		Region:      aws.String(region),
-		Credentials: credentials.NewStaticCredentials(id, secret, token),
-	})
+	sess, err := awssession.Get(clog, fileEntry, access, uo, io)
+	v.Session = sess
 
 	return err
 }
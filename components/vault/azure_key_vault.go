@@ -0,0 +1,152 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/turnerlabs/cstore/v4/components/azure"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+const (
+	azureKeyVaultNameSetting = "AZURE_KEY_VAULT_NAME"
+	azureKeyVaultEndpoint    = "AZURE_KEY_VAULT_ENDPOINT"
+)
+
+// AzureKeyVault ...
+type AzureKeyVault struct {
+	URL   string
+	Token string
+
+	clog      catalog.Catalog
+	fileEntry *catalog.File
+
+	uo cfg.UserOptions
+	io models.IO
+}
+
+// Name ...
+func (v AzureKeyVault) Name() string {
+	return "azure-key-vault"
+}
+
+// Description ...
+func (v AzureKeyVault) Description() string {
+	return `
+Secrets are saved and retrieved from Azure Key Vault.
+
+Placing secret tokens in the file {{ENV/KEY::SECRET}} will remove and push secrets into Azure Key Vault.
+
+Using '-i' cli flag during a pull, will inject secrets into a copy of the file created with a '.secrets' extension during the restore.
+
+The Key Vault name is saved in the catalog. The service principal AZURE_TENANT_ID, AZURE_CLIENT_ID, and AZURE_CLIENT_SECRET are retrieved from the access vault.
+
+When used as an access vault, AZURE_TENANT_ID, AZURE_CLIENT_ID, and AZURE_CLIENT_SECRET are read from environment variables.
+`
+}
+
+// BuildKey ...
+func (v AzureKeyVault) BuildKey(contextID, group, prop string) string {
+	return fmt.Sprintf("%s--%s--%s", azureSecretName(contextID), azureSecretName(group), azureSecretName(prop))
+}
+
+//------------------------------------------
+//- Secret names may only contain
+//- alphanumeric characters and dashes, so
+//- other characters and dashes are escaped
+//- as a dash and the hex of each byte. An
+//- escape never contains two dashes, so
+//- "--" separates the key parts.
+//------------------------------------------
+func azureSecretName(value string) string {
+	var name strings.Builder
+
+	for _, b := range []byte(value) {
+		if (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9') {
+			name.WriteByte(b)
+		} else {
+			fmt.Fprintf(&name, "-%02x", b)
+		}
+	}
+
+	return name.String()
+}
+
+// Pre ...
+func (v *AzureKeyVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	v.clog = clog
+	v.uo = uo
+	v.io = io
+
+	v.fileEntry = fileEntry
+
+	//------------------------------------------
+	//- Vault Configuration
+	//------------------------------------------
+	v.URL = os.Getenv(azureKeyVaultEndpoint)
+
+	if len(v.URL) == 0 {
+		name, err := setting.Setting{
+			Description:  "Name of the Azure Key Vault that will store secrets.",
+			Prop:         azureKeyVaultNameSetting,
+			Prompt:       uo.Prompt,
+			Silent:       uo.Silent,
+			AutoSave:     true,
+			PromptOnce:   true,
+			DefaultValue: clog.GetDataByVault(v.Name(), azureKeyVaultNameSetting, ""),
+			Vault:        fileEntry,
+		}.Get(clog.Context, io)
+		if err != nil {
+			return err
+		}
+
+		if len(name) == 0 {
+			return errors.New("Azure Key Vault name is required")
+		}
+
+		v.URL = azure.KeyVaultURL(name)
+	}
+
+	//------------------------------------------
+	//- Authenticate
+	//------------------------------------------
+	var credentials setting.IKeyValueStore = EnvVault{}
+	if access != nil {
+		credentials = access
+	}
+
+	token, err := azure.Token(clog, credentials, azure.KeyVaultScope, uo, io)
+	if err != nil {
+		return err
+	}
+
+	v.Token = token
+
+	return nil
+}
+
+// Set ...
+func (v AzureKeyVault) Set(contextID, group, prop, value string) error {
+	return azure.SetSecret(v.URL, v.Token, v.BuildKey(contextID, group, prop), value)
+}
+
+// Delete ...
+func (v AzureKeyVault) Delete(contextID, group, prop string) error {
+	return azure.DeleteSecret(v.URL, v.Token, v.BuildKey(contextID, group, prop))
+}
+
+// Get ...
+func (v AzureKeyVault) Get(contextID, group, prop string) (string, error) {
+	return azure.GetSecret(v.URL, v.Token, v.BuildKey(contextID, group, prop))
+}
+
+func init() {
+	v := AzureKeyVault{}
+	vaults[v.Name()] = &v
+}
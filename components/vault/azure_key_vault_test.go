@@ -0,0 +1,174 @@
+package vault
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turnerlabs/cstore/v4/components/azure"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+// fakeKeyVault is a minimal stand-in for Azure AD and the Key Vault
+// secrets API with soft delete enabled.
+type fakeKeyVault struct {
+	sync.Mutex
+	secrets map[string]string
+	deleted map[string]string
+}
+
+func (f *fakeKeyVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token"):
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+	case parts[0] == "deletedsecrets" && r.Method == http.MethodPost:
+		f.secrets[parts[1]] = f.deleted[parts[1]]
+		delete(f.deleted, parts[1])
+		w.WriteHeader(http.StatusOK)
+	case parts[0] == "secrets" && r.Method == http.MethodPut:
+		if _, found := f.deleted[parts[1]]; found {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]interface{}{
+				"code":       "Conflict",
+				"innererror": map[string]string{"code": "ObjectIsDeletedButRecoverable"},
+			}})
+			return
+		}
+
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.secrets[parts[1]] = body["value"]
+		json.NewEncoder(w).Encode(body)
+	case parts[0] == "secrets" && r.Method == http.MethodDelete:
+		value, found := f.secrets[parts[1]]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		f.deleted[parts[1]] = value
+		delete(f.secrets, parts[1])
+		w.WriteHeader(http.StatusOK)
+	case parts[0] == "secrets" && r.Method == http.MethodGet:
+		value, found := f.secrets[parts[1]]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]string{"value": value})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func setupAzureKeyVault(t *testing.T) (*AzureKeyVault, func()) {
+	server := httptest.NewServer(&fakeKeyVault{secrets: map[string]string{}, deleted: map[string]string{}})
+
+	env := map[string]string{
+		"AZURE_KEY_VAULT_ENDPOINT": server.URL,
+		"AZURE_AUTHORITY_HOST":     server.URL,
+		"AZURE_TENANT_ID":          t.Name(),
+		"AZURE_CLIENT_ID":          "client",
+		"AZURE_CLIENT_SECRET":      "secret",
+	}
+
+	for k, value := range env {
+		os.Setenv(k, value)
+	}
+
+	wait := azure.RecoverWait
+	azure.RecoverWait = time.Millisecond
+
+	v := &AzureKeyVault{}
+	if err := v.Pre(catalog.Catalog{Context: "app"}, &catalog.File{Data: map[string]string{}}, nil, cfg.UserOptions{Silent: true}, models.IO{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return v, func() {
+		azure.RecoverWait = wait
+		server.Close()
+
+		for k := range env {
+			os.Unsetenv(k)
+		}
+	}
+}
+
+func TestEnsureAzureSecretNamesDoNotCollide(t *testing.T) {
+	// arrange
+	v, cleanup := setupAzureKeyVault(t)
+	defer cleanup()
+
+	secrets := []struct {
+		group string
+		prop  string
+		value string
+	}{
+		{"dev/db", "A_B", "underscore"},
+		{"dev/db", "A-B", "dash"},
+		{"dev-db", "A_B", "group dash"},
+	}
+
+	// act
+	for _, s := range secrets {
+		if err := v.Set("app", s.group, s.prop, s.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	for _, s := range secrets {
+		value, err := v.Get("app", s.group, s.prop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value != s.value {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", s.value, value)
+		}
+	}
+}
+
+func TestEnsureDeletedAzureSecretCanBeSetAgain(t *testing.T) {
+	// arrange
+	v, cleanup := setupAzureKeyVault(t)
+	defer cleanup()
+
+	if err := v.Set("app", "dev/db", "PASSWORD", "old"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Delete("app", "dev/db", "PASSWORD"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Get("app", "dev/db", "PASSWORD"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, err)
+	}
+
+	// act
+	err := v.Set("app", "dev/db", "PASSWORD", "new")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := v.Get("app", "dev/db", "PASSWORD"); err != nil || value != "new" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "new", value, err)
+	}
+}
@@ -1,7 +1,16 @@
 package vault
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
@@ -9,14 +18,52 @@ import (
 	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/local"
 	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/prompt"
 	yaml "gopkg.in/yaml.v2"
 )
 
 const fileName = "file.vlt"
 const fileKeyName = "file.vlt.key"
 
+// During a key rotation, the new key is saved as pending until the vault
+// is encrypted with it, and the old key is kept as previous for a grace
+// period.
+const filePendingKeyName = "file.vlt.key.new"
+const filePreviousKeyName = "file.vlt.key.old"
+
+const previousKeyGracePeriod = 7 * 24 * time.Hour
+
+// When enabled, secrets are saved in a vault file for each catalog
+// context.
+const filePerContextEnv = "CSTORE_FILE_VAULT_PER_CONTEXT"
+const fileContextName = "file.%s.vlt"
+
+// A passphrase protected vault is decrypted with a passphrase read from
+// the environment, an agent socket, or the user.
+const filePassphraseEnv = "CSTORE_FILE_VAULT_PASSPHRASE"
+const filePassphraseAgentEnv = "CSTORE_FILE_VAULT_AGENT"
+
+const minPassphraseLength = 8
+
+var errPassphraseRequired = fmt.Errorf("file vault passphrase required (set %s or %s)", filePassphraseEnv, filePassphraseAgentEnv)
+
+//------------------------------------------
+//- The passphrase and derived keys are kept
+//- for the process, so the user is prompted
+//- once and keys are derived once.
+//------------------------------------------
+var passphrase = struct {
+	sync.Mutex
+	value string
+	keys  map[string]string
+}{keys: map[string]string{}}
+
 // FileVault ...
-type FileVault struct{}
+type FileVault struct {
+	io         models.IO
+	silent     bool
+	perContext bool
+}
 
 // Name ...
 func (v FileVault) Name() string {
@@ -26,11 +73,17 @@ func (v FileVault) Name() string {
 // Description ...
 func (v FileVault) Description() string {
 	return fmt.Sprintf(`
-Secrets are stored in an encrypted file with the default file '~/.cstore/%s' using a default key '~/.cstore/%s'. 
-	
+Secrets are stored in an encrypted file with the default file '~/.cstore/%s' using a default key '~/.cstore/%s'.
+
 The key can be shared by placing it into the same folder on another machine to allow access to the encrypted vault data.
 
-`, local.BuildPath(fileName), local.BuildPath(fileKeyName))
+Use 'cstore vault rotate-key --vault file' to re-encrypt the file with a new key. The old key is kept in '%s' for %s.
+
+Use 'cstore vault passphrase --vault file' to derive the key from a passphrase instead of saving it. The passphrase is read from '%s', an agent socket at '%s', or prompted for.
+
+Set '%s=true' to save secrets in a file for each catalog context. Secrets in the shared file are still read.
+
+`, local.BuildPath(fileName), local.BuildPath(fileKeyName), local.BuildPath(filePreviousKeyName), previousKeyGracePeriod, filePassphraseEnv, filePassphraseAgentEnv, filePerContextEnv)
 }
 
 // BuildKey ...
@@ -43,99 +96,647 @@ func (v FileVault) BuildKey(contextID, group, prop string) string {
 }
 
 // Pre ...
-func (v FileVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+func (v *FileVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	v.io = io
+	v.silent = uo.Silent
+	v.perContext, _ = strconv.ParseBool(os.Getenv(filePerContextEnv))
+
 	return nil
 }
 
 // Set ...
 func (v FileVault) Set(contextID, group, prop, value string) error {
-	eKey, _ := getEncryptionKey()
-	data, _ := get(fileName, eKey)
+	unlock, err := v.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	name := v.vaultName(contextID)
+
+	data, err := v.load(name)
+	if err != nil {
+		return err
+	}
 
 	data[v.BuildKey(contextID, group, prop)] = value
 
-	return create(eKey, data)
+	return v.save(name, data)
 }
 
-func getEncryptionKey() (string, error) {
+// Delete ...
+func (v FileVault) Delete(contextID, group, prop string) error {
+	unlock, err := v.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
-	b, err := local.Get(fileKeyName, "")
+	for _, name := range v.vaultNames(contextID) {
+		if local.Missing(name) {
+			continue
+		}
+
+		data, err := v.load(name)
+		if err != nil {
+			return err
+		}
+
+		delete(data, v.BuildKey(contextID, group, prop))
+
+		if err := v.save(name, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get ...
+func (v FileVault) Get(contextID, group, prop string) (string, error) {
+	unlock, err := v.begin()
 	if err != nil {
-		return cipher.GenerateAES256Key(), err
+		return "", err
+	}
+	defer unlock()
+
+	for _, name := range v.vaultNames(contextID) {
+		data, err := v.load(name)
+		if err != nil {
+			return "", err
+		}
+
+		if value, found := data[v.BuildKey(contextID, group, prop)]; found {
+			if len(value) == 0 {
+				return value, contract.ErrSecretNotFound
+			}
+			return value, nil
+		}
 	}
 
-	return string(b), nil
+	return "", contract.ErrSecretNotFound
 }
 
-func saveEncryptionKey(eKey string) error {
-	return local.Update(fileKeyName, "", []byte(eKey))
+// Upgrade re-encrypts vault files encrypted with a legacy format.
+func (v FileVault) Upgrade() (bool, error) {
+	unlock, err := v.begin()
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	names, err := vaultFiles()
+	if err != nil {
+		return false, err
+	}
+
+	upgraded := false
+
+	for _, name := range names {
+		b, err := local.Get(name, "")
+		if err != nil {
+			return upgraded, err
+		}
+
+		if cipher.IsCurrent(b) {
+			continue
+		}
+
+		data, err := v.load(name)
+		if err != nil {
+			return upgraded, err
+		}
+
+		if err := v.save(name, data); err != nil {
+			return upgraded, err
+		}
+
+		upgraded = true
+	}
+
+	return upgraded, nil
 }
 
-func create(eKey string, data map[string]string) error {
-	d, err := yaml.Marshal(data)
+// RotateKey re-encrypts the vault files with a new key. A passphrase
+// protected vault keeps the passphrase with a new salt.
+func (v FileVault) RotateKey() error {
+	unlock, err := v.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if names, err := vaultFiles(); err != nil || len(names) == 0 {
+		return fmt.Errorf("%s not found", local.BuildPath(fileName))
+	}
+
+	content, err := savedKey()
+	if err != nil {
+		return err
+	}
+
+	if cipher.IsKDFParams([]byte(content)) {
+		pass, err := v.passphrase()
+		if err != nil {
+			return err
+		}
+
+		return v.rotateToPassphrase(pass)
+	}
+
+	key, err := cipher.NewAES256Key()
 	if err != nil {
 		return err
 	}
 
-	if err = local.Update(fileName, eKey, d); err != nil {
+	return v.rotate(key, key, true)
+}
+
+// SetPassphrase re-encrypts the vault files with a key derived from a
+// new passphrase. The saved key is deleted.
+func (v FileVault) SetPassphrase() error {
+
+	pass, err := v.newPassphrase()
+	if err != nil {
 		return err
 	}
 
-	return saveEncryptionKey(eKey)
+	unlock, err := v.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return v.rotateToPassphrase(pass)
 }
 
-// Delete ...
-func (v FileVault) Delete(contextID, group, prop string) error {
+// RemovePassphrase re-encrypts the vault files with a saved key.
+func (v FileVault) RemovePassphrase() error {
+	unlock, err := v.begin()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	content, err := savedKey()
+	if err != nil {
+		return err
+	}
+
+	if !cipher.IsKDFParams([]byte(content)) {
+		return errors.New("file vault is not passphrase protected")
+	}
+
+	key, err := cipher.NewAES256Key()
+	if err != nil {
+		return err
+	}
+
+	return v.rotate(key, key, true)
+}
+
+func (v FileVault) rotateToPassphrase(pass string) error {
+
+	p, err := cipher.NewKDFParams()
+	if err != nil {
+		return err
+	}
+
+	b, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+
+	//------------------------------------------
+	//- A saved key is not kept as the previous
+	//- key when switching to a passphrase.
+	//------------------------------------------
+	content, err := savedKey()
+	if err != nil {
+		return err
+	}
+
+	return v.rotate(string(b), p.DeriveKey(pass), cipher.IsKDFParams([]byte(content)))
+}
+
+//------------------------------------------
+//- Each file is replaced atomically. When a
+//- rotation is interrupted, the pending key
+//- is found and the rotation is completed
+//- before the vault is used again.
+//------------------------------------------
+func (v FileVault) rotate(newContent, newKey string, keepPrevious bool) error {
+
+	names, err := vaultFiles()
+	if err != nil {
+		return err
+	}
+
+	files := map[string][]byte{}
+
+	for _, name := range names {
+		data, err := v.load(name)
+		if err != nil {
+			return err
+		}
+
+		if files[name], err = yaml.Marshal(data); err != nil {
+			return err
+		}
+	}
+
+	oldContent, err := savedKey()
+	if err != nil {
+		return err
+	}
+
+	if err := local.Update(filePendingKeyName, "", []byte(newContent)); err != nil {
+		return err
+	}
+
+	if keepPrevious && len(oldContent) > 0 {
+		err = local.Update(filePreviousKeyName, "", []byte(oldContent))
+	} else {
+		err = local.Remove(filePreviousKeyName)
+	}
+	if err != nil {
+		return err
+	}
+
+	for name, d := range files {
+		if err := local.Update(name, newKey, d); err != nil {
+			return err
+		}
+	}
+
+	return promotePendingKey(newContent)
+}
+
+func (v FileVault) completeRotation() error {
 
-	if local.Missing(fileName) {
+	if local.Missing(filePendingKeyName) {
 		return nil
 	}
 
-	eKey, err := getEncryptionKey()
+	b, err := local.Get(filePendingKeyName, "")
 	if err != nil {
 		return err
 	}
 
-	data, err := get(fileName, eKey)
+	newKey, err := v.resolveKey(string(b))
 	if err != nil {
 		return err
 	}
 
-	delete(data, v.BuildKey(contextID, group, prop))
+	names, err := vaultFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		c, err := local.Get(name, "")
+		if err != nil {
+			return err
+		}
+
+		if _, err := cipher.Decrypt(newKey, c); err == nil && cipher.IsCurrent(c) {
+			continue
+		}
+
+		data, err := v.load(name)
+		if err != nil {
+			return err
+		}
+
+		d, err := yaml.Marshal(data)
+		if err != nil {
+			return err
+		}
+
+		if err := local.Update(name, newKey, d); err != nil {
+			return err
+		}
+	}
+
+	return promotePendingKey(string(b))
+}
+
+//------------------------------------------
+//- Vault files are only changed by one
+//- process at a time.
+//------------------------------------------
+func (v FileVault) begin() (func(), error) {
+
+	unlock, err := local.Lock(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := v.completeRotation(); err != nil {
+		unlock()
+		return nil, err
+	}
+
+	return unlock, nil
+}
+
+func (v FileVault) vaultName(contextID string) string {
+	if v.perContext && len(contextID) > 0 {
+		return fmt.Sprintf(fileContextName, contextID)
+	}
+
+	return fileName
+}
+
+// vaultNames returns the vault files holding secrets for a context. The
+// shared vault file is read after a context vault file, so secrets saved
+// before context vault files were enabled are found.
+func (v FileVault) vaultNames(contextID string) []string {
+	name := v.vaultName(contextID)
+
+	if name == fileName {
+		return []string{fileName}
+	}
+
+	return []string{name, fileName}
+}
+
+func vaultFiles() ([]string, error) {
+	names := []string{}
+
+	if !local.Missing(fileName) {
+		names = append(names, fileName)
+	}
+
+	contexts, err := local.List(fmt.Sprintf(fileContextName, "*"))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(names, contexts...), nil
+}
+
+// fileKey is a saved key or key derivation settings that may decrypt
+// a vault file.
+type fileKey struct {
+	name    string
+	content string
+}
+
+//------------------------------------------
+//- Vault files are decrypted with the
+//- current key, the pending key of an
+//- interrupted rotation, or the previous key
+//- during the grace period.
+//------------------------------------------
+func decryptionKeys() ([]fileKey, error) {
+	keys := []fileKey{}
+
+	if !local.Missing(filePreviousKeyName) {
+		modified, err := local.Modified(filePreviousKeyName)
+		if err != nil {
+			return keys, err
+		}
+
+		if time.Since(modified) > previousKeyGracePeriod {
+			if err := local.Remove(filePreviousKeyName); err != nil {
+				return keys, err
+			}
+		}
+	}
+
+	for _, name := range []string{fileKeyName, filePendingKeyName, filePreviousKeyName} {
+		if local.Missing(name) {
+			continue
+		}
+
+		b, err := local.Get(name, "")
+		if err != nil {
+			return keys, err
+		}
+
+		keys = append(keys, fileKey{name: name, content: string(b)})
+	}
+
+	return keys, nil
+}
 
+func (v FileVault) load(name string) (map[string]string, error) {
+
+	if local.Missing(name) {
+		return map[string]string{}, nil
+	}
+
+	keys, err := decryptionKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	err = fmt.Errorf("%s not found", local.BuildPath(fileKeyName))
+
+	for _, key := range keys {
+		k, e := v.resolveKey(key.content)
+		if e != nil {
+			return nil, e
+		}
+
+		data, e := get(name, k)
+		if e != nil {
+			err = e
+			continue
+		}
+
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("failed to decrypt %s (%s)", local.BuildPath(name), err)
+}
+
+func (v FileVault) save(name string, data map[string]string) error {
 	d, err := yaml.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	return local.Update(fileName, eKey, d)
+	key, err := v.currentKey()
+	if err != nil {
+		return err
+	}
+
+	return local.Update(name, key, d)
 }
 
-// Get ...
-func (v FileVault) Get(contextID, group, prop string) (string, error) {
+//------------------------------------------
+//- A key is generated and saved before the
+//- first vault file is encrypted with it.
+//------------------------------------------
+func (v FileVault) currentKey() (string, error) {
+
+	content, err := savedKey()
+	if err != nil {
+		return "", err
+	}
+
+	if len(content) == 0 {
+		key, err := cipher.NewAES256Key()
+		if err != nil {
+			return "", err
+		}
+
+		return key, local.Update(fileKeyName, "", []byte(key))
+	}
+
+	return v.resolveKey(content)
+}
+
+// savedKey returns the saved key or key derivation settings without
+// generating a key.
+func savedKey() (string, error) {
+
+	if local.Missing(fileKeyName) {
+		return "", nil
+	}
+
+	b, err := local.Get(fileKeyName, "")
+
+	return string(b), err
+}
+
+// resolveKey returns a saved key or derives the key from the passphrase.
+func (v FileVault) resolveKey(content string) (string, error) {
+
+	if !cipher.IsKDFParams([]byte(content)) {
+		return content, nil
+	}
+
+	p, err := cipher.ParseKDFParams([]byte(content))
+	if err != nil {
+		return "", err
+	}
+
+	pass, err := v.passphrase()
+	if err != nil {
+		return "", err
+	}
+
+	passphrase.Lock()
+	defer passphrase.Unlock()
+
+	if key, found := passphrase.keys[content]; found {
+		return key, nil
+	}
+
+	key := p.DeriveKey(pass)
+	passphrase.keys[content] = key
+
+	return key, nil
+}
 
-	if local.Missing(fileName) {
-		return "", contract.ErrSecretNotFound
+func (v FileVault) passphrase() (string, error) {
+	passphrase.Lock()
+	defer passphrase.Unlock()
+
+	if len(passphrase.value) > 0 {
+		return passphrase.value, nil
 	}
 
-	eKey, err := getEncryptionKey()
+	value, err := passphraseFromEnv()
 	if err != nil {
 		return "", err
 	}
 
-	data, err := get(fileName, eKey)
+	if len(value) == 0 {
+		if v.silent || v.io.UserOutput == nil {
+			return "", errPassphraseRequired
+		}
+
+		value = prompt.GetValFromUser("Passphrase", prompt.Options{
+			Description: fmt.Sprintf("Enter the passphrase protecting %s.", local.BuildPath(fileName)),
+			HideInput:   true,
+		}, v.io)
+	}
+
+	if len(value) == 0 {
+		return "", errPassphraseRequired
+	}
+
+	passphrase.value = value
+
+	return value, nil
+}
+
+func (v FileVault) newPassphrase() (string, error) {
+
+	value, err := passphraseFromEnv()
 	if err != nil {
 		return "", err
 	}
 
-	if value, found := data[v.BuildKey(contextID, group, prop)]; found {
-		if len(value) == 0 {
-			return value, contract.ErrSecretNotFound
+	if len(value) == 0 {
+		if v.silent || v.io.UserOutput == nil {
+			return "", errPassphraseRequired
 		}
+
+		value = prompt.GetValFromUser("New Passphrase", prompt.Options{
+			Description: fmt.Sprintf("Enter a passphrase to protect %s. Secrets cannot be recovered without it.", local.BuildPath(fileName)),
+			HideInput:   true,
+		}, v.io)
+
+		if value != prompt.GetValFromUser("Confirm Passphrase", prompt.Options{HideInput: true}, v.io) {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+
+	if len(value) < minPassphraseLength {
+		return "", fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+	}
+
+	passphrase.Lock()
+	passphrase.value = value
+	passphrase.Unlock()
+
+	return value, nil
+}
+
+//------------------------------------------
+//- An agent is any process listening on a
+//- unix socket that writes the passphrase
+//- to each connection, like a helper
+//- reading an OS keychain.
+//------------------------------------------
+func passphraseFromEnv() (string, error) {
+
+	if value := os.Getenv(filePassphraseEnv); len(value) > 0 {
 		return value, nil
 	}
 
-	return "", contract.ErrSecretNotFound
+	socket := os.Getenv(filePassphraseAgentEnv)
+	if len(socket) == 0 {
+		return "", nil
+	}
+
+	conn, err := net.DialTimeout("unix", socket, 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to passphrase agent (%s)", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	b, err := ioutil.ReadAll(io.LimitReader(conn, 4096))
+	if err != nil {
+		return "", fmt.Errorf("failed to read from passphrase agent (%s)", err)
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+func promotePendingKey(key string) error {
+	if err := local.Update(fileKeyName, "", []byte(key)); err != nil {
+		return err
+	}
+
+	return local.Remove(filePendingKeyName)
 }
 
 func get(file, key string) (map[string]string, error) {
@@ -156,5 +757,5 @@ func get(file, key string) (map[string]string, error) {
 
 func init() {
 	v := FileVault{}
-	vaults[v.Name()] = v
+	vaults[v.Name()] = &v
 }
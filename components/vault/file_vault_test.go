@@ -0,0 +1,425 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	cstorecipher "github.com/turnerlabs/cstore/v4/components/cipher"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/local"
+)
+
+func setupHome(t *testing.T) func() {
+	home, err := ioutil.TempDir("", "cstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := os.Getenv("HOME")
+
+	os.Setenv("HOME", home)
+	homedir.DisableCache = true
+
+	return func() {
+		os.Setenv("HOME", previous)
+		homedir.DisableCache = false
+		os.RemoveAll(home)
+	}
+}
+
+func TestEnsureLegacyFileVaultIsUpgraded(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	key := "AES256Key-32Characters1234567890"
+	data := []byte("group-prop: secret\n")
+
+	block, _ := aes.NewCipher([]byte(key))
+
+	legacy := make([]byte, aes.BlockSize+len(data))
+	copy(legacy, "0123456789abcdef")
+	cipher.NewCFBEncrypter(block, legacy[:aes.BlockSize]).XORKeyStream(legacy[aes.BlockSize:], data)
+
+	if err := local.Update(fileName, "", legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := local.Update(fileKeyName, "", []byte(key)); err != nil {
+		t.Fatal(err)
+	}
+
+	v := FileVault{}
+
+	// act
+	upgraded, err := v.Upgrade()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ := local.Get(fileName, "")
+
+	if !upgraded || !cstorecipher.IsCurrent(b) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %t", "upgraded", upgraded)
+	}
+
+	if value, err := v.Get("", "group", "prop"); err != nil || value != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "secret", value, err)
+	}
+
+	if upgraded, _ := v.Upgrade(); upgraded {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "current vault skipped", "upgraded again")
+	}
+}
+
+func TestEnsureRotatedKeyDecryptsSecrets(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	v := FileVault{}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, _ := local.Get(fileKeyName, "")
+
+	// act
+	err := v.RotateKey()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, _ := local.Get(fileKeyName, "")
+	previousKey, _ := local.Get(filePreviousKeyName, "")
+
+	if string(newKey) == string(oldKey) || string(previousKey) != string(oldKey) || len(newKey) != 32 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "new key with old key kept", string(newKey))
+	}
+
+	if !local.Missing(filePendingKeyName) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "pending key removed", "pending key found")
+	}
+
+	if value, err := v.Get("", "group", "prop"); err != nil || value != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "secret", value, err)
+	}
+}
+
+func TestEnsureInterruptedKeyRotationIsCompleted(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	v := FileVault{}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, _ := cstorecipher.NewAES256Key()
+
+	//------------------------------------------
+	//- Interrupted after the vault file was
+	//- encrypted with the new key.
+	//------------------------------------------
+	local.Update(filePendingKeyName, "", []byte(newKey))
+	local.Update(fileName, newKey, []byte("group-prop: secret\n"))
+
+	// act
+	value, err := v.Get("", "group", "prop")
+
+	// assert
+	if err != nil || value != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "secret", value, err)
+	}
+
+	if key, _ := local.Get(fileKeyName, ""); string(key) != newKey || !local.Missing(filePendingKeyName) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", newKey, string(key))
+	}
+}
+
+func TestEnsureExpiredPreviousKeyIsRemoved(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	v := FileVault{}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-previousKeyGracePeriod - time.Hour)
+	os.Chtimes(local.BuildPath(filePreviousKeyName), expired, expired)
+
+	// act
+	_, err := v.Get("", "group", "prop")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !local.Missing(filePreviousKeyName) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "previous key removed", "previous key found")
+	}
+}
+
+func TestEnsureVaultIsNotOverwrittenWithTheWrongKey(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	v := FileVault{}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	local.Update(fileKeyName, "", []byte("AES256Key-32Characters0987654321"))
+
+	// act
+	err := v.Set("", "group", "other", "value")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "decrypt error", err)
+	}
+}
+
+func resetPassphrase() {
+	passphrase.Lock()
+	passphrase.value = ""
+	passphrase.keys = map[string]string{}
+	passphrase.Unlock()
+}
+
+func TestEnsurePassphraseProtectedVaultDoesNotSaveTheKey(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer resetPassphrase()
+
+	os.Setenv(filePassphraseEnv, "correct horse battery staple")
+	defer os.Unsetenv(filePassphraseEnv)
+
+	v := FileVault{}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	oldKey, _ := local.Get(fileKeyName, "")
+
+	// act
+	err := v.SetPassphrase()
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := local.Get(fileKeyName, "")
+
+	if !cstorecipher.IsKDFParams(key) || !local.Missing(filePreviousKeyName) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "key derivation settings only", string(key))
+	}
+
+	if _, err := get(fileName, string(oldKey)); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "vault encrypted with passphrase", "decrypted with old key")
+	}
+
+	resetPassphrase()
+
+	if value, err := v.Get("", "group", "prop"); err != nil || value != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "secret", value, err)
+	}
+}
+
+func TestEnsureWrongPassphraseDoesNotDecryptVault(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer resetPassphrase()
+
+	os.Setenv(filePassphraseEnv, "correct horse battery staple")
+	defer os.Unsetenv(filePassphraseEnv)
+
+	v := FileVault{}
+
+	if err := v.SetPassphrase(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	resetPassphrase()
+	os.Setenv(filePassphraseEnv, "wrong horse battery staple")
+
+	// act
+	_, err := v.Get("", "group", "prop")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "decrypt error", err)
+	}
+}
+
+func TestEnsurePassphraseIsRequiredWhenSilent(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer resetPassphrase()
+
+	os.Setenv(filePassphraseEnv, "correct horse battery staple")
+
+	v := FileVault{}
+
+	if err := v.SetPassphrase(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Set("", "group", "prop", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	resetPassphrase()
+	os.Unsetenv(filePassphraseEnv)
+
+	v.silent = true
+
+	// act
+	_, err := v.Get("", "group", "prop")
+
+	// assert
+	if err != errPassphraseRequired {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", errPassphraseRequired, err)
+	}
+}
+
+func TestEnsurePassphraseIsReadFromAgent(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer resetPassphrase()
+
+	socket := filepath.Join(os.Getenv("HOME"), "agent.sock")
+
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.Write([]byte("correct horse battery staple\n"))
+			conn.Close()
+		}
+	}()
+
+	os.Setenv(filePassphraseAgentEnv, socket)
+	defer os.Unsetenv(filePassphraseAgentEnv)
+
+	// act
+	value, err := passphraseFromEnv()
+
+	// assert
+	if err != nil || value != "correct horse battery staple" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "correct horse battery staple", value, err)
+	}
+}
+
+func TestEnsureConcurrentSetsAreNotLost(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	v := FileVault{}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+
+	// act
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			errs <- v.Set("", "group", fmt.Sprintf("prop%d", i), "secret")
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	// assert
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 20; i++ {
+		if value, err := v.Get("", "group", fmt.Sprintf("prop%d", i)); err != nil || value != "secret" {
+			t.Errorf("\nEXPECTED: prop%d %s \nACTUAL: %s %v", i, "secret", value, err)
+		}
+	}
+}
+
+func TestEnsureContextVaultFilesReadSharedSecrets(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+
+	shared := FileVault{}
+
+	if err := shared.Set("context-a", "group", "shared", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	v := FileVault{perContext: true}
+
+	// act
+	err := v.Set("context-a", "group", "prop", "value")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if local.Missing(fmt.Sprintf(fileContextName, "context-a")) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "context vault file", "missing")
+	}
+
+	if value, err := v.Get("context-a", "group", "shared"); err != nil || value != "secret" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "secret", value, err)
+	}
+
+	if _, err := shared.Get("context-a", "group", "prop"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, err)
+	}
+
+	if err := v.RotateKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	if value, err := v.Get("context-a", "group", "prop"); err != nil || value != "value" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s %v", "value", value, err)
+	}
+}
@@ -0,0 +1,165 @@
+package vault
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/hashicorp"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/setting"
+)
+
+// HashiCorpVault ...
+type HashiCorpVault struct {
+	Client *api.Client
+
+	clog      catalog.Catalog
+	fileEntry *catalog.File
+
+	uo cfg.UserOptions
+	io models.IO
+
+	mount string
+}
+
+// Name ...
+func (v HashiCorpVault) Name() string {
+	return "hashicorp-vault"
+}
+
+// Description ...
+func (v HashiCorpVault) Description() string {
+	return `
+Secrets are saved and retrieved from a HashiCorp Vault KV version 2 secrets engine.
+
+Placing secret tokens in the file {{ENV/KEY::SECRET}} will remove and push secrets into HashiCorp Vault.
+
+Using '-i' cli flag during a pull, will inject secrets into a copy of the file created with a '.secrets' extension during the restore.
+
+The Vault address, auth method (token, approle, or kubernetes), and KV mount are saved in the catalog. Tokens, role ids, and secret ids are retrieved from the access vault.
+
+When used as an access vault, VAULT_TOKEN, VAULT_ROLE_ID, and VAULT_SECRET_ID are read from environment variables.
+`
+}
+
+// BuildKey ...
+func (v HashiCorpVault) BuildKey(contextID, group, prop string) string {
+	return fmt.Sprintf("%s/%s", contextID, strings.ToLower(group))
+}
+
+// Pre ...
+func (v *HashiCorpVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	v.clog = clog
+	v.uo = uo
+	v.io = io
+
+	v.fileEntry = fileEntry
+
+	//------------------------------------------
+	//- Vault Configuration
+	//------------------------------------------
+	mount, err := setting.Setting{
+		Description:  "Mount of the KV version 2 secrets engine that will store secrets.",
+		Prop:         hashicorp.MountSetting,
+		Prompt:       uo.Prompt,
+		Silent:       uo.Silent,
+		AutoSave:     true,
+		DefaultValue: clog.GetDataByVault(v.Name(), hashicorp.MountSetting, hashicorp.DefaultMount),
+		Vault:        fileEntry,
+	}.Get(clog.Context, io)
+	if err != nil {
+		return err
+	}
+
+	if len(mount) == 0 {
+		mount = hashicorp.DefaultMount
+	}
+
+	v.mount = mount
+
+	//------------------------------------------
+	//- Authenticate
+	//------------------------------------------
+	var credentials setting.IKeyValueStore = EnvVault{}
+	if access != nil {
+		credentials = access
+	}
+
+	v.Client, err = hashicorp.Client(clog, fileEntry, credentials, uo, io)
+
+	return err
+}
+
+// Set ...
+func (v HashiCorpVault) Set(contextID, group, prop, value string) error {
+
+	key := v.BuildKey(contextID, group, prop)
+
+	storedProps, version, err := hashicorp.Read(v.Client, v.mount, key, 0)
+	if err != nil {
+		if err != contract.ErrSecretNotFound {
+			return err
+		}
+
+		storedProps = map[string]interface{}{}
+	}
+
+	storedProps[prop] = value
+
+	_, err = hashicorp.Write(v.Client, v.mount, key, storedProps, version.Number)
+
+	return err
+}
+
+// Delete ...
+func (v HashiCorpVault) Delete(contextID, group, prop string) error {
+
+	key := v.BuildKey(contextID, group, prop)
+
+	storedProps, version, err := hashicorp.Read(v.Client, v.mount, key, 0)
+	if err != nil {
+		return err
+	}
+
+	if _, found := storedProps[prop]; !found {
+		return contract.ErrSecretNotFound
+	}
+
+	delete(storedProps, prop)
+
+	if len(storedProps) == 0 {
+		return hashicorp.DeleteAll(v.Client, v.mount, key)
+	}
+
+	_, err = hashicorp.Write(v.Client, v.mount, key, storedProps, version.Number)
+
+	return err
+}
+
+// Get ...
+func (v HashiCorpVault) Get(contextID, group, prop string) (string, error) {
+
+	storedProps, _, err := hashicorp.Read(v.Client, v.mount, v.BuildKey(contextID, group, prop), 0)
+	if err != nil {
+		return "", err
+	}
+
+	if value, found := storedProps[prop]; found {
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+
+		return fmt.Sprint(value), nil
+	}
+
+	return "", contract.ErrSecretNotFound
+}
+
+func init() {
+	v := HashiCorpVault{}
+	vaults[v.Name()] = &v
+}
@@ -0,0 +1,100 @@
+package vault
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/hashicorp/hashicorptest"
+	"github.com/turnerlabs/cstore/v4/components/models"
+)
+
+func setupHashiCorpVault(t *testing.T) (*HashiCorpVault, func()) {
+	server := httptest.NewServer(hashicorptest.NewKV())
+	os.Setenv("VAULT_TOKEN", "root")
+
+	file := &catalog.File{
+		Data: map[string]string{
+			"VAULT_ADDR":        server.URL,
+			"VAULT_AUTH_METHOD": "token",
+		},
+	}
+
+	v := &HashiCorpVault{}
+	if err := v.Pre(catalog.Catalog{Context: t.Name()}, file, nil, cfg.UserOptions{Silent: true}, models.IO{}); err != nil {
+		t.Fatal(err)
+	}
+
+	return v, func() {
+		server.Close()
+		os.Unsetenv("VAULT_TOKEN")
+	}
+}
+
+func TestEnsureHashiCorpSecretsAreGroupedByTokenPath(t *testing.T) {
+	// arrange
+	v, cleanup := setupHashiCorpVault(t)
+	defer cleanup()
+
+	secrets := map[string]string{"USER": "admin", "PASSWORD": "secret"}
+
+	// act
+	for prop, value := range secrets {
+		if err := v.Set("app", "dev/DB", prop, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// assert
+	for prop, expected := range secrets {
+		value, err := v.Get("app", "dev/db", prop)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if value != expected {
+			t.Errorf("\nEXPECTED: %s \nACTUAL: %s", expected, value)
+		}
+	}
+
+	if _, err := v.Get("app", "dev/db", "HOST"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, err)
+	}
+}
+
+func TestEnsureHashiCorpSecretDeleteKeepsOtherProps(t *testing.T) {
+	// arrange
+	v, cleanup := setupHashiCorpVault(t)
+	defer cleanup()
+
+	for _, prop := range []string{"USER", "PASSWORD"} {
+		if err := v.Set("app", "dev/db", prop, "value"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// act
+	if err := v.Delete("app", "dev/db", "PASSWORD"); err != nil {
+		t.Fatal(err)
+	}
+
+	// assert
+	if _, err := v.Get("app", "dev/db", "PASSWORD"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, err)
+	}
+
+	if value, err := v.Get("app", "dev/db", "USER"); err != nil || value != "value" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s (%v)", "value", value, err)
+	}
+
+	if err := v.Delete("app", "dev/db", "USER"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.Get("app", "dev/db", "USER"); err != contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", contract.ErrSecretNotFound, err)
+	}
+}
@@ -0,0 +1,671 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/turnerlabs/cstore/v4/components/catalog"
+	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/file"
+	"github.com/turnerlabs/cstore/v4/components/local"
+	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/prompt"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	_ "golang.org/x/crypto/ripemd160" // used with keys not listing preferred hashes
+	yaml "gopkg.in/yaml.v2"
+)
+
+// The vault file and the public keys of its recipients are saved next to
+// the catalog, so they can be committed with it.
+const pgpVaultFileName = "cstore.vlt.asc"
+const pgpRecipientsFileName = "cstore.recipients.asc"
+const pgpSignatureFileName = "cstore.recipients.sig"
+
+// The private key of the user decrypts and signs the vault file.
+const pgpKeyFileEnv = "CSTORE_PGP_KEY_FILE"
+const pgpPassphraseEnv = "CSTORE_PGP_PASSPHRASE"
+const pgpDefaultKeyFile = "pgp.key"
+
+//------------------------------------------
+//- Decrypted private keys are kept for the
+//- process, so the user is prompted once.
+//------------------------------------------
+var pgpKeys = struct {
+	sync.Mutex
+	entities map[string]openpgp.EntityList
+}{entities: map[string]openpgp.EntityList{}}
+
+// PGPVault ...
+type PGPVault struct {
+	root   string
+	io     models.IO
+	silent bool
+}
+
+// Name ...
+func (v PGPVault) Name() string {
+	return "pgp"
+}
+
+// Description ...
+func (v PGPVault) Description() string {
+	return fmt.Sprintf(`
+Secrets are stored in '%s' next to the catalog, encrypted to the OpenPGP public keys in '%s'. Both files can be committed with the catalog, so each team member decrypts secrets with their own private key instead of sharing a key.
+
+The private key is read from '%s' or the file in '%s'. When the private key is protected, the passphrase is read from '%s' or prompted for.
+
+Use 'cstore vault add-recipient --vault pgp {public_key_file}' and 'cstore vault remove-recipient --vault pgp {key_id}' to change who can decrypt secrets.
+
+Recipients are signed in '%s' by the recipient who changed them. Secrets are only encrypted for recipients that changed since the vault was last signed after confirmation.
+
+`, pgpVaultFileName, pgpRecipientsFileName, local.BuildPath(pgpDefaultKeyFile), pgpKeyFileEnv, pgpPassphraseEnv, pgpSignatureFileName)
+}
+
+// BuildKey ...
+func (v PGPVault) BuildKey(contextID, group, prop string) string {
+	if len(prop) > 0 {
+		return fmt.Sprintf("%s-%s", group, prop)
+	}
+
+	return group
+}
+
+// Pre ...
+func (v *PGPVault) Pre(clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) error {
+	v.root = clog.Location()
+	v.io = io
+	v.silent = uo.Silent
+
+	return nil
+}
+
+// Set ...
+func (v PGPVault) Set(contextID, group, prop, value string) error {
+	unlock, err := local.Lock(pgpVaultFileName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	recipients, err := v.recipients()
+	if err != nil {
+		return err
+	}
+
+	data, encryptedTo, err := v.open(recipients)
+	if err != nil {
+		return err
+	}
+
+	if err := v.confirmRecipients(recipients, encryptedTo); err != nil {
+		return err
+	}
+
+	data[v.BuildKey(contextID, group, prop)] = value
+
+	return v.write(data, recipients)
+}
+
+// Delete ...
+func (v PGPVault) Delete(contextID, group, prop string) error {
+	unlock, err := local.Lock(pgpVaultFileName)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if v.missing(pgpVaultFileName) {
+		return nil
+	}
+
+	recipients, err := v.recipients()
+	if err != nil {
+		return err
+	}
+
+	data, encryptedTo, err := v.open(recipients)
+	if err != nil {
+		return err
+	}
+
+	if err := v.confirmRecipients(recipients, encryptedTo); err != nil {
+		return err
+	}
+
+	delete(data, v.BuildKey(contextID, group, prop))
+
+	return v.write(data, recipients)
+}
+
+// Get ...
+func (v PGPVault) Get(contextID, group, prop string) (string, error) {
+
+	if v.missing(pgpVaultFileName) {
+		return "", contract.ErrSecretNotFound
+	}
+
+	data, err := v.read()
+	if err != nil {
+		return "", err
+	}
+
+	if value, found := data[v.BuildKey(contextID, group, prop)]; found {
+		if len(value) == 0 {
+			return value, contract.ErrSecretNotFound
+		}
+		return value, nil
+	}
+
+	return "", contract.ErrSecretNotFound
+}
+
+// AddRecipient adds the public keys to the recipients and re-encrypts the
+// vault file for them.
+func (v PGPVault) AddRecipient(publicKey []byte) ([]string, error) {
+	unlock, err := local.Lock(pgpVaultFileName)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	keys, err := readKeyRing(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, signed, err := v.trustRecipients()
+	if err != nil {
+		return nil, err
+	}
+
+	recipients := append(openpgp.EntityList{}, previous...)
+	added := []string{}
+
+	for _, key := range keys {
+		if len(findRecipients(recipients, fingerprint(key))) > 0 {
+			continue
+		}
+
+		recipients = append(recipients, key)
+		added = append(added, describeRecipient(key))
+	}
+
+	if len(added) == 0 && signed {
+		return added, nil
+	}
+
+	return added, v.reencrypt(previous, recipients)
+}
+
+// RemoveRecipient removes the public key matching the key id, fingerprint,
+// or email and re-encrypts the vault file without it.
+func (v PGPVault) RemoveRecipient(id string) (string, error) {
+	unlock, err := local.Lock(pgpVaultFileName)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	recipients, _, err := v.trustRecipients()
+	if err != nil {
+		return "", err
+	}
+
+	matches := findRecipients(recipients, id)
+
+	switch {
+	case len(matches) == 0:
+		return "", fmt.Errorf("recipient %s not found", id)
+	case len(matches) > 1:
+		return "", fmt.Errorf("%s matches %d recipients, use the key fingerprint", id, len(matches))
+	case len(recipients) == 1:
+		return "", errors.New("the last recipient cannot be removed")
+	}
+
+	remaining := openpgp.EntityList{}
+	for _, r := range recipients {
+		if r != matches[0] {
+			remaining = append(remaining, r)
+		}
+	}
+
+	return describeRecipient(matches[0]), v.reencrypt(recipients, remaining)
+}
+
+//------------------------------------------
+//- The vault file is re-encrypted before the
+//- recipients are saved, so it is always
+//- signed by a saved recipient. The new
+//- recipients are signed by one of the
+//- previous recipients.
+//------------------------------------------
+func (v PGPVault) reencrypt(previous, recipients openpgp.EntityList) error {
+
+	keys, err := v.privateKeys()
+	if err != nil {
+		return err
+	}
+
+	signer := signingKey(keys, previous)
+	if len(previous) == 0 {
+		signer = signingKey(keys, recipients)
+	}
+
+	if signer == nil {
+		return errors.New("your public key must be a recipient to change recipients")
+	}
+
+	if !v.missing(pgpVaultFileName) {
+		data, _, err := v.open(previous)
+		if err != nil {
+			return err
+		}
+
+		if err := v.write(data, recipients); err != nil {
+			return err
+		}
+	}
+
+	var buff bytes.Buffer
+
+	w, err := armor.Encode(&buff, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range recipients {
+		if err := r.Serialize(w); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	var sig bytes.Buffer
+
+	if err := openpgp.ArmoredDetachSign(&sig, signer, bytes.NewReader(buff.Bytes()), nil); err != nil {
+		return err
+	}
+
+	if err := file.SaveAtomic(v.path(pgpRecipientsFileName), buff.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	return file.SaveAtomic(v.path(pgpSignatureFileName), sig.Bytes(), 0644)
+}
+
+func (v PGPVault) read() (map[string]string, error) {
+
+	if v.missing(pgpVaultFileName) {
+		return map[string]string{}, nil
+	}
+
+	recipients, err := v.recipients()
+	if err != nil {
+		return nil, err
+	}
+
+	data, _, err := v.open(recipients)
+
+	return data, err
+}
+
+//------------------------------------------
+//- Only vault files signed by a recipient
+//- are trusted, so a vault file replaced by
+//- someone else is rejected. The ids of the
+//- keys the vault was encrypted to are
+//- returned to detect changed recipients.
+//------------------------------------------
+func (v PGPVault) open(recipients openpgp.EntityList) (map[string]string, []uint64, error) {
+	data := map[string]string{}
+
+	if v.missing(pgpVaultFileName) {
+		return data, nil, nil
+	}
+
+	keys, err := v.privateKeys()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b, err := ioutil.ReadFile(v.path(pgpVaultFileName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := armor.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s (%s)", pgpVaultFileName, err)
+	}
+
+	md, err := openpgp.ReadMessage(block.Body, append(keys, recipients...), nil, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt %s (%s)", pgpVaultFileName, err)
+	}
+
+	plain, err := ioutil.ReadAll(md.UnverifiedBody)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt %s (%s)", pgpVaultFileName, err)
+	}
+
+	if !md.IsSigned || md.SignedBy == nil || md.SignatureError != nil {
+		return nil, nil, fmt.Errorf("%s is not signed by a recipient", pgpVaultFileName)
+	}
+
+	if len(recipients.KeysById(md.SignedByKeyId)) == 0 {
+		return nil, nil, fmt.Errorf("%s is not signed by a recipient", pgpVaultFileName)
+	}
+
+	if err := yaml.Unmarshal(plain, &data); err != nil {
+		return nil, nil, err
+	}
+
+	return data, md.EncryptedToKeyIds, nil
+}
+
+func (v PGPVault) write(data map[string]string, recipients openpgp.EntityList) error {
+
+	if len(recipients) == 0 {
+		return fmt.Errorf("%s has no recipients (use 'cstore vault add-recipient --vault pgp' to add public keys)", pgpRecipientsFileName)
+	}
+
+	keys, err := v.privateKeys()
+	if err != nil {
+		return err
+	}
+
+	signer := signingKey(keys, recipients)
+	if signer == nil {
+		return errors.New("your public key must be a recipient to save secrets")
+	}
+
+	d, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var buff bytes.Buffer
+
+	aw, err := armor.Encode(&buff, "PGP MESSAGE", nil)
+	if err != nil {
+		return err
+	}
+
+	w, err := openpgp.Encrypt(aw, recipients, signer, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(d); err != nil {
+		return err
+	}
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if err := aw.Close(); err != nil {
+		return err
+	}
+
+	return file.SaveAtomic(v.path(pgpVaultFileName), buff.Bytes(), 0644)
+}
+
+// recipients returns the public keys in the recipients file, which must be
+// signed by one of the recipients.
+func (v PGPVault) recipients() (openpgp.EntityList, error) {
+
+	recipients, err := v.readRecipients()
+	if err == errRecipientsNotSigned {
+		return nil, fmt.Errorf("%s is not signed by a recipient (use 'cstore vault add-recipient --vault pgp' to review and sign it)", pgpRecipientsFileName)
+	}
+
+	return recipients, err
+}
+
+var errRecipientsNotSigned = errors.New("recipients not signed")
+
+func (v PGPVault) readRecipients() (openpgp.EntityList, error) {
+
+	if v.missing(pgpRecipientsFileName) {
+		return openpgp.EntityList{}, nil
+	}
+
+	b, err := ioutil.ReadFile(v.path(pgpRecipientsFileName))
+	if err != nil {
+		return nil, err
+	}
+
+	recipients, err := readKeyRing(b)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := ioutil.ReadFile(v.path(pgpSignatureFileName))
+	if err != nil {
+		return recipients, errRecipientsNotSigned
+	}
+
+	if _, err := openpgp.CheckArmoredDetachedSignature(recipients, bytes.NewReader(b), bytes.NewReader(sig)); err != nil {
+		return recipients, errRecipientsNotSigned
+	}
+
+	return recipients, nil
+}
+
+//------------------------------------------
+//- Recipients not signed by a recipient are
+//- only changed once the user trusts them.
+//------------------------------------------
+func (v PGPVault) trustRecipients() (openpgp.EntityList, bool, error) {
+
+	recipients, err := v.readRecipients()
+	if err != errRecipientsNotSigned {
+		return recipients, err == nil, err
+	}
+
+	names := []string{}
+	for _, r := range recipients {
+		names = append(names, describeRecipient(r))
+	}
+
+	description := fmt.Sprintf("%s is not signed by a recipient. Trust %s?", pgpRecipientsFileName, strings.Join(names, ", "))
+
+	if err := v.confirm(description); err != nil {
+		return nil, false, err
+	}
+
+	return recipients, false, nil
+}
+
+//------------------------------------------
+//- Recipients changed outside of cstore
+//- since the vault was last signed are only
+//- given secrets once the user confirms.
+//------------------------------------------
+func (v PGPVault) confirmRecipients(recipients openpgp.EntityList, encryptedTo []uint64) error {
+
+	if len(encryptedTo) == 0 {
+		return nil
+	}
+
+	previous := map[string]bool{}
+	unknown := 0
+
+	for _, id := range encryptedTo {
+		keys := recipients.KeysById(id)
+		if len(keys) == 0 {
+			unknown++
+			continue
+		}
+		previous[fingerprint(keys[0].Entity)] = true
+	}
+
+	added := []string{}
+	for _, r := range recipients {
+		if !previous[fingerprint(r)] {
+			added = append(added, describeRecipient(r))
+		}
+	}
+
+	if len(added) == 0 && unknown == 0 {
+		return nil
+	}
+
+	description := fmt.Sprintf("%s changed since %s was last signed", pgpRecipientsFileName, pgpVaultFileName)
+	if len(added) > 0 {
+		description = fmt.Sprintf("%s (added %s)", description, strings.Join(added, ", "))
+	}
+
+	return v.confirm(description + ". Encrypt secrets for the current recipients?")
+}
+
+func (v PGPVault) confirm(description string) error {
+
+	if v.silent || v.io.UserOutput == nil || v.io.UserInput == nil {
+		return fmt.Errorf("%s (confirmation required)", strings.TrimSuffix(description, "?"))
+	}
+
+	if !prompt.Confirm(description, prompt.Warn, v.io) {
+		return errors.New("recipients not trusted")
+	}
+
+	return nil
+}
+
+func (v PGPVault) privateKeys() (openpgp.EntityList, error) {
+
+	path := os.Getenv(pgpKeyFileEnv)
+	if len(path) == 0 {
+		path = local.BuildPath(pgpDefaultKeyFile)
+	}
+
+	pgpKeys.Lock()
+	defer pgpKeys.Unlock()
+
+	if keys, found := pgpKeys.entities[path]; found {
+		return keys, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("private key not found at %s (set %s)", path, pgpKeyFileEnv)
+	}
+
+	keys, err := readKeyRing(b)
+	if err != nil {
+		return nil, err
+	}
+
+	pass := []byte(os.Getenv(pgpPassphraseEnv))
+
+	for _, key := range keys {
+		privateKeys := []*openpgp.Key{{PrivateKey: key.PrivateKey}}
+		for _, subkey := range key.Subkeys {
+			privateKeys = append(privateKeys, &openpgp.Key{PrivateKey: subkey.PrivateKey})
+		}
+
+		for _, pk := range privateKeys {
+			if pk.PrivateKey == nil || !pk.PrivateKey.Encrypted {
+				continue
+			}
+
+			if len(pass) == 0 {
+				if v.silent || v.io.UserOutput == nil {
+					return nil, fmt.Errorf("private key passphrase required (set %s)", pgpPassphraseEnv)
+				}
+
+				pass = []byte(prompt.GetValFromUser("Passphrase", prompt.Options{
+					Description: fmt.Sprintf("Enter the passphrase protecting %s.", path),
+					HideInput:   true,
+				}, v.io))
+			}
+
+			if err := pk.PrivateKey.Decrypt(pass); err != nil {
+				return nil, fmt.Errorf("failed to decrypt private key (%s)", err)
+			}
+		}
+	}
+
+	pgpKeys.entities[path] = keys
+
+	return keys, nil
+}
+
+func (v PGPVault) path(name string) string {
+	return filepath.Join(v.root, name)
+}
+
+func (v PGPVault) missing(name string) bool {
+	_, err := os.Stat(v.path(name))
+	return os.IsNotExist(err)
+}
+
+func readKeyRing(b []byte) (openpgp.EntityList, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	}
+
+	return openpgp.ReadKeyRing(bytes.NewReader(b))
+}
+
+// signingKey returns the private key of a recipient.
+func signingKey(keys, recipients openpgp.EntityList) *openpgp.Entity {
+	for _, key := range keys {
+		if key.PrivateKey != nil && len(findRecipients(recipients, fingerprint(key))) > 0 {
+			return key
+		}
+	}
+
+	return nil
+}
+
+func findRecipients(recipients openpgp.EntityList, id string) []*openpgp.Entity {
+	id = strings.ToUpper(strings.Replace(id, " ", "", -1))
+
+	matches := []*openpgp.Entity{}
+
+	for _, r := range recipients {
+		match := len(id) >= 8 && strings.HasSuffix(fingerprint(r), id)
+
+		for name := range r.Identities {
+			if strings.Contains(strings.ToUpper(name), id) {
+				match = true
+			}
+		}
+
+		if match {
+			matches = append(matches, r)
+		}
+	}
+
+	return matches
+}
+
+func fingerprint(e *openpgp.Entity) string {
+	return fmt.Sprintf("%X", e.PrimaryKey.Fingerprint)
+}
+
+func describeRecipient(e *openpgp.Entity) string {
+	for name := range e.Identities {
+		return fmt.Sprintf("%s (%s)", name, fingerprint(e))
+	}
+
+	return fingerprint(e)
+}
+
+func init() {
+	v := PGPVault{}
+	vaults[v.Name()] = &v
+}
@@ -0,0 +1,313 @@
+package vault
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+)
+
+func newPGPUser(t *testing.T, home, name string) (*openpgp.Entity, string) {
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// keys created by gpg prefer SHA256
+	for _, id := range e.Identities {
+		id.SelfSignature.PreferredHash = []uint8{8}
+	}
+
+	var private bytes.Buffer
+	w, err := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SerializePrivate(w, nil); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	path := filepath.Join(home, name+".key")
+	if err := ioutil.WriteFile(path, private.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return e, path
+}
+
+func publicKey(t *testing.T, e *openpgp.Entity) []byte {
+	var public bytes.Buffer
+	w, err := armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	return public.Bytes()
+}
+
+func TestEnsurePGPRecipientsCanReadSecrets(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+	bob, bobKey := newPGPUser(t, home, "bob")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("ctx", "group", "prop", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.AddRecipient(publicKey(t, bob)); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv(pgpKeyFileEnv, bobKey)
+
+	// act
+	value, err := v.Get("ctx", "group", "prop")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if value != "value" {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "value", value)
+	}
+}
+
+func TestEnsureRemovedPGPRecipientCannotReadSecrets(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+	bob, bobKey := newPGPUser(t, home, "bob")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.AddRecipient(publicKey(t, bob)); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("ctx", "group", "prop", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	removed, err := v.RemoveRecipient("bob@example.com")
+
+	// assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if removed != describeRecipient(bob) {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", describeRecipient(bob), removed)
+	}
+
+	if _, err := v.Get("ctx", "group", "prop"); err != nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %s", "alice can read secrets", err)
+	}
+
+	os.Setenv(pgpKeyFileEnv, bobKey)
+
+	if _, err := v.Get("ctx", "group", "prop"); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "bob cannot read secrets", err)
+	}
+}
+
+func TestEnsureLastPGPRecipientIsNotRemoved(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, err := v.RemoveRecipient(fingerprint(alice))
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "last recipient error", err)
+	}
+}
+
+func TestEnsurePGPVaultNotSignedByRecipientIsRejected(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+	mallory, _ := newPGPUser(t, home, "mallory")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buff bytes.Buffer
+	aw, _ := armor.Encode(&buff, "PGP MESSAGE", nil)
+	w, err := openpgp.Encrypt(aw, openpgp.EntityList{alice}, mallory, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("group-prop: forged\n"))
+	w.Close()
+	aw.Close()
+
+	if err := ioutil.WriteFile(v.path(pgpVaultFileName), buff.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	_, err = v.Get("ctx", "group", "prop")
+
+	// assert
+	if err == nil || err == contract.ErrSecretNotFound {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "not signed error", err)
+	}
+}
+
+func TestEnsureUnsignedPGPRecipientsAreRejected(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+	mallory, _ := newPGPUser(t, home, "mallory")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+
+	recipients := append(publicKey(t, alice), publicKey(t, mallory)...)
+	if err := ioutil.WriteFile(v.path(pgpRecipientsFileName), recipients, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err := v.Set("ctx", "group", "prop", "value")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "not signed error", err)
+	}
+}
+
+func TestEnsureChangedPGPRecipientsRequireConfirmation(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	alice, aliceKey := newPGPUser(t, home, "alice")
+	mallory, malloryKey := newPGPUser(t, home, "mallory")
+
+	v := PGPVault{root: home, silent: true}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.AddRecipient(publicKey(t, alice)); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Set("ctx", "group", "prop", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	vault, err := ioutil.ReadFile(v.path(pgpVaultFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := v.AddRecipient(publicKey(t, mallory)); err != nil {
+		t.Fatal(err)
+	}
+
+	// the vault is restored, so mallory is a recipient it was not encrypted to
+	if err := ioutil.WriteFile(v.path(pgpVaultFileName), vault, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// act
+	err = v.Set("ctx", "group", "other", "secret")
+
+	// assert
+	if err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "confirmation error", err)
+	}
+
+	os.Setenv(pgpKeyFileEnv, malloryKey)
+
+	if _, err := v.Get("ctx", "group", "prop"); err == nil {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "mallory cannot read secrets", err)
+	}
+}
+
+func TestEnsurePGPPrivateKeysAreDecryptedOnce(t *testing.T) {
+	// arrange
+	defer setupHome(t)()
+	defer os.Unsetenv(pgpKeyFileEnv)
+
+	home := os.Getenv("HOME")
+	_, aliceKey := newPGPUser(t, home, "alice")
+
+	v := PGPVault{root: home}
+
+	os.Setenv(pgpKeyFileEnv, aliceKey)
+
+	if _, err := v.privateKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Remove(aliceKey)
+
+	// act
+	keys, err := v.privateKeys()
+
+	// assert
+	if err != nil || len(keys) != 1 {
+		t.Errorf("\nEXPECTED: %s \nACTUAL: %v", "cached private key", err)
+	}
+}
@@ -2,29 +2,42 @@ package vault
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/turnerlabs/cstore/v4/components/cfg"
 
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/contract"
 	"github.com/turnerlabs/cstore/v4/components/models"
+	"github.com/turnerlabs/cstore/v4/components/plugin"
 )
 
 var vaults = map[string]contract.IVault{}
 
-// Get ...
+var plugins sync.Once
+
+// Get returns the built in vaults and any vault plugins. Built in
+// vaults take precedence over plugins with the same name.
 func Get() map[string]contract.IVault {
+	plugins.Do(func() {
+		for name, p := range plugin.Vaults() {
+			if _, found := vaults[name]; !found {
+				vaults[name] = p
+			}
+		}
+	})
+
 	return vaults
 }
 
 // GetBy ...
 func GetBy(name, defaultVault string, clog catalog.Catalog, fileEntry *catalog.File, access contract.IVault, uo cfg.UserOptions, io models.IO) (contract.IVault, error) {
 	if len(name) == 0 {
-		v := vaults[defaultVault]
+		v := Get()[defaultVault]
 		return v, v.Pre(clog, fileEntry, access, uo, io)
 	}
 
-	if v, found := vaults[name]; found {
+	if v, found := Get()[name]; found {
 		return v, v.Pre(clog, fileEntry, access, uo, io)
 	}
 	return nil, errors.New("vault not found")
@@ -3,8 +3,12 @@ module github.com/turnerlabs/cstore/v4
 go 1.13
 
 require (
-	github.com/aws/aws-sdk-go v1.29.18
+	cloud.google.com/go/storage v1.6.0
+	github.com/Azure/azure-storage-blob-go v0.8.0
+	github.com/Azure/go-autorest/autorest/adal v0.8.2 // indirect
+	github.com/aws/aws-sdk-go v1.37.0
 	github.com/fatih/color v1.9.0
+	github.com/hashicorp/vault/api v1.0.4
 	github.com/keybase/go-keychain v0.0.0-20200218013740-86d4642e4ce2
 	github.com/mattn/go-colorable v0.1.4
 	github.com/mitchellh/go-homedir v1.1.0
@@ -14,6 +18,8 @@ require (
 	github.com/subosito/gotenv v1.2.0
 	github.com/tidwall/gjson v1.6.0
 	github.com/tidwall/sjson v1.0.4
-	golang.org/x/crypto v0.0.0-20200302210943-78000ba7a073
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	golang.org/x/sys v0.0.0-20200930185726-fdedc70b468f
+	google.golang.org/api v0.18.0
 	gopkg.in/yaml.v2 v2.2.8
 )
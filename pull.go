@@ -3,20 +3,28 @@ package cstore
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"time"
 
 	"github.com/subosito/gotenv"
 	"github.com/turnerlabs/cstore/v4/components/models"
 
 	"github.com/turnerlabs/cstore/v4/components/remote"
 
+	"github.com/turnerlabs/cstore/v4/components/cache"
 	"github.com/turnerlabs/cstore/v4/components/catalog"
 	"github.com/turnerlabs/cstore/v4/components/cfg"
+	"github.com/turnerlabs/cstore/v4/components/contract"
+	"github.com/turnerlabs/cstore/v4/components/display"
 	"github.com/turnerlabs/cstore/v4/components/path"
+	"github.com/turnerlabs/cstore/v4/components/plugin"
+	"github.com/turnerlabs/cstore/v4/components/store"
 	"github.com/turnerlabs/cstore/v4/components/token"
 )
 
 // Pull retrieves configuration fron a remote store using cstore.yml
 func Pull(catalogPath string, o Options) ([]byte, error) {
+	defer plugin.Close()
 
 	opt := o.ToUserOptions()
 
@@ -63,71 +71,198 @@ func Pull(catalogPath string, o Options) ([]byte, error) {
 			continue
 		}
 
-		//----------------------------------------------------
-		//- Get the remote store and vaults components ready.
-		//----------------------------------------------------
-		fileEntryTemp := fileEntry
-		remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, models.IO{})
+		pulled, err := pullFile(clog, root, fileEntry, opt)
 		if err != nil {
+			return data, err
+		}
+
+		data = pulled.Data
+	}
+
+	return data, nil
+}
+
+// PulledFile is a file retrieved from a remote store along with the
+// attributes the store reported for it. Cached is true when the file
+// was served from the offline cache, because the remote store failed.
+type PulledFile struct {
+	Path       string
+	Data       []byte
+	Attributes contract.Attributes
+	Cached     bool
+}
+
+// PullFiles retrieves each file from a remote store using cstore.yml
+// including the remote attributes of each file.
+func PullFiles(catalogPath string, o Options) ([]PulledFile, error) {
+	defer plugin.Close()
+
+	opt := o.ToUserOptions()
+
+	pulled := []PulledFile{}
+
+	//-------------------------------------------------
+	//- Get the local catalog for reference.
+	//-------------------------------------------------
+	clog, err := catalog.Get(catalogPath)
+	if err != nil {
+		return pulled, err
+	}
 
-			p := path.BuildPath(root, fileEntry.Path)
-			if len(opt.Version) > 0 {
-				p = fmt.Sprintf("%s (%s)", p, opt.Version)
+	root := path.RemoveFileName(catalogPath)
+
+	files := clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, opt.Version)
+
+	if len(opt.Version) > 0 && len(files) == 0 {
+		files = clog.FilesBy(opt.GetPaths(clog.CWD), opt.TagList, opt.AllTags, "")
+	}
+
+	if len(files) == 0 {
+		return pulled, fmt.Errorf("FileNotFoundError: file not found in %s", catalogPath)
+	}
+
+	for _, fileEntry := range files {
+
+		fileEntry = remote.OverrideFileSettings(fileEntry, opt)
+
+		if fileEntry.IsRef {
+			children, err := PullFiles(path.BuildPath(root, fileEntry.Path), o)
+			if err != nil {
+				return pulled, err
 			}
 
-			return data, fmt.Errorf("PullFailedError1: %s (%s)", p, err)
+			pulled = append(pulled, children...)
+
+			continue
 		}
 
-		//----------------------------------------------------
-		//- Pull remote file from store.
-		//----------------------------------------------------
-		file, _, err := remoteComp.Store.Pull(&fileEntry, opt.Version)
+		file, err := pullFile(clog, root, fileEntry, opt)
 		if err != nil {
+			return pulled, err
+		}
 
-			p := path.BuildPath(root, fileEntry.Path)
-			if len(opt.Version) > 0 {
-				p = fmt.Sprintf("%s (%s)", p, opt.Version)
-			}
+		pulled = append(pulled, file)
+	}
+
+	return pulled, nil
+}
 
-			return data, fmt.Errorf("PullFailedError2: %s (%s)", p, err)
+//----------------------------------------------------
+//- Pull a single file injecting secrets if requested.
+//----------------------------------------------------
+func pullFile(clog catalog.Catalog, root string, fileEntry catalog.File, opt cfg.UserOptions) (PulledFile, error) {
+
+	pulled := PulledFile{
+		Path: path.BuildPath(root, fileEntry.Path),
+	}
+
+	p := pulled.Path
+	if len(opt.Version) > 0 {
+		p = fmt.Sprintf("%s (%s)", p, opt.Version)
+	}
+
+	//----------------------------------------------------
+	//- Pull remote file from store or, when the store
+	//- fails, from the cache if the caller allows it.
+	//----------------------------------------------------
+	remoteComp, file, attr, err := pullRemote(clog, p, &fileEntry, opt)
+	if err != nil {
+		if !opt.Cache {
+			return PulledFile{}, err
 		}
 
-		//-------------------------------------------------
-		//- If user specifies, inject secrets into file.
-		//-------------------------------------------------
-		fileWithSecrets := file
+		cachedComp, entry, cacheErr := pullCache(clog, fileEntry, opt)
+		if cacheErr != nil {
+			return PulledFile{}, fmt.Errorf("%s (cache: %s)", err, cacheErr)
+		}
 
-		if opt.InjectSecrets {
-			if !fileEntry.SupportsSecrets() {
-				return data, fmt.Errorf("IncompatibleFileError: %s secrets not supported", fileEntry.Path)
-			}
+		display.Warn(fmt.Errorf("%s served from cache pulled %s ago, because the remote store failed (%s)", p, entry.Age(), err), os.Stderr)
 
-			tokens, err := token.Find(fileWithSecrets, fileEntry.Type, false)
-			if err != nil {
-				return data, fmt.Errorf("MissingTokensError: failed to find tokens in file %s (%s)", fileEntry.Path, err)
-			}
+		remoteComp, file, attr, pulled.Cached = cachedComp, entry.Data, entry.Attributes, true
 
-			for k, t := range tokens {
+	} else if opt.Cache {
+		if err := cache.Save(clog, fileEntry, opt.Version, file, attr, remoteComp.Access); err != nil {
+			display.Warn(fmt.Errorf("%s was not cached (%s)", p, err), os.Stderr)
+		}
+	}
 
-				value, err := remoteComp.Secrets.Get(clog.Context, t.Secret(), t.Prop)
-				if err != nil {
-					return data, fmt.Errorf("GetSecretValueError: failed to get value for %s/%s for %s (%s)", t.Secret(), t.Prop, path.BuildPath(root, fileEntry.Path), err)
-				}
+	pulled.Attributes = attr
 
-				t.Value = value
-				tokens[k] = t
-			}
+	//-------------------------------------------------
+	//- If user specifies, inject secrets into file.
+	//-------------------------------------------------
+	fileWithSecrets := file
 
-			fileWithSecrets, err = token.Replace(fileWithSecrets, fileEntry.Type, tokens, false)
+	if opt.InjectSecrets {
+		if !fileEntry.SupportsSecrets() {
+			return PulledFile{}, fmt.Errorf("IncompatibleFileError: %s secrets not supported", fileEntry.Path)
+		}
+
+		tokens, err := token.Find(fileWithSecrets, fileEntry.Type, false)
+		if err != nil {
+			return PulledFile{}, fmt.Errorf("MissingTokensError: failed to find tokens in file %s (%s)", fileEntry.Path, err)
+		}
+
+		for k, t := range tokens {
+
+			value, err := remoteComp.Secrets.Get(clog.Context, t.Secret(), t.Prop)
 			if err != nil {
-				return data, fmt.Errorf("TokenReplacementError: failed to replace tokens in file %s (%s)", fileEntry.Path, err)
+				return PulledFile{}, fmt.Errorf("GetSecretValueError: failed to get value for %s/%s for %s (%s)", t.Secret(), t.Prop, path.BuildPath(root, fileEntry.Path), err)
 			}
+
+			t.Value = value
+			tokens[k] = t
+		}
+
+		fileWithSecrets, err = token.Replace(fileWithSecrets, fileEntry.Type, tokens, false)
+		if err != nil {
+			return PulledFile{}, fmt.Errorf("TokenReplacementError: failed to replace tokens in file %s (%s)", fileEntry.Path, err)
 		}
+	}
+
+	pulled.Data = fileWithSecrets
+
+	return pulled, nil
+}
 
-		data = fileWithSecrets
+//----------------------------------------------------
+//- Get the remote store and vaults components ready
+//- and pull the file.
+//----------------------------------------------------
+func pullRemote(clog catalog.Catalog, p string, fileEntry *catalog.File, opt cfg.UserOptions) (remote.Components, []byte, contract.Attributes, error) {
+
+	fileEntryTemp := *fileEntry
+	remoteComp, err := remote.InitComponents(&fileEntryTemp, clog, opt, models.IO{})
+	if err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedError1: %s (%s)", p, err)
 	}
 
-	return data, nil
+	if err := store.ValidatePull(remoteComp.Store, *fileEntry, opt.Version); err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedError1: %s (%s)", p, err)
+	}
+
+	file, attr, err := remoteComp.Store.Pull(fileEntry, opt.Version)
+	if err != nil {
+		return remoteComp, nil, contract.Attributes{}, fmt.Errorf("PullFailedError2: %s (%s)", p, err)
+	}
+
+	return remoteComp, file, attr, nil
+}
+
+//----------------------------------------------------
+//- Get the vaults ready without the store and read
+//- the last pulled copy of the file.
+//----------------------------------------------------
+func pullCache(clog catalog.Catalog, fileEntry catalog.File, opt cfg.UserOptions) (remote.Components, cache.Entry, error) {
+
+	remoteComp, err := remote.InitVaults(&fileEntry, clog, opt, models.IO{})
+	if err != nil {
+		return remoteComp, cache.Entry{}, err
+	}
+
+	entry, err := cache.Get(clog, fileEntry, opt.Version, opt.CacheMaxAge, remoteComp.Access)
+
+	return remoteComp, entry, err
 }
 
 // PullEnv retrieves configuration stored in .env format as a map
@@ -155,6 +290,13 @@ type Options struct {
 	Paths         []string
 	Version       string
 	InjectSecrets bool
+
+	// Cache saves each pulled file encrypted in ~/.cstore/cache and
+	// serves the cached copy when the remote store fails.
+	Cache bool
+
+	// CacheMaxAge is the oldest cached copy served. Zero accepts any age.
+	CacheMaxAge time.Duration
 }
 
 func (o Options) ToUserOptions() cfg.UserOptions {
@@ -164,6 +306,8 @@ func (o Options) ToUserOptions() cfg.UserOptions {
 		TagList:       o.Tags,
 		AllTags:       o.AllTags,
 		InjectSecrets: o.InjectSecrets,
+		Cache:         o.Cache,
+		CacheMaxAge:   o.CacheMaxAge,
 		Silent:        true,
 	}
 }